@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// annotation marks something worth pointing out on a chart. Which fields
+// are set decides what it is:
+//
+//	date          a vertical marker, e.g. a deploy
+//	value         a horizontal reference line, e.g. an SLO target
+//	from and to   a shaded time range, e.g. an incident
+//	date, value   a callout at that point
+//
+// Color is a hex color or one of the theme's status colors "good", "warn"
+// and "critical".
+type annotation struct {
+	Label string     `json:"label"`
+	Date  *time.Time `json:"date"`
+	Value *float64   `json:"value"`
+	From  *time.Time `json:"from"`
+	To    *time.Time `json:"to"`
+	Color string     `json:"color"`
+}
+
+// kind returns "range", "callout", "marker" or "line", or "" for an
+// annotation with nothing to draw.
+func (a annotation) kind() string {
+	switch {
+	case a.From != nil && a.To != nil:
+		return "range"
+	case a.Date != nil && a.Value != nil:
+		return "callout"
+	case a.Date != nil:
+		return "marker"
+	case a.Value != nil:
+		return "line"
+	}
+	return ""
+}
+
+// color resolves the annotation's color against the theme. By default
+// reference lines use the critical color, ranges the text color and
+// markers and callouts the warn color.
+func (a annotation) color(config dataviz.RenderConfig) string {
+	switch a.Color {
+	case "good", "warn", "critical":
+		return statusColor(config.Theme, a.Color)
+	case "":
+	default:
+		return a.Color
+	}
+	switch a.kind() {
+	case "line":
+		return statusColor(config.Theme, "critical")
+	case "range":
+		_, text := themeColors(config)
+		return text
+	default:
+		return statusColor(config.Theme, "warn")
+	}
+}
+
+// parseAnnotationDate reads a date in RFC 3339 or a shorter ISO form.
+func parseAnnotationDate(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseAnnotation reads an annotation flag: "DATE" for a marker, "VALUE"
+// for a line, "FROM..TO" for a range or "DATE,VALUE" for a callout, each
+// optionally followed by "=LABEL".
+func parseAnnotation(kind, spec string) (annotation, error) {
+	where, label, _ := strings.Cut(spec, "=")
+	a := annotation{Label: label}
+	var err error
+	date := func(s string) *time.Time {
+		t, e := parseAnnotationDate(strings.TrimSpace(s))
+		if e != nil && err == nil {
+			err = e
+		}
+		return &t
+	}
+	value := func(s string) *float64 {
+		v, e := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if e != nil && err == nil {
+			err = fmt.Errorf("invalid value %q", s)
+		}
+		return &v
+	}
+
+	switch kind {
+	case "marker":
+		a.Date = date(where)
+	case "line":
+		a.Value = value(where)
+	case "range":
+		from, to, ok := strings.Cut(where, "..")
+		if !ok {
+			return a, fmt.Errorf("invalid range %q (want FROM..TO)", where)
+		}
+		a.From, a.To = date(from), date(to)
+	case "callout":
+		at, v, ok := strings.Cut(where, ",")
+		if !ok {
+			return a, fmt.Errorf("invalid callout %q (want DATE,VALUE)", where)
+		}
+		a.Date, a.Value = date(at), value(v)
+	}
+	return a, err
+}
+
+// annotationFlag is a repeatable command line flag that adds annotations
+// of one kind.
+type annotationFlag struct {
+	kind string
+	list *[]annotation
+}
+
+func (f annotationFlag) String() string { return "" }
+
+func (f annotationFlag) Set(spec string) error {
+	a, err := parseAnnotation(f.kind, spec)
+	if err != nil {
+		return err
+	}
+	*f.list = append(*f.list, a)
+	return nil
+}
+
+// withAnnotations widens the extent so every annotation is in view.
+func (e seriesExtent) withAnnotations(annotations []annotation) seriesExtent {
+	for _, a := range annotations {
+		for _, t := range []*time.Time{a.Date, a.From, a.To} {
+			if t == nil {
+				continue
+			}
+			if t.Before(e.start) {
+				e.start = *t
+			}
+			if t.After(e.end) {
+				e.end = *t
+			}
+		}
+		if a.Value != nil {
+			e.min = math.Min(e.min, *a.Value)
+			e.max = math.Max(e.max, *a.Value)
+		}
+	}
+	return e
+}
+
+// markText writes label text onto the canvas on row, starting at col, or
+// ending just before col when it does not fit to the right.
+func markText(canvas *colorCanvas, col, row int, text, color string) {
+	width := utf8.RuneCountInString(text)
+	if col+width > canvas.cols {
+		col = max(0, col-width-1)
+	}
+	for i, r := range []rune(text) {
+		canvas.Label(col+i, row, r, color)
+	}
+}
+
+// shadeRangesTerminal fills the columns of each range annotation with a
+// light shade, labelled along the top row. It is drawn before gridlines so
+// that they stay visible.
+func shadeRangesTerminal(canvas *colorCanvas, ext seriesExtent, annotations []annotation, config dataviz.RenderConfig) {
+	maxX := float64(canvas.PixelWidth() - 1)
+	for _, a := range annotations {
+		if a.kind() != "range" {
+			continue
+		}
+		color := a.color(config)
+		from := int(math.Round(math.Max(0, ext.xRatio(*a.From))*maxX)) / 2
+		to := int(math.Round(math.Min(1, ext.xRatio(*a.To))*maxX)) / 2
+		for col := from; col <= to; col++ {
+			for row := 0; row < canvas.rows; row++ {
+				canvas.Mark(col, row, '░', color)
+			}
+		}
+		if a.Label != "" {
+			markText(canvas, from, 0, a.Label, color)
+		}
+	}
+}
+
+// annotateTerminal draws markers as vertical rules, reference lines as
+// dashed horizontal rules and callouts as a heavier dot at their point,
+// each with its label beside it.
+func annotateTerminal(canvas *colorCanvas, ext seriesExtent, scale valueScale, annotations []annotation, config dataviz.RenderConfig) {
+	maxX := float64(canvas.PixelWidth() - 1)
+	maxY := float64(canvas.PixelHeight() - 1)
+	for _, a := range annotations {
+		color := a.color(config)
+		switch a.kind() {
+		case "marker":
+			col := int(math.Round(ext.xRatio(*a.Date)*maxX)) / 2
+			for row := 0; row < canvas.rows; row++ {
+				canvas.Mark(col, row, '│', color)
+			}
+			if a.Label != "" {
+				markText(canvas, col+1, 0, a.Label, color)
+			}
+		case "line":
+			row := int(math.Round((1-scale.Ratio(*a.Value))*maxY)) / 4
+			for col := 0; col < canvas.cols; col++ {
+				canvas.Mark(col, row, '╌', color)
+			}
+			if a.Label != "" {
+				above := row - 1
+				if above < 0 {
+					above = row + 1
+				}
+				markText(canvas, canvas.cols-utf8.RuneCountInString(a.Label), above, a.Label, color)
+			}
+		case "callout":
+			x := int(math.Round(ext.xRatio(*a.Date) * maxX))
+			y := int(math.Round((1 - scale.Ratio(*a.Value)) * maxY))
+			for dx := 0; dx < 2; dx++ {
+				for dy := -1; dy < 1; dy++ {
+					canvas.Set(x+dx, y+dy, color)
+				}
+			}
+			if a.Label != "" {
+				row := y / 4
+				if row > 0 {
+					row--
+				}
+				markText(canvas, x/2+1, row, a.Label, color)
+			}
+		}
+	}
+}
+
+// shadeRangesSVG draws range annotations as translucent bands behind the
+// data, labelled at their top left.
+func shadeRangesSVG(doc *svgDocument, area svgPlotArea, ext seriesExtent, annotations []annotation, config dataviz.RenderConfig) {
+	for _, a := range annotations {
+		if a.kind() != "range" {
+			continue
+		}
+		color := a.color(config)
+		from := area.X + math.Max(0, ext.xRatio(*a.From))*area.W
+		to := area.X + math.Min(1, ext.xRatio(*a.To))*area.W
+		doc.Add(svgRect{X: from, Y: area.Y, W: math.Max(1, to-from), H: area.H, Fill: color, Opacity: 0.15, Title: a.Label})
+		if a.Label != "" {
+			doc.Add(svgText{X: from + 4, Y: area.Y + 12, Text: a.Label, Fill: color, Size: 10})
+		}
+	}
+}
+
+// annotateSVG draws markers and reference lines as dashed lines and
+// callouts as rings around their point, each with its label.
+func annotateSVG(doc *svgDocument, area svgPlotArea, ext seriesExtent, scale valueScale, annotations []annotation, config dataviz.RenderConfig, opts chartOptions) {
+	for _, a := range annotations {
+		color := a.color(config)
+		switch a.kind() {
+		case "marker":
+			x := area.X + ext.xRatio(*a.Date)*area.W
+			doc.Add(svgLine{X1: x, Y1: area.Y, X2: x, Y2: area.Y + area.H, Stroke: color, Width: 1.5, Dash: "4 3"})
+			if a.Label != "" {
+				doc.Add(svgText{X: x + 4, Y: area.Y + 12, Text: a.Label, Fill: color, Size: 10})
+			}
+		case "line":
+			y := area.Y + (1-scale.Ratio(*a.Value))*area.H
+			doc.Add(svgLine{X1: area.X, Y1: y, X2: area.X + area.W, Y2: y, Stroke: color, Width: 1.5, Dash: "6 4"})
+			if a.Label != "" {
+				doc.Add(svgText{X: area.X + area.W - 4, Y: y - 4, Text: a.Label, Fill: color, Size: 10, Anchor: "end"})
+			}
+		case "callout":
+			x := area.X + ext.xRatio(*a.Date)*area.W
+			y := area.Y + (1-scale.Ratio(*a.Value))*area.H
+			title := opts.Dates.Format(*a.Date, "Jan 2 2006 15:04") + ": " + opts.Numbers.Format(*a.Value)
+			if a.Label != "" {
+				title = a.Label + " · " + title
+				doc.Add(svgText{X: x + 8, Y: y - 8, Text: a.Label, Fill: color, Size: 10})
+			}
+			doc.Add(svgCircle{CX: x, CY: y, R: 5, Fill: "none", Stroke: color, StrokeWidth: 2, Title: title})
+		}
+	}
+}
+
+// annotationOn returns the annotation that applies to a heatmap day: a
+// marker or callout on that day, or else a range that covers it.
+func annotationOn(day time.Time, annotations []annotation) (annotation, bool) {
+	var covering *annotation
+	for i, a := range annotations {
+		switch a.kind() {
+		case "marker", "callout":
+			if dayOf(*a.Date).Equal(day) {
+				return a, true
+			}
+		case "range":
+			if covering == nil && !day.Before(dayOf(*a.From)) && !day.After(dayOf(*a.To)) {
+				covering = &annotations[i]
+			}
+		}
+	}
+	if covering != nil {
+		return *covering, true
+	}
+	return annotation{}, false
+}
+
+// annotationLegend lists labelled annotations, for charts that cannot
+// label them in place.
+func annotationLegend(annotations []annotation, config dataviz.RenderConfig) []legendEntry {
+	var entries []legendEntry
+	for _, a := range annotations {
+		if a.Label == "" || a.kind() == "line" {
+			continue
+		}
+		var when string
+		switch a.kind() {
+		case "range":
+			when = a.From.Format("Jan 2") + "–" + a.To.Format("Jan 2")
+		default:
+			when = a.Date.Format("Jan 2")
+		}
+		entries = append(entries, legendEntry{Label: fmt.Sprintf("%s (%s)", a.Label, when), Color: a.color(config)})
+	}
+	return entries
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAnnotation(t *testing.T) {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		kind, spec string
+		label      string
+		want       string
+		date       *time.Time
+		value      *float64
+	}{
+		{"marker", "2024-03-10=release", "release", "marker", &day, nil},
+		{"line", " 42.5 =limit", "limit", "line", nil, ptr(42.5)},
+		{"range", "2024-03-01..2024-03-10=outage", "outage", "range", nil, nil},
+		{"callout", "2024-03-10, -3", "", "callout", &day, ptr(-3.0)},
+	}
+	for _, tt := range tests {
+		a, err := parseAnnotation(tt.kind, tt.spec)
+		if err != nil {
+			t.Errorf("parseAnnotation(%q, %q): %v", tt.kind, tt.spec, err)
+			continue
+		}
+		if a.kind() != tt.want || a.Label != tt.label {
+			t.Errorf("parseAnnotation(%q, %q) = %s %q, want %s %q", tt.kind, tt.spec, a.kind(), a.Label, tt.want, tt.label)
+		}
+		if tt.date != nil && !a.Date.Equal(*tt.date) {
+			t.Errorf("parseAnnotation(%q, %q): date = %v, want %v", tt.kind, tt.spec, a.Date, tt.date)
+		}
+		if tt.value != nil && *a.Value != *tt.value {
+			t.Errorf("parseAnnotation(%q, %q): value = %v, want %v", tt.kind, tt.spec, *a.Value, *tt.value)
+		}
+	}
+}
+
+func TestParseAnnotationErrors(t *testing.T) {
+	for _, tt := range []struct{ kind, spec string }{
+		{"marker", "yesterday"},
+		{"line", "high"},
+		{"range", "2024-03-01"},
+		{"range", "2024-03-01..soon"},
+		{"callout", "2024-03-10"},
+		{"callout", "2024-03-10,x"},
+	} {
+		if _, err := parseAnnotation(tt.kind, tt.spec); err == nil {
+			t.Errorf("parseAnnotation(%q, %q) succeeded, want an error", tt.kind, tt.spec)
+		}
+	}
+}
+
+func TestParseAnnotationDate(t *testing.T) {
+	want := time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)
+	for _, s := range []string{"2024-03-10T14:30:00Z", "2024-03-10T14:30", "2024-03-10 14:30"} {
+		if got, err := parseAnnotationDate(s); err != nil || !got.Equal(want) {
+			t.Errorf("parseAnnotationDate(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+}
+
+func TestAnnotationFlagAppends(t *testing.T) {
+	var list []annotation
+	f := annotationFlag{kind: "line", list: &list}
+	for _, spec := range []string{"1", "2=two"} {
+		if err := f.Set(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Set("x"); err == nil {
+		t.Error("Set(\"x\") succeeded, want an error")
+	}
+	if len(list) != 2 || list[1].Label != "two" {
+		t.Errorf("flag list = %+v, want two lines", list)
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
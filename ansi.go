@@ -0,0 +1,55 @@
+package main
+
+import "fmt"
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiReverse = "\x1b[7m"
+	ansiClear   = "\x1b[H\x1b[2J"
+)
+
+// ansiColor converts a hex color to an ANSI TrueColor foreground escape code.
+// It returns an empty string for colors it cannot parse.
+func ansiColor(hexColor string) string {
+	r, g, b, ok := parseHex(hexColor)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", r, g, b)
+}
+
+// ansiBackground converts a hex color to an ANSI TrueColor background escape
+// code, or an empty string for colors it cannot parse.
+func ansiBackground(hexColor string) string {
+	r, g, b, ok := parseHex(hexColor)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", r, g, b)
+}
+
+// parseHex splits a #RRGGBB color into its channels.
+func parseHex(hexColor string) (r, g, b int, ok bool) {
+	if len(hexColor) > 0 && hexColor[0] == '#' {
+		hexColor = hexColor[1:]
+	}
+	if len(hexColor) != 6 {
+		return 0, 0, 0, false
+	}
+	if _, err := fmt.Sscanf(hexColor, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return 0, 0, 0, false
+	}
+	return r, g, b, true
+}
+
+// colorize wraps s in the foreground color, or returns it unchanged when the
+// color cannot be parsed.
+func colorize(s, hexColor string) string {
+	code := ansiColor(hexColor)
+	if code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
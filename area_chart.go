@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// areaChartInput is the JSON accepted by -type area. It has the same shape
+// as the line graph input; several series are stacked on top of each other
+// in the order given.
+type areaChartInput struct {
+	lineGraphInput
+}
+
+// interpolate returns the value of a date-sorted series at t, linearly
+// interpolated between neighbouring points. Outside the series' own date
+// range it contributes nothing to the stack.
+func interpolate(points []timePoint, t time.Time) float64 {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(t) })
+	switch {
+	case i == len(points):
+		return 0
+	case points[i].Date.Equal(t):
+		return points[i].Value
+	case i == 0:
+		return 0
+	}
+	prev, next := points[i-1], points[i]
+	f := float64(t.Sub(prev.Date)) / float64(next.Date.Sub(prev.Date))
+	return prev.Value + f*(next.Value-prev.Value)
+}
+
+// stackAt returns the running totals of the series at t: element i is the
+// top edge of series i, and the bottom edge of series i+1.
+func stackAt(series []lineSeries, t time.Time) []float64 {
+	tops := make([]float64, len(series))
+	total := 0.0
+	for i, s := range series {
+		total += interpolate(s.Points, t)
+		tops[i] = total
+	}
+	return tops
+}
+
+// stackedDates returns every date that appears in any series, in order.
+func stackedDates(series []lineSeries) []time.Time {
+	seen := make(map[time.Time]bool)
+	var dates []time.Time
+	for _, s := range series {
+		for _, p := range s.Points {
+			if !seen[p.Date] {
+				seen[p.Date] = true
+				dates = append(dates, p.Date)
+			}
+		}
+	}
+	sort.Slice(dates, func(a, b int) bool { return dates[a].Before(dates[b]) })
+	return dates
+}
+
+// stackedRange returns the value range covered by the stack, always
+// including the zero baseline the areas are filled from.
+func stackedRange(series []lineSeries, dates []time.Time) (lo, hi float64) {
+	for _, t := range dates {
+		for _, v := range stackAt(series, t) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	return lo, hi
+}
+
+// Terminal implements chart. Each column is sampled at its date and the
+// stacked areas are shaded with eighth blocks.
+func (in areaChartInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	series := in.series(config)
+	ext, ok := extentOf(series)
+	if !ok {
+		return ""
+	}
+
+	legend := terminalLegend(seriesLegend(series), bounds.Width)
+	frame := newPlotFrame(bounds.Width, bounds.Height-len(legend), opts.Axes)
+	lo, hi := stackedRange(series, stackedDates(series))
+	scale := newValueScale(lo, hi, opts, maxYTicks(frame.rows))
+	frame.setYTicks(scale.Ticks(maxYTicks(frame.rows), opts.Numbers))
+	frame.xTicks = dateTicks(ext.start, ext.end, maxXTicks(frame.cols), opts.Dates)
+
+	eighths := float64(frame.rows * 8)
+	span := ext.end.Sub(ext.start)
+	columns := make([][]band, frame.cols)
+	for col := range columns {
+		t := ext.start
+		if frame.cols > 1 {
+			t = t.Add(time.Duration(float64(span) * float64(col) / float64(frame.cols-1)))
+		}
+		base := scale.Ratio(0) * eighths
+		for i, top := range stackAt(series, t) {
+			edge := scale.Ratio(top) * eighths
+			columns[col] = append(columns[col], band{From: base, To: edge, Color: series[i].Color})
+			base = edge
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range frame.decorate(renderColumns(columns, frame.rows)) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	for _, line := range legend {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SVG implements chart. Each series is a filled polygon between its own top
+// edge and the top edge of the series below it.
+func (in areaChartInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	series := in.series(config)
+	ext, ok := extentOf(series)
+	if !ok {
+		return doc.String()
+	}
+
+	legend := seriesLegend(series)
+	area := newSVGPlotArea(bounds, len(legend) > 0, opts.Axes)
+	dates := stackedDates(series)
+	lo, hi := stackedRange(series, dates)
+	scale := newValueScale(lo, hi, opts, maxSVGTicks(area.H))
+	xTicks := dateTicks(ext.start, ext.end, maxSVGTicks(area.W), opts.Dates)
+	area.drawAxes(doc, scale.Ticks(maxSVGTicks(area.H), opts.Numbers), xTicks, opts, textColor)
+	if len(legend) > 0 {
+		svgLegend(doc, legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+	}
+
+	point := func(t time.Time, v float64) svgPoint {
+		return svgPoint{X: area.X + ext.xRatio(t)*area.W, Y: area.Y + (1-scale.Ratio(v))*area.H}
+	}
+	stacks := make([][]float64, len(dates))
+	for j, t := range dates {
+		stacks[j] = stackAt(series, t)
+	}
+	for i, s := range series {
+		var top, bottom []svgPoint
+		for j, t := range dates {
+			below := 0.0
+			if i > 0 {
+				below = stacks[j][i-1]
+			}
+			top = append(top, point(t, stacks[j][i]))
+			bottom = append(bottom, point(t, below))
+		}
+		outline := append([]svgPoint(nil), top...)
+		for j := len(bottom) - 1; j >= 0; j-- {
+			outline = append(outline, bottom[j])
+		}
+		title := s.Label
+		if len(legend) > 0 {
+			title = legend[i].Label
+		}
+		doc.Add(
+			svgPolygon{Points: outline, Fill: s.Color, Opacity: 0.55, Title: title},
+			svgPolyline{Points: top, Stroke: s.Color, Width: 1.5},
+		)
+	}
+	return doc.String()
+}
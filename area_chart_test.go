@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStackAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	series := []lineSeries{
+		{Points: []timePoint{{day(1), 2}, {day(3), 4}}},
+		{Points: []timePoint{{day(2), 1}, {day(3), -1}}},
+	}
+	tests := []struct {
+		at   time.Time
+		want []float64
+	}{
+		{day(1), []float64{2, 2}},
+		{day(2), []float64{3, 4}},
+		{day(3), []float64{4, 3}},
+		{day(4), []float64{0, 0}},
+	}
+	for _, tt := range tests {
+		got := stackAt(series, tt.at)
+		if got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("stackAt(%s) = %v, want %v", tt.at.Format("Jan 2"), got, tt.want)
+		}
+	}
+
+	dates := stackedDates(series)
+	if len(dates) != 3 {
+		t.Errorf("stackedDates = %v, want the 3 distinct dates", dates)
+	}
+	if lo, hi := stackedRange(series, dates); lo != 0 || hi != 4 {
+		t.Errorf("stackedRange = %v..%v, want 0..4", lo, hi)
+	}
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// chartOptions holds the axis, scale and label settings shared by the
+// charts that viz-cli draws itself. YMin and YMax are NaN when not set.
+type chartOptions struct {
+	Axes    bool
+	Grid    bool
+	YMin    float64
+	YMax    float64
+	YLog    bool
+	Numbers numberFormat
+	Dates   dateFormat
+}
+
+// defaultChartOptions returns options with no axes, an automatic scale and
+// plain labels.
+func defaultChartOptions() chartOptions {
+	numbers, _ := parseNumberFormat("", "")
+	return chartOptions{
+		YMin:    math.NaN(),
+		YMax:    math.NaN(),
+		Numbers: numbers,
+		Dates:   parseDateFormat(""),
+	}
+}
+
+// enabled reports whether any option asks for more than the plain chart.
+func (o chartOptions) enabled() bool {
+	return o.Axes || o.Grid || o.YLog || !math.IsNaN(o.YMin) || !math.IsNaN(o.YMax) ||
+		o.Numbers.Kind != "plain" || o.Dates.Kind != "auto"
+}
+
+// valueScale maps data values onto the 0..1 range of a plot axis.
+type valueScale struct {
+	Min float64
+	Max float64
+	Log bool
+}
+
+// newValueScale builds a scale covering the data range. Explicit bounds in
+// opts win; otherwise, when axes or gridlines are drawn, the range is
+// widened to round tick values.
+func newValueScale(dataMin, dataMax float64, opts chartOptions, maxTicks int) valueScale {
+	s := valueScale{Min: dataMin, Max: dataMax, Log: opts.YLog}
+	if s.Log {
+		// Values at or below zero cannot be shown on a log axis; fall back
+		// to a one decade range below the smallest positive bound.
+		if s.Max <= 0 {
+			s.Max = 1
+		}
+		if s.Min <= 0 {
+			s.Min = s.Max / 10
+		}
+		if opts.Axes || opts.Grid {
+			s.Min = math.Pow(10, math.Floor(math.Log10(s.Min)))
+			s.Max = math.Pow(10, math.Ceil(math.Log10(s.Max)))
+		}
+	} else if opts.Axes || opts.Grid {
+		s.Min, s.Max, _ = niceRange(s.Min, s.Max, maxTicks)
+	}
+	if !math.IsNaN(opts.YMin) {
+		s.Min = opts.YMin
+	}
+	if !math.IsNaN(opts.YMax) {
+		s.Max = opts.YMax
+	}
+	if s.Max <= s.Min {
+		s.Max = s.Min + 1
+	}
+	return s
+}
+
+// Ratio positions v on the scale, from 0 at Min to 1 at Max. Values outside
+// the scale are clamped.
+func (s valueScale) Ratio(v float64) float64 {
+	var r float64
+	if s.Log {
+		if v <= 0 {
+			return 0
+		}
+		r = (math.Log10(v) - math.Log10(s.Min)) / (math.Log10(s.Max) - math.Log10(s.Min))
+	} else {
+		r = (v - s.Min) / (s.Max - s.Min)
+	}
+	return math.Max(0, math.Min(1, r))
+}
+
+// axisTick is a labelled position along an axis, 0 at the start and 1 at
+// the end.
+type axisTick struct {
+	Ratio float64
+	Label string
+}
+
+// Ticks returns at most maxTicks values inside the scale, labelled with nf.
+func (s valueScale) Ticks(maxTicks int, nf numberFormat) []axisTick {
+	if maxTicks < 2 {
+		maxTicks = 2
+	}
+	var ticks []axisTick
+	if s.Log {
+		for e := math.Ceil(math.Log10(s.Min)); e <= math.Floor(math.Log10(s.Max)); e++ {
+			v := math.Pow(10, e)
+			ticks = append(ticks, axisTick{Ratio: s.Ratio(v), Label: nf.FormatTick(v, v)})
+		}
+		return thinTicks(ticks, maxTicks)
+	}
+
+	// The nice step for the range can be coarser than the range itself once
+	// explicit bounds are applied; refine it until at least two ticks fit.
+	_, _, step := niceRange(s.Min, s.Max, maxTicks)
+	for i := 0; i < maxStepRefinements && step > 0 && math.Floor(s.Max/step)-math.Ceil(s.Min/step) < 1; i++ {
+		step = finerStep(step)
+	}
+	if !(step > 0) {
+		return nil
+	}
+
+	// Ticks are numbered rather than found by adding up steps, which stops
+	// advancing once the step is below the precision of the values.
+	first := math.Ceil(s.Min / step)
+	count := math.Min(math.Floor(s.Max/step+1e-6)-first+1, maxAxisTicks)
+	for i := 0.0; i < count; i++ {
+		v := (first + i) * step
+		// Avoid printing -0.
+		if math.Abs(v) < step/1e6 {
+			v = 0
+		}
+		if n := len(ticks); n > 0 && ticks[n-1].Ratio == s.Ratio(v) {
+			continue
+		}
+		ticks = append(ticks, axisTick{Ratio: s.Ratio(v), Label: nf.FormatTick(v, step)})
+	}
+	return ticks
+}
+
+// Ticks gives up refining a step after maxStepRefinements tries, and never
+// returns more than maxAxisTicks ticks, however the range and step round.
+const (
+	maxStepRefinements = 20
+	maxAxisTicks       = 100
+)
+
+// niceRange widens from..to to multiples of a 1, 2 or 5 step so that the
+// range splits into at most maxTicks round values.
+func niceRange(from, to float64, maxTicks int) (lo, hi, step float64) {
+	if maxTicks < 2 {
+		maxTicks = 2
+	}
+	if to <= from {
+		to = from + 1
+	}
+	span := niceNumber(to-from, false)
+	step = niceNumber(span/float64(maxTicks-1), true)
+	return math.Floor(from/step) * step, math.Ceil(to/step) * step, step
+}
+
+// finerStep returns the next smaller step in the 1, 2, 5 sequence.
+func finerStep(step float64) float64 {
+	exp := math.Pow(10, math.Floor(math.Log10(step)))
+	switch m := math.Round(step / exp); {
+	case m >= 5:
+		return 2 * exp
+	case m >= 2:
+		return exp
+	default:
+		return exp / 2
+	}
+}
+
+// niceNumber returns a 1, 2, 5 or 10 multiple of a power of ten close to x,
+// rounding when round is set and taking the ceiling otherwise.
+func niceNumber(x float64, round bool) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	var nice float64
+	switch {
+	case round && f < 1.5, !round && f <= 1:
+		nice = 1
+	case round && f < 3, !round && f <= 2:
+		nice = 2
+	case round && f < 7, !round && f <= 5:
+		nice = 5
+	default:
+		nice = 10
+	}
+	return nice * math.Pow(10, exp)
+}
+
+// thinTicks keeps every n-th tick so that at most limit remain.
+func thinTicks(ticks []axisTick, limit int) []axisTick {
+	if len(ticks) <= limit {
+		return ticks
+	}
+	every := (len(ticks) + limit - 1) / limit
+	var kept []axisTick
+	for i := 0; i < len(ticks); i += every {
+		kept = append(kept, ticks[i])
+	}
+	return kept
+}
+
+// dateStep is a candidate spacing for date ticks.
+type dateStep struct {
+	duration time.Duration // fixed length steps
+	months   int           // calendar steps, used when duration is 0
+	layout   string
+}
+
+var dateSteps = []dateStep{
+	{duration: time.Minute, layout: "15:04"},
+	{duration: 5 * time.Minute, layout: "15:04"},
+	{duration: 15 * time.Minute, layout: "15:04"},
+	{duration: 30 * time.Minute, layout: "15:04"},
+	{duration: time.Hour, layout: "15:04"},
+	{duration: 3 * time.Hour, layout: "15:04"},
+	{duration: 6 * time.Hour, layout: "Jan 2 15h"},
+	{duration: 12 * time.Hour, layout: "Jan 2 15h"},
+	{duration: 24 * time.Hour, layout: "Jan 2"},
+	{duration: 2 * 24 * time.Hour, layout: "Jan 2"},
+	{duration: 7 * 24 * time.Hour, layout: "Jan 2"},
+	{duration: 14 * 24 * time.Hour, layout: "Jan 2"},
+	{months: 1, layout: "Jan 2006"},
+	{months: 3, layout: "Jan 2006"},
+	{months: 6, layout: "Jan 2006"},
+	{months: 12, layout: "2006"},
+}
+
+// dateTicks picks the finest calendar-aligned step that yields at most
+// maxTicks ticks between start and end, labelled with df. Spans too long
+// for even yearly ticks keep every few years. When fewer than two of its
+// boundaries fall inside a short range, it steps down to finer units until
+// two do, keeping at most maxTicks of them.
+func dateTicks(start, end time.Time, maxTicks int, df dateFormat) []axisTick {
+	span := end.Sub(start)
+	if span <= 0 || maxTicks < 1 {
+		return nil
+	}
+
+	i := len(dateSteps) - 1
+	for j, s := range dateSteps {
+		length := s.duration
+		if length == 0 {
+			length = time.Duration(s.months) * 30 * 24 * time.Hour
+		}
+		if int(span/length) < maxTicks {
+			i = j
+			break
+		}
+	}
+
+	ticks := thinTicks(dateTicksEvery(start, end, dateSteps[i], df), maxTicks)
+	for ; len(ticks) < 2 && maxTicks >= 2 && i > 0; i-- {
+		ticks = thinTicks(dateTicksEvery(start, end, dateSteps[i-1], df), maxTicks)
+	}
+	return ticks
+}
+
+// dateTicksEvery returns a tick at every boundary of step between start
+// and end.
+func dateTicksEvery(start, end time.Time, step dateStep, df dateFormat) []axisTick {
+	span := end.Sub(start)
+	var ticks []axisTick
+	for t := alignDate(start, step); !t.After(end); t = advanceDate(t, step) {
+		if t.Before(start) {
+			continue
+		}
+		ticks = append(ticks, axisTick{
+			Ratio: float64(t.Sub(start)) / float64(span),
+			Label: df.Format(t, step.layout),
+		})
+	}
+	return ticks
+}
+
+// alignDate rounds t down to the boundary of step in t's location.
+func alignDate(t time.Time, step dateStep) time.Time {
+	switch {
+	case step.months >= 12:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	case step.months > 0:
+		month := (int(t.Month())-1)/step.months*step.months + 1
+		return time.Date(t.Year(), time.Month(month), 1, 0, 0, 0, 0, t.Location())
+	case step.duration >= 24*time.Hour:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	default:
+		return t.Truncate(step.duration)
+	}
+}
+
+func advanceDate(t time.Time, step dateStep) time.Time {
+	if step.months > 0 {
+		return t.AddDate(0, step.months, 0)
+	}
+	if step.duration >= 24*time.Hour {
+		return t.AddDate(0, 0, int(step.duration/(24*time.Hour)))
+	}
+	return t.Add(step.duration)
+}
+
+// plotFrame lays out a terminal plot area with an optional labelled y-axis
+// on its left and an x-axis with tick labels below it.
+type plotFrame struct {
+	axes   bool
+	gutter int // columns taken by y labels and the axis line
+	cols   int // plot area size in character cells
+	rows   int
+	yTicks []axisTick
+	xTicks []axisTick
+}
+
+// newPlotFrame sizes the plot area within width x height cells.
+func newPlotFrame(width, height int, axes bool) plotFrame {
+	f := plotFrame{axes: axes, cols: width, rows: height}
+	if axes {
+		f.rows -= 2
+	}
+	if f.rows < 1 {
+		f.rows = 1
+	}
+	return f
+}
+
+// setYTicks records the y ticks and reserves the gutter for their labels.
+func (f *plotFrame) setYTicks(ticks []axisTick) {
+	f.yTicks = ticks
+	if !f.axes {
+		return
+	}
+	width := 0
+	for _, t := range ticks {
+		width = max(width, utf8.RuneCountInString(t.Label))
+	}
+	f.gutter = width + 1
+	f.cols -= f.gutter
+	if f.cols < 1 {
+		f.cols = 1
+	}
+}
+
+// maxYTicks suggests how many y ticks fit in height rows.
+func maxYTicks(height int) int {
+	return max(3, height/2)
+}
+
+// maxXTicks suggests how many x ticks fit in width columns.
+func maxXTicks(width int) int {
+	return max(2, width/12)
+}
+
+// row returns the plot row for a y ratio, 0 being the top row.
+func (f plotFrame) row(ratio float64) int {
+	return int(math.Round((1 - ratio) * float64(f.rows-1)))
+}
+
+// col returns the plot column for an x ratio.
+func (f plotFrame) col(ratio float64) int {
+	return int(math.Round(ratio * float64(f.cols-1)))
+}
+
+// drawGrid marks dim gridlines at every tick on the canvas.
+func (f plotFrame) drawGrid(c *colorCanvas) {
+	for _, t := range f.yTicks {
+		row := f.row(t.Ratio)
+		for col := 0; col < f.cols; col++ {
+			c.Guide(col, row, '┈')
+		}
+	}
+	for _, t := range f.xTicks {
+		col := f.col(t.Ratio)
+		for row := 0; row < f.rows; row++ {
+			c.Guide(col, row, '┊')
+		}
+	}
+}
+
+// decorate adds the y-axis to each plot line and appends the x-axis rows.
+// Without axes the lines are returned unchanged.
+func (f plotFrame) decorate(lines []string) []string {
+	if !f.axes {
+		return lines
+	}
+
+	labels := make(map[int]string, len(f.yTicks))
+	for _, t := range f.yTicks {
+		labels[f.row(t.Ratio)] = t.Label
+	}
+
+	out := make([]string, 0, len(lines)+2)
+	for i, line := range lines {
+		label, ok := labels[i]
+		edge := "│"
+		if ok {
+			edge = "┤"
+		}
+		pad := strings.Repeat(" ", f.gutter-1-utf8.RuneCountInString(label))
+		out = append(out, pad+label+ansiDim+edge+ansiReset+line)
+	}
+
+	return append(out, xAxisLines(f.gutter, f.cols, f.xTicks)...)
+}
+
+// xAxisLines draws a horizontal axis with tick marks below a plot that
+// starts gutter columns from the left and is cols wide, followed by a row
+// of tick labels.
+func xAxisLines(gutter, cols int, ticks []axisTick) []string {
+	colOf := func(ratio float64) int { return int(math.Round(ratio * float64(cols-1))) }
+
+	tickCols := make(map[int]bool, len(ticks))
+	for _, t := range ticks {
+		tickCols[colOf(t.Ratio)] = true
+	}
+	var axis strings.Builder
+	axis.WriteString(strings.Repeat(" ", max(0, gutter-1)))
+	axis.WriteString(ansiDim + "└")
+	for col := 0; col < cols; col++ {
+		if tickCols[col] {
+			axis.WriteString("┬")
+		} else {
+			axis.WriteString("─")
+		}
+	}
+	axis.WriteString(ansiReset)
+
+	// Center each label under its tick, dropping labels that would collide
+	// with the previous one.
+	row := []rune(strings.Repeat(" ", gutter+cols))
+	next := 0
+	for _, t := range ticks {
+		label := []rune(t.Label)
+		start := gutter + colOf(t.Ratio) - len(label)/2
+		start = max(start, gutter)
+		start = min(start, len(row)-len(label))
+		if start < next || start < 0 {
+			continue
+		}
+		copy(row[start:], label)
+		next = start + len(label) + 1
+	}
+	return []string{axis.String(), strings.TrimRight(string(row), " ")}
+}
+
+// svgPadding is the margin around SVG charts drawn by viz-cli.
+const svgPadding = 16.0
+
+// svgPlotArea is the rectangle of an SVG chart that holds the data.
+type svgPlotArea struct {
+	X, Y, W, H float64
+}
+
+// newSVGPlotArea reserves room for the legend row and axis labels.
+func newSVGPlotArea(bounds dataviz.Bounds, legend, axes bool) svgPlotArea {
+	a := svgPlotArea{
+		X: svgPadding,
+		Y: svgPadding,
+		W: float64(bounds.Width) - 2*svgPadding,
+		H: float64(bounds.Height) - 2*svgPadding,
+	}
+	if legend {
+		a.Y += 24
+		a.H -= 24
+	}
+	if axes {
+		a.X += 40
+		a.W -= 40
+		a.H -= 20
+	}
+	return a
+}
+
+// maxSVGTicks suggests how many ticks fit along an axis of the given length.
+func maxSVGTicks(length float64) int {
+	return max(2, int(length/60))
+}
+
+// drawAxes adds gridlines and labelled axes for value ticks along y and
+// ticks along x, as requested by opts.
+func (a svgPlotArea) drawAxes(doc *svgDocument, yTicks, xTicks []axisTick, opts chartOptions, textColor string) {
+	if opts.Grid {
+		for _, t := range yTicks {
+			y := a.Y + (1-t.Ratio)*a.H
+			doc.Add(svgLine{X1: a.X, Y1: y, X2: a.X + a.W, Y2: y, Stroke: textColor, Width: 1, Opacity: 0.12})
+		}
+		for _, t := range xTicks {
+			x := a.X + t.Ratio*a.W
+			doc.Add(svgLine{X1: x, Y1: a.Y, X2: x, Y2: a.Y + a.H, Stroke: textColor, Width: 1, Opacity: 0.12})
+		}
+	}
+	if !opts.Axes {
+		return
+	}
+
+	bottom := a.Y + a.H
+	doc.Add(
+		svgLine{X1: a.X, Y1: a.Y, X2: a.X, Y2: bottom, Stroke: textColor, Width: 1, Opacity: 0.4},
+		svgLine{X1: a.X, Y1: bottom, X2: a.X + a.W, Y2: bottom, Stroke: textColor, Width: 1, Opacity: 0.4},
+	)
+	for _, t := range yTicks {
+		y := a.Y + (1-t.Ratio)*a.H
+		doc.Add(
+			svgLine{X1: a.X - 4, Y1: y, X2: a.X, Y2: y, Stroke: textColor, Width: 1, Opacity: 0.4},
+			svgText{X: a.X - 6, Y: y + 3.5, Text: t.Label, Fill: textColor, Size: 10, Anchor: "end"},
+		)
+	}
+	for _, t := range xTicks {
+		x := a.X + t.Ratio*a.W
+		doc.Add(
+			svgLine{X1: x, Y1: bottom, X2: x, Y2: bottom + 4, Stroke: textColor, Width: 1, Opacity: 0.4},
+			svgText{X: x, Y: bottom + 15, Text: t.Label, Fill: textColor, Size: 10, Anchor: "middle"},
+		)
+	}
+}
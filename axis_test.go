@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func tickLabels(ticks []axisTick) []string {
+	labels := make([]string, len(ticks))
+	for i, t := range ticks {
+		labels[i] = t.Label
+	}
+	return labels
+}
+
+func TestValueScaleTicks(t *testing.T) {
+	plain, _ := parseNumberFormat("", "")
+	tests := []struct {
+		name  string
+		scale valueScale
+		max   int
+		want  []string
+	}{
+		{"round range", valueScale{Min: 0, Max: 100}, 6, []string{"0", "20", "40", "60", "80", "100"}},
+		{"negative", valueScale{Min: -10, Max: 10}, 5, []string{"-10", "-5", "0", "5", "10"}},
+		{"narrow explicit bounds", valueScale{Min: 3, Max: 4}, 5, []string{"3.0", "3.2", "3.4", "3.6", "3.8", "4.0"}},
+		{"log", valueScale{Min: 1, Max: 1000, Log: true}, 5, []string{"1", "10", "100", "1000"}},
+	}
+	for _, tt := range tests {
+		got := tickLabels(tt.scale.Ticks(tt.max, plain))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ticks = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValueScaleTicksTerminate(t *testing.T) {
+	plain, _ := parseNumberFormat("", "")
+	scales := []valueScale{
+		// Steps below the precision of the values.
+		{Min: 1e20, Max: math.Nextafter(1e20, math.Inf(1))},
+		{Min: 1e20, Max: 1e20 + 1e5},
+		// Bounds too close to tell apart.
+		{Min: 0.5, Max: 0.5},
+		{Min: 1e-300, Max: 2e-300},
+		{Min: math.NaN(), Max: math.NaN()},
+	}
+	for _, s := range scales {
+		done := make(chan []axisTick)
+		go func() { done <- s.Ticks(5, plain) }()
+		select {
+		case ticks := <-done:
+			if len(ticks) > maxAxisTicks {
+				t.Errorf("%+v: %d ticks, want at most %d", s, len(ticks), maxAxisTicks)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%+v: Ticks did not return", s)
+		}
+	}
+}
+
+func TestNiceRange(t *testing.T) {
+	tests := []struct {
+		from, to     float64
+		max          int
+		lo, hi, step float64
+	}{
+		{0, 97, 5, 0, 100, 20},
+		{3, 7, 5, 3, 7, 1},
+		{-0.3, 0.8, 6, -0.5, 1, 0.5},
+	}
+	for _, tt := range tests {
+		lo, hi, step := niceRange(tt.from, tt.to, tt.max)
+		if math.Abs(lo-tt.lo) > 1e-9 || math.Abs(hi-tt.hi) > 1e-9 || math.Abs(step-tt.step) > 1e-9 {
+			t.Errorf("niceRange(%v, %v, %d) = %v, %v, %v, want %v, %v, %v",
+				tt.from, tt.to, tt.max, lo, hi, step, tt.lo, tt.hi, tt.step)
+		}
+	}
+}
+
+func TestDateTicks(t *testing.T) {
+	df := parseDateFormat("")
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		start, end time.Time
+		max        int
+		want       []string
+	}{
+		{"days", at(1, 0), at(5, 0), 5, []string{"Mar 1", "Mar 2", "Mar 3", "Mar 4", "Mar 5"}},
+		{"quarters", at(1, 0), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), 4, []string{"Apr 2024", "Jul 2024"}},
+		// The 30 hours from noon hold a single midnight.
+		{"shorter than a step", at(1, 12), at(2, 18), 2, []string{"Mar 1 12h", "Mar 2 12h"}},
+		{"hours", at(1, 13), at(1, 15), 2, []string{"13:00", "15:00"}},
+		// Five centuries hold 501 year boundaries.
+		{"centuries", time.Date(1500, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), 5,
+			[]string{"1500", "1601", "1702", "1803", "1904"}},
+	}
+	for _, tt := range tests {
+		ticks := dateTicks(tt.start, tt.end, tt.max, df)
+		got := tickLabels(ticks)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: ticks = %q, want %q", tt.name, got, tt.want)
+		}
+		if len(ticks) > tt.max {
+			t.Errorf("%s: %d ticks, want at most %d", tt.name, len(ticks), tt.max)
+		}
+	}
+}
@@ -0,0 +1,642 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// barEighths are the partial block glyphs used to draw bar ends at 1/8 cell
+// resolution, indexed by the number of eighths filled.
+var barEighths = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// barSegment names one of the values of every bar in a segmented chart.
+type barSegment struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// barChartInput is the JSON accepted by -type bar-chart. With Segments,
+// each bar's Values are split into named segments. Mode draws them
+// "stacked", "grouped" side by side, or "percent", stacked and scaled to
+// the same length; without segments, a stacked, grouped or percent chart
+// uses Secondary as a second segment. Orientation "vertical" draws columns
+// instead of rows.
+type barChartInput struct {
+	dataviz.BarChartData
+	Bars        []barValue   `json:"bars"`
+	Segments    []barSegment `json:"segments"`
+	Mode        string       `json:"mode"`
+	Orientation string       `json:"orientation"`
+}
+
+// barChartOptions are the bar chart settings from the command line. They
+// take precedence over the matching JSON fields.
+type barChartOptions struct {
+	Mode        string
+	Orientation string
+}
+
+// apply merges the command line settings into the input and checks the
+// result.
+func (in *barChartInput) apply(opts barChartOptions) error {
+	if opts.Mode != "" {
+		in.Mode = opts.Mode
+	}
+	if opts.Orientation != "" {
+		in.Orientation = opts.Orientation
+	}
+	switch in.Mode {
+	case "", "stacked", "grouped", "percent":
+	default:
+		return fmt.Errorf("unknown bar mode %q (want stacked, grouped or percent)", in.Mode)
+	}
+	switch in.Orientation {
+	case "", "horizontal", "vertical":
+	default:
+		return fmt.Errorf("unknown bar orientation %q (want horizontal or vertical)", in.Orientation)
+	}
+	return nil
+}
+
+// fitsDataviz reports whether the dataviz bar chart can draw the input
+// unchanged: horizontal bars of non-negative whole numbers with at most
+// the dataviz secondary value.
+func (in barChartInput) fitsDataviz() bool {
+	if len(in.Segments) > 0 || in.Mode != "" || in.Orientation == "vertical" {
+		return false
+	}
+	values := make([]float64, 0, 2*len(in.Bars))
+	for _, bar := range in.Bars {
+		values = append(values, bar.Value, bar.Secondary)
+	}
+	return integral(values) && nonNegative(values)
+}
+
+// toDataviz converts the input for the dataviz renderer.
+func (in barChartInput) toDataviz() dataviz.BarChartData {
+	data := in.BarChartData
+	data.Bars = make([]dataviz.BarData, len(in.Bars))
+	for i, bar := range in.Bars {
+		data.Bars[i] = dataviz.BarData{Label: bar.Label, Value: int(bar.Value), Secondary: int(bar.Secondary)}
+	}
+	return data
+}
+
+// mode returns how segments are drawn, honouring the dataviz stacked flag.
+func (in barChartInput) mode() string {
+	if in.Mode == "" && in.Stacked {
+		return "stacked"
+	}
+	return in.Mode
+}
+
+// segments returns the segments with their colors resolved.
+func (in barChartInput) segments(config dataviz.RenderConfig) []barSegment {
+	if len(in.Segments) > 0 {
+		segments := make([]barSegment, len(in.Segments))
+		for i, s := range in.Segments {
+			if s.Color == "" {
+				s.Color = paletteColor(config.Theme, i)
+			}
+			segments[i] = s
+		}
+		return segments
+	}
+	color := in.Color
+	if color == "" {
+		color = config.Color
+	}
+	if in.mode() != "" {
+		return []barSegment{{Color: color}, {Color: paletteColor(config.Theme, 1)}}
+	}
+	return []barSegment{{Color: color}}
+}
+
+// parts returns a bar's value for each of n segments.
+func (in barChartInput) parts(bar barValue, n int) []float64 {
+	switch {
+	case len(in.Segments) > 0:
+		parts := make([]float64, n)
+		copy(parts, bar.Values)
+		return parts
+	case n == 2:
+		return []float64{bar.Value, bar.Secondary}
+	default:
+		return []float64{bar.Value}
+	}
+}
+
+// barPiece is one drawn segment of a bar, spanning From..To on the value
+// axis. Value is the segment's input value, which differs from the span
+// in percent charts.
+type barPiece struct {
+	From, To float64
+	Value    float64
+	Color    string
+	Label    string
+}
+
+// barLane is one drawn bar: a whole bar when stacked, or one segment of it
+// when grouped. Text is the value printed at its end.
+type barLane struct {
+	Pieces []barPiece
+	Text   string
+}
+
+// end returns the value the lane reaches furthest from zero.
+func (l barLane) end() float64 {
+	end := 0.0
+	for _, p := range l.Pieces {
+		for _, v := range []float64{p.From, p.To} {
+			if math.Abs(v) > math.Abs(end) {
+				end = v
+			}
+		}
+	}
+	return end
+}
+
+// lanes lays out a bar's segments. Stacked segments grow away from zero,
+// positive values to one side and negative ones to the other; percent
+// stacks are scaled so their magnitudes add up to one.
+func (in barChartInput) lanes(bar barValue, segments []barSegment, nf numberFormat) []barLane {
+	parts := in.parts(bar, len(segments))
+	if in.mode() == "grouped" {
+		lanes := make([]barLane, len(parts))
+		for i, v := range parts {
+			lanes[i] = barLane{
+				Pieces: []barPiece{{From: math.Min(0, v), To: math.Max(0, v), Value: v, Color: segments[i].Color, Label: segments[i].Label}},
+				Text:   nf.Format(v),
+			}
+		}
+		return lanes
+	}
+
+	total, magnitude := 0.0, 0.0
+	for _, v := range parts {
+		total += v
+		magnitude += math.Abs(v)
+	}
+	lane := barLane{Text: nf.Format(total)}
+	pos, neg := 0.0, 0.0
+	for i, v := range parts {
+		piece := barPiece{Value: v, Color: segments[i].Color, Label: segments[i].Label}
+		if in.mode() == "percent" {
+			if magnitude == 0 {
+				break
+			}
+			v /= magnitude
+		}
+		if v >= 0 {
+			piece.From, piece.To = pos, pos+v
+			pos += v
+		} else {
+			piece.From, piece.To = neg+v, neg
+			neg += v
+		}
+		lane.Pieces = append(lane.Pieces, piece)
+	}
+	return []barLane{lane}
+}
+
+// barLayout is a bar chart resolved into lanes, with the value range and
+// the format of the value axis.
+type barLayout struct {
+	lanes  [][]barLane // per bar
+	legend []legendEntry
+	lo, hi float64
+	axis   numberFormat
+}
+
+// layout resolves every bar. The range always includes zero so bars grow
+// from a baseline; percent charts label their axis in percent.
+func (in barChartInput) layout(config dataviz.RenderConfig, opts chartOptions) barLayout {
+	segments := in.segments(config)
+	l := barLayout{lanes: make([][]barLane, len(in.Bars)), axis: opts.Numbers}
+	for i, bar := range in.Bars {
+		l.lanes[i] = in.lanes(bar, segments, opts.Numbers)
+		for _, lane := range l.lanes[i] {
+			for _, p := range lane.Pieces {
+				l.lo = math.Min(l.lo, p.From)
+				l.hi = math.Max(l.hi, p.To)
+			}
+		}
+	}
+	if in.mode() == "percent" {
+		l.axis = numberFormat{Kind: "percent", Decimals: -1, Locale: opts.Numbers.Locale}
+	}
+	for _, s := range segments {
+		if s.Label != "" {
+			l.legend = append(l.legend, legendEntry{Label: s.Label, Color: s.Color})
+		}
+	}
+	return l
+}
+
+// Terminal implements chart.
+func (in barChartInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	if in.Orientation == "vertical" {
+		return renderColumnChartTerminal(in, bounds, config, opts)
+	}
+	return renderBarChartTerminal(in, bounds, config, opts)
+}
+
+// SVG implements chart.
+func (in barChartInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	if in.Orientation == "vertical" {
+		return renderColumnChartSVG(in, bounds, config, opts)
+	}
+	return renderBarChartSVG(in, bounds, config, opts)
+}
+
+// rowCells renders a horizontal strip of cols cells from bands measured in
+// eighths of a cell from the left. A cell shows up to two colors, split at
+// 1/8 resolution; cells that no band reaches are returned as "".
+func rowCells(bands []band, cols int) []string {
+	cells := make([]string, cols)
+	for col := range cells {
+		base := float64(col * 8)
+		left := colorAt(bands, base+0.5)
+		k := 1
+		for k < 8 && colorAt(bands, base+float64(k)+0.5) == left {
+			k++
+		}
+		right := colorAt(bands, base+7.5)
+		switch {
+		case k >= 8 || left == right:
+			if left != "" {
+				cells[col] = colorize("█", left)
+			}
+		case right == "":
+			cells[col] = ansiColor(left) + barEighths[k] + ansiReset
+		case left == "":
+			// Reverse video paints the glyph in the background and the
+			// rest of the cell in the foreground color.
+			cells[col] = ansiReverse + ansiColor(right) + barEighths[k] + ansiReset
+		default:
+			cells[col] = ansiColor(left) + ansiBackground(right) + barEighths[k] + ansiReset
+		}
+	}
+	return cells
+}
+
+// renderBarChartTerminal draws horizontal bars with their labels on the left
+// and formatted values on the right and, when requested, a labelled value
+// axis and gridlines. When some values are negative, bars grow left and
+// right from a zero baseline. Grouped bars take one row per segment.
+func renderBarChartTerminal(data barChartInput, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	if len(data.Bars) == 0 {
+		return ""
+	}
+	l := data.layout(config, opts)
+	legend := terminalLegend(l.legend, bounds.Width)
+
+	labelWidth := 0
+	for _, bar := range data.Bars {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(bar.Label))
+	}
+	labelWidth = min(labelWidth, bounds.Width/3)
+	gutter := labelWidth + 1
+
+	valueWidth := 0
+	for _, lanes := range l.lanes {
+		for _, lane := range lanes {
+			valueWidth = max(valueWidth, utf8.RuneCountInString(lane.Text))
+		}
+	}
+	cols := max(1, bounds.Width-gutter-valueWidth-1)
+
+	rows := bounds.Height - len(legend)
+	if opts.Axes {
+		rows -= 2
+	}
+
+	scale := newValueScale(l.lo, l.hi, opts, maxXTicks(cols))
+	ticks := scale.Ticks(maxXTicks(cols), l.axis)
+	gridCols := make(map[int]bool, len(ticks))
+	if opts.Grid {
+		for _, t := range ticks {
+			gridCols[int(math.Round(t.Ratio*float64(cols-1)))] = true
+		}
+	}
+
+	// With negative values the zero column becomes a baseline and bars are
+	// measured from it; otherwise bars start at the left edge.
+	zeroCol := -1
+	origin := scale.Ratio(0) * float64(cols-1)
+	if scale.Min < 0 {
+		zeroCol = int(math.Round(origin))
+	}
+	// eighth maps a value on the given side of zero to a position along
+	// the row in eighths of a cell.
+	eighth := func(v float64, negative bool) float64 {
+		switch {
+		case zeroCol < 0:
+			return scale.Ratio(v) * float64(cols) * 8
+		case negative:
+			return float64(zeroCol*8) - (origin-scale.Ratio(v)*float64(cols-1))*8
+		default:
+			return float64((zeroCol+1)*8) + (scale.Ratio(v)*float64(cols-1)-origin)*8
+		}
+	}
+
+	var b strings.Builder
+	for i, bar := range data.Bars {
+		for j, lane := range l.lanes[i] {
+			if rows <= 0 {
+				break
+			}
+			rows--
+
+			label := []rune(bar.Label)
+			if j > 0 {
+				label = nil
+			}
+			if len(label) > labelWidth {
+				label = label[:labelWidth]
+			}
+			b.WriteString(strings.Repeat(" ", labelWidth-len(label)))
+			b.WriteString(string(label))
+			if opts.Axes {
+				b.WriteString(ansiDim + "│" + ansiReset)
+			} else {
+				b.WriteString(" ")
+			}
+
+			var bands []band
+			for _, p := range lane.Pieces {
+				negative := p.From < 0
+				bands = append(bands, band{From: eighth(p.From, negative), To: eighth(p.To, negative), Color: p.Color})
+			}
+			for col, cell := range rowCells(bands, cols) {
+				switch {
+				case cell != "":
+					b.WriteString(cell)
+				case col == zeroCol:
+					b.WriteString(ansiDim + "│" + ansiReset)
+				case gridCols[col]:
+					b.WriteString(ansiDim + "┊" + ansiReset)
+				default:
+					b.WriteString(" ")
+				}
+			}
+			b.WriteString(" ")
+			b.WriteString(lane.Text)
+			b.WriteString("\n")
+		}
+	}
+
+	if opts.Axes {
+		for _, line := range xAxisLines(gutter, cols, ticks) {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	for _, line := range legend {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// barLabelTicks places each bar's label at the middle of its slot along
+// the category axis.
+func barLabelTicks(data barChartInput) []axisTick {
+	ticks := make([]axisTick, len(data.Bars))
+	for i, bar := range data.Bars {
+		ticks[i] = axisTick{Ratio: (float64(i) + 0.5) / float64(len(data.Bars)), Label: bar.Label}
+	}
+	return ticks
+}
+
+// renderColumnChartTerminal draws the bars as vertical columns shaded in
+// eighths, with bar labels below them. Each bar takes an equal slot with a
+// one column gap when there is room; grouped segments split the slot.
+func renderColumnChartTerminal(data barChartInput, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	if len(data.Bars) == 0 {
+		return ""
+	}
+	l := data.layout(config, opts)
+	legend := terminalLegend(l.legend, bounds.Width)
+	height := bounds.Height - len(legend)
+	if !opts.Axes {
+		height-- // the label row
+	}
+	frame := newPlotFrame(bounds.Width, height, opts.Axes)
+	scale := newValueScale(l.lo, l.hi, opts, maxYTicks(frame.rows))
+	frame.setYTicks(scale.Ticks(maxYTicks(frame.rows), l.axis))
+	frame.xTicks = barLabelTicks(data)
+
+	n := len(data.Bars)
+	eighths := float64(frame.rows * 8)
+	columns := make([][]band, frame.cols)
+	for col := range columns {
+		i := min(n-1, col*n/frame.cols)
+		start, end := i*frame.cols/n, (i+1)*frame.cols/n
+		width := end - start
+		if width >= 3 {
+			width-- // leave a gap before the next bar
+		}
+		if col-start >= width {
+			continue
+		}
+		lanes := l.lanes[i]
+		lane := lanes[min(len(lanes)-1, (col-start)*len(lanes)/width)]
+		for _, p := range lane.Pieces {
+			columns[col] = append(columns[col], band{
+				From:  scale.Ratio(p.From) * eighths,
+				To:    scale.Ratio(p.To) * eighths,
+				Color: p.Color,
+			})
+		}
+	}
+
+	lines := frame.decorate(renderColumns(columns, frame.rows))
+	if !opts.Axes {
+		lines = append(lines, xAxisLines(0, frame.cols, frame.xTicks)[1])
+	}
+	var b strings.Builder
+	for _, line := range append(lines, legend...) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderBarChartSVG draws horizontal bars with labels on the left, values
+// at the bar ends and, when requested, a labelled value axis and gridlines.
+// Negative bars extend left of a zero baseline. Grouped segments share
+// their bar's slot.
+func renderBarChartSVG(data barChartInput, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+	if len(data.Bars) == 0 {
+		return doc.String()
+	}
+	l := data.layout(config, opts)
+
+	labelChars := 0
+	for _, bar := range data.Bars {
+		labelChars = max(labelChars, utf8.RuneCountInString(bar.Label))
+	}
+	area := svgPlotArea{
+		X: svgPadding + float64(labelChars)*6.6 + 8,
+		Y: svgPadding,
+	}
+	valueChars := 0
+	for _, lanes := range l.lanes {
+		for _, lane := range lanes {
+			valueChars = max(valueChars, utf8.RuneCountInString(lane.Text))
+		}
+	}
+	area.W = float64(bounds.Width) - area.X - svgPadding - float64(valueChars)*6.6 - 6
+	area.H = float64(bounds.Height) - 2*svgPadding
+	if opts.Axes {
+		area.H -= 20
+	}
+	if len(l.legend) > 0 {
+		svgLegend(doc, l.legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+		area.Y += 24
+		area.H -= 24
+	}
+
+	scale := newValueScale(l.lo, l.hi, opts, maxSVGTicks(area.W))
+	area.drawAxes(doc, nil, scale.Ticks(maxSVGTicks(area.W), l.axis), opts, textColor)
+
+	slot := area.H / float64(len(data.Bars))
+	base := area.X + scale.Ratio(0)*area.W
+	if scale.Min < 0 {
+		doc.Add(svgLine{X1: base, Y1: area.Y, X2: base, Y2: area.Y + area.H, Stroke: textColor, Width: 1, Opacity: 0.5})
+	}
+	for i, bar := range data.Bars {
+		y := area.Y + float64(i)*slot
+		doc.Add(svgText{X: area.X - 6, Y: y + slot/2 + 4, Text: bar.Label, Fill: textColor, Size: 11, Anchor: "end"})
+
+		lanes := l.lanes[i]
+		height := slot * 0.7 / float64(len(lanes))
+		for j, lane := range lanes {
+			top := y + slot*0.15 + float64(j)*height
+			radius := 0.0
+			if len(lane.Pieces) == 1 {
+				radius = 2
+			}
+			for _, p := range lane.Pieces {
+				from, to := area.X+scale.Ratio(p.From)*area.W, area.X+scale.Ratio(p.To)*area.W
+				title := bar.Label + ": " + lane.Text
+				if p.Label != "" {
+					title = bar.Label + " " + p.Label + ": " + opts.Numbers.Format(p.Value)
+				}
+				doc.Add(svgRect{X: math.Min(from, to), Y: top, W: math.Abs(to - from), H: height, Fill: p.Color, Radius: radius, Title: title})
+			}
+
+			end := area.X + scale.Ratio(lane.end())*area.W
+			textY := top + height/2 + 4
+			if lane.end() < 0 {
+				doc.Add(svgText{X: end - 6, Y: textY, Text: lane.Text, Fill: textColor, Size: 11, Anchor: "end"})
+			} else {
+				doc.Add(svgText{X: end + 6, Y: textY, Text: lane.Text, Fill: textColor, Size: 11})
+			}
+		}
+	}
+	return doc.String()
+}
+
+// renderColumnChartSVG draws the bars as vertical columns with their
+// labels below and the value axis on the left when requested.
+func renderColumnChartSVG(data barChartInput, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+	if len(data.Bars) == 0 {
+		return doc.String()
+	}
+	l := data.layout(config, opts)
+	area := newSVGPlotArea(bounds, len(l.legend) > 0, opts.Axes)
+	if len(l.legend) > 0 {
+		svgLegend(doc, l.legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+	}
+
+	scale := newValueScale(l.lo, l.hi, opts, maxSVGTicks(area.H))
+	labels := barLabelTicks(data)
+	if opts.Axes {
+		area.drawAxes(doc, scale.Ticks(maxSVGTicks(area.H), l.axis), labels, opts, textColor)
+	} else {
+		area.H -= 20
+		area.drawAxes(doc, scale.Ticks(maxSVGTicks(area.H), l.axis), nil, opts, textColor)
+		for _, t := range labels {
+			doc.Add(svgText{X: area.X + t.Ratio*area.W, Y: area.Y + area.H + 15, Text: t.Label, Fill: textColor, Size: 10, Anchor: "middle"})
+		}
+	}
+
+	slot := area.W / float64(len(data.Bars))
+	base := area.Y + (1-scale.Ratio(0))*area.H
+	if scale.Min < 0 {
+		doc.Add(svgLine{X1: area.X, Y1: base, X2: area.X + area.W, Y2: base, Stroke: textColor, Width: 1, Opacity: 0.5})
+	}
+	for i, bar := range data.Bars {
+		lanes := l.lanes[i]
+		width := slot * 0.7 / float64(len(lanes))
+		for j, lane := range lanes {
+			left := area.X + float64(i)*slot + slot*0.15 + float64(j)*width
+			radius := 0.0
+			if len(lane.Pieces) == 1 {
+				radius = 2
+			}
+			for _, p := range lane.Pieces {
+				from, to := area.Y+(1-scale.Ratio(p.From))*area.H, area.Y+(1-scale.Ratio(p.To))*area.H
+				title := bar.Label + ": " + lane.Text
+				if p.Label != "" {
+					title = bar.Label + " " + p.Label + ": " + opts.Numbers.Format(p.Value)
+				}
+				doc.Add(svgRect{X: left, Y: math.Min(from, to), W: width, H: math.Abs(to - from), Fill: p.Color, Radius: radius, Title: title})
+			}
+		}
+	}
+	return doc.String()
+}
+
+// barCells returns the glyph for each of cols cells of a bar that reaches
+// ratio along the value axis. Without a baseline (zeroCol < 0) the bar grows
+// from the left edge at 1/8 cell resolution. With one, positive bars grow
+// right of zeroCol and negative bars grow left of it; left-growing ends can
+// only be drawn at half cell resolution.
+func barCells(ratio float64, cols, zeroCol int) []string {
+	cells := make([]string, cols)
+	if zeroCol < 0 {
+		eighths := int(math.Round(ratio * float64(cols) * 8))
+		full, part := eighths/8, eighths%8
+		for col := 0; col < full && col < cols; col++ {
+			cells[col] = "█"
+		}
+		if part > 0 && full < cols {
+			cells[full] = barEighths[part]
+		}
+		return cells
+	}
+
+	pos := ratio * float64(cols-1)
+	if pos >= float64(zeroCol) {
+		eighths := int(math.Round((pos - float64(zeroCol)) * 8))
+		full, part := eighths/8, eighths%8
+		for i := 0; i < full && zeroCol+1+i < cols; i++ {
+			cells[zeroCol+1+i] = "█"
+		}
+		if part > 0 && zeroCol+1+full < cols {
+			cells[zeroCol+1+full] = barEighths[part]
+		}
+		return cells
+	}
+
+	halves := int(math.Round((float64(zeroCol) - pos) * 2))
+	full, half := halves/2, halves%2
+	for i := 1; i <= full && zeroCol-i >= 0; i++ {
+		cells[zeroCol-i] = "█"
+	}
+	if half > 0 && zeroCol-full-1 >= 0 {
+		cells[zeroCol-full-1] = "▐"
+	}
+	return cells
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func TestBarChartApply(t *testing.T) {
+	in := barChartInput{Mode: "stacked"}
+	if err := in.apply(barChartOptions{Mode: "percent", Orientation: "vertical"}); err != nil {
+		t.Fatal(err)
+	}
+	if in.Mode != "percent" || in.Orientation != "vertical" {
+		t.Errorf("command line settings not applied: mode %q, orientation %q", in.Mode, in.Orientation)
+	}
+	for _, opts := range []barChartOptions{{Mode: "layered"}, {Orientation: "diagonal"}} {
+		if err := (&barChartInput{}).apply(opts); err == nil {
+			t.Errorf("apply(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+func TestBarSegmentLanes(t *testing.T) {
+	var in barChartInput
+	if err := json.Unmarshal([]byte(`{
+		"segments": [{"label": "a"}, {"label": "b"}, {"label": "c"}],
+		"bars": [{"label": "x", "values": [3, -1, 1]}]}`), &in); err != nil {
+		t.Fatal(err)
+	}
+	segments := in.segments(dataviz.RenderConfig{Theme: "default"})
+	nf := defaultChartOptions().Numbers
+	tests := []struct {
+		mode  string
+		spans [][][2]float64 // per lane, per piece
+	}{
+		{"stacked", [][][2]float64{{{0, 3}, {-1, 0}, {3, 4}}}},
+		{"grouped", [][][2]float64{{{0, 3}}, {{-1, 0}}, {{0, 1}}}},
+		{"percent", [][][2]float64{{{0, 0.6}, {-0.2, 0}, {0.6, 0.8}}}},
+	}
+	for _, tt := range tests {
+		in.Mode = tt.mode
+		lanes := in.lanes(in.Bars[0], segments, nf)
+		if len(lanes) != len(tt.spans) {
+			t.Errorf("%s: %d lanes, want %d", tt.mode, len(lanes), len(tt.spans))
+			continue
+		}
+		for i, lane := range lanes {
+			for j, p := range lane.Pieces {
+				want := tt.spans[i][j]
+				if p.From != want[0] || p.To != want[1] {
+					t.Errorf("%s: lane %d piece %d spans %v..%v, want %v..%v", tt.mode, i, j, p.From, p.To, want[0], want[1])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchManifest lists the charts for one batch run. The manifest is either
+// an array of entries or an object with the entries under "charts", the
+// number of workers, and defaults applied to every entry.
+type batchManifest struct {
+	Workers  int          `json:"workers"`
+	Defaults batchEntry   `json:"defaults"`
+	Charts   []batchEntry `json:"charts"`
+}
+
+// batchEntry describes one chart. Args holds any further command line
+// options, e.g. ["-axes", "-grid"]. Relative data, database and output
+// paths are resolved against the manifest's directory.
+type batchEntry struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Data   string   `json:"data"`
+	Format string   `json:"format"`
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Theme  string   `json:"theme"`
+	Color  string   `json:"color"`
+	Output string   `json:"output"`
+	Args   []string `json:"args"`
+}
+
+// args converts the entry into command line options.
+func (e batchEntry) args() []string {
+	var args []string
+	add := func(name, value string) {
+		if value != "" {
+			args = append(args, "-"+name, value)
+		}
+	}
+	add("type", e.Type)
+	add("data", e.Data)
+	add("format", e.Format)
+	if e.Width > 0 {
+		add("width", strconv.Itoa(e.Width))
+	}
+	if e.Height > 0 {
+		add("height", strconv.Itoa(e.Height))
+	}
+	add("theme", e.Theme)
+	add("color", e.Color)
+	add("output", e.Output)
+	return append(args, e.Args...)
+}
+
+// batchResult is the outcome of rendering one entry.
+type batchResult struct {
+	name     string
+	vizType  string
+	output   string
+	err      error
+	duration time.Duration
+}
+
+// parseManifest reads a manifest in either of its forms.
+func parseManifest(data []byte) (batchManifest, error) {
+	var manifest batchManifest
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err := json.Unmarshal(data, &manifest.Charts)
+		return manifest, err
+	}
+	err := json.Unmarshal(data, &manifest)
+	return manifest, err
+}
+
+// batch runs the batch subcommand and returns the process exit code: 0
+// when every chart was written, 1 otherwise.
+func batch(args []string) int {
+	fs := flag.NewFlagSet("batch", flag.ExitOnError)
+	workers := fs.Int("workers", 0, "Charts to render at once")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: viz-cli batch [-workers N] manifest.json")
+		return 2
+	}
+
+	path := fs.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading manifest: %v\n", err)
+		return 1
+	}
+	manifest, err := parseManifest(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing manifest: %v\n", err)
+		return 1
+	}
+	if *workers > 0 {
+		manifest.Workers = *workers
+	}
+	if manifest.Workers <= 0 {
+		manifest.Workers = runtime.NumCPU()
+	}
+
+	results := renderBatch(manifest, filepath.Dir(path))
+	return reportBatch(os.Stdout, results)
+}
+
+// renderBatch renders every entry with a pool of manifest.Workers workers
+// and returns the results in manifest order.
+func renderBatch(manifest batchManifest, dir string) []batchResult {
+	results := make([]batchResult, len(manifest.Charts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(manifest.Workers, len(manifest.Charts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = renderEntry(manifest.Defaults, manifest.Charts[i], dir)
+				if results[i].name == "" {
+					results[i].name = fmt.Sprintf("chart %d", i+1)
+				}
+			}
+		}()
+	}
+	for i := range manifest.Charts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// renderEntry renders one chart to its output file.
+func renderEntry(defaults, entry batchEntry, dir string) batchResult {
+	start := time.Now()
+	result := batchResult{name: entry.Name}
+	finish := func(err error) batchResult {
+		result.err = err
+		result.duration = time.Since(start)
+		return result
+	}
+
+	fs := flag.NewFlagSet(entry.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := parseFlags(fs, append(defaults.args(), entry.args()...))
+	result.vizType, result.output = cfg.vizType, cfg.output
+	if result.name == "" {
+		result.name = cfg.output
+	}
+	switch {
+	case err != nil:
+		return finish(err)
+	case cfg.sql.Query == "" && (cfg.dataFile == "" || cfg.dataFile == "-"):
+		return finish(fmt.Errorf("no data file"))
+	case cfg.output == "" || cfg.output == "-":
+		return finish(fmt.Errorf("no output file"))
+	case cfg.watch.Enabled:
+		return finish(fmt.Errorf("-watch is not supported in batches"))
+	}
+	if cfg.sql.Query != "" {
+		cfg.sql.DB = resolveDB(dir, cfg.sql.DB)
+	} else {
+		cfg.dataFile = resolvePath(dir, cfg.dataFile)
+	}
+	cfg.output = resolvePath(dir, cfg.output)
+
+	data, err := loadData(cfg)
+	if err != nil {
+		return finish(fmt.Errorf("reading data: %w", err))
+	}
+	output, err := render(cfg, data)
+	if err != nil {
+		return finish(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.output), 0o755); err != nil {
+		return finish(err)
+	}
+	return finish(writeOutput(cfg.output, output))
+}
+
+// resolvePath makes a manifest path relative to the manifest's directory.
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) || isURL(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// reportBatch prints one line per entry and a summary naming the failed
+// entries, and returns the exit code.
+func reportBatch(w io.Writer, results []batchResult) int {
+	nameWidth, typeWidth := 0, 0
+	for _, r := range results {
+		nameWidth = max(nameWidth, len(r.name))
+		typeWidth = max(typeWidth, len(r.vizType))
+	}
+
+	var failed []string
+	for _, r := range results {
+		status, detail := "ok  ", r.duration.Round(time.Millisecond).String()
+		if r.err != nil {
+			status, detail = "FAIL", r.err.Error()
+			failed = append(failed, r.name)
+		}
+		fmt.Fprintf(w, "%s  %-*s  %-*s  %s\n", status, nameWidth, r.name, typeWidth, r.vizType, detail)
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "%d of %d charts failed: %s\n", len(failed), len(results), strings.Join(failed, ", "))
+		return 1
+	}
+	fmt.Fprintf(w, "%d charts rendered\n", len(results))
+	return 0
+}
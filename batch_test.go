@@ -0,0 +1,101 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		workers int
+		charts  []string
+	}{
+		{"array", `[{"name": "a"}, {"name": "b"}]`, 0, []string{"a", "b"}},
+		{"object", `{"workers": 2, "defaults": {"theme": "nord"}, "charts": [{"name": "c"}]}`, 2, []string{"c"}},
+		{"leading space", "\n  [{\"name\": \"d\"}]", 0, []string{"d"}},
+	}
+	for _, tt := range tests {
+		m, err := parseManifest([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var names []string
+		for _, c := range m.Charts {
+			names = append(names, c.Name)
+		}
+		if m.Workers != tt.workers || !slices.Equal(names, tt.charts) {
+			t.Errorf("%s: workers %d, charts %q, want %d, %q", tt.name, m.Workers, names, tt.workers, tt.charts)
+		}
+	}
+	if _, err := parseManifest([]byte(`{"charts": {}}`)); err == nil {
+		t.Error("charts object parsed, want an error")
+	}
+}
+
+func TestBatchEntryArgs(t *testing.T) {
+	e := batchEntry{Type: "gauge", Data: "g.json", Width: 80, Output: "g.svg", Args: []string{"-axes"}}
+	got := e.args()
+	want := []string{"-type", "gauge", "-data", "g.json", "-width", "80", "-output", "g.svg", "-axes"}
+	if !slices.Equal(got, want) {
+		t.Errorf("args() = %q, want %q", got, want)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"data.json", filepath.Join("reports", "data.json")},
+		{"../shared/data.json", filepath.Join("shared", "data.json")},
+		{"/abs/data.json", "/abs/data.json"},
+		{"https://example.com/data.json", "https://example.com/data.json"},
+	}
+	for _, tt := range tests {
+		if got := resolvePath("reports", tt.path); got != tt.want {
+			t.Errorf("resolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRenderBatchRelativeToManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "gauge.json"), []byte(`{"value": 40, "max": 100}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := parseManifest([]byte(`{"workers": 2, "defaults": {"format": "svg"}, "charts": [
+		{"name": "ok", "type": "gauge", "data": "gauge.json", "output": "out/gauge.svg"},
+		{"name": "missing", "type": "gauge", "data": "nope.json", "output": "out/nope.svg"},
+		{"type": "gauge", "data": "gauge.json"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := renderBatch(m, dir)
+	if results[0].err != nil {
+		t.Errorf("ok: %v", results[0].err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "gauge.svg")); err != nil {
+		t.Errorf("output not written next to the manifest: %v", err)
+	}
+	if !errors.Is(results[1].err, os.ErrNotExist) {
+		t.Errorf("missing: err = %v, want a missing file", results[1].err)
+	}
+	if results[2].name != "chart 3" || results[2].err == nil {
+		t.Errorf("unnamed entry = %q, %v, want a numbered failure", results[2].name, results[2].err)
+	}
+
+	var out bytes.Buffer
+	if code := reportBatch(&out, results); code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "2 of 3 charts failed: missing, chart 3") {
+		t.Errorf("summary does not name the failures:\n%s", out.String())
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// lowerBlocks are the block glyphs filled from the bottom, indexed by the
+// number of eighths filled.
+var lowerBlocks = []string{" ", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// band is a vertical run of one color within a column, measured in eighths
+// of a character cell from the bottom of the plot.
+type band struct {
+	From, To float64
+	Color    string
+}
+
+// verticalCell renders a character cell whose lower k eighths show the lower
+// color and the rest the upper color. An empty color leaves that part of the
+// cell as terminal background.
+func verticalCell(k int, lower, upper string) string {
+	if k >= 8 {
+		upper = lower
+	}
+	switch {
+	case lower == "" && upper == "":
+		return " "
+	case lower == upper:
+		return colorize("█", lower)
+	case upper == "":
+		return ansiColor(lower) + lowerBlocks[k] + ansiReset
+	case lower == "":
+		// Reverse video paints the glyph in the background and the rest of
+		// the cell in the foreground color.
+		return ansiReverse + ansiColor(upper) + lowerBlocks[k] + ansiReset
+	default:
+		return ansiColor(lower) + ansiBackground(upper) + lowerBlocks[k] + ansiReset
+	}
+}
+
+// colorAt returns the color of the band covering position e, or "".
+func colorAt(bands []band, e float64) string {
+	for _, b := range bands {
+		lo, hi := math.Min(b.From, b.To), math.Max(b.From, b.To)
+		if e >= lo && e < hi {
+			return b.Color
+		}
+	}
+	return ""
+}
+
+// renderColumns draws one stack of bands per column into rows lines, top
+// line first. Each cell shows at most two colors, split at the first color
+// change from its bottom.
+func renderColumns(columns [][]band, rows int) []string {
+	lines := make([]string, rows)
+	for line := range lines {
+		var b strings.Builder
+		row := rows - 1 - line
+		for _, bands := range columns {
+			base := float64(row * 8)
+			lower := colorAt(bands, base+0.5)
+			k := 1
+			for k < 8 && colorAt(bands, base+float64(k)+0.5) == lower {
+				k++
+			}
+			b.WriteString(verticalCell(k, lower, colorAt(bands, base+7.5)))
+		}
+		lines[line] = b.String()
+	}
+	return lines
+}
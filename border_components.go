@@ -14,6 +14,12 @@ type BorderStyle struct {
 	BottomRight string
 	Horizontal  string
 	Vertical    string
+	// Junctions where inner lines meet, used by tables
+	TeeDown  string // ┬
+	TeeUp    string // ┴
+	TeeRight string // ├
+	TeeLeft  string // ┤
+	Cross    string // ┼
 	// Title bar style
 	TitleTopLeft     string
 	TitleTopRight    string
@@ -32,6 +38,11 @@ var (
 		BottomRight:      "┘",
 		Horizontal:       "─",
 		Vertical:         "│",
+		TeeDown:          "┬",
+		TeeUp:            "┴",
+		TeeRight:         "├",
+		TeeLeft:          "┤",
+		Cross:            "┼",
 		TitleTopLeft:     "╔",
 		TitleTopRight:    "╗",
 		TitleBottomLeft:  "╚",
@@ -39,8 +50,103 @@ var (
 		TitleHorizontal:  "═",
 		TitleVertical:    "║",
 	}
+
+	// RoundedBorderStyle is LightBorderStyle with rounded corners
+	RoundedBorderStyle = BorderStyle{
+		TopLeft:          "╭",
+		TopRight:         "╮",
+		BottomLeft:       "╰",
+		BottomRight:      "╯",
+		Horizontal:       "─",
+		Vertical:         "│",
+		TeeDown:          "┬",
+		TeeUp:            "┴",
+		TeeRight:         "├",
+		TeeLeft:          "┤",
+		Cross:            "┼",
+		TitleTopLeft:     "╔",
+		TitleTopRight:    "╗",
+		TitleBottomLeft:  "╚",
+		TitleBottomRight: "╝",
+		TitleHorizontal:  "═",
+		TitleVertical:    "║",
+	}
+
+	// HeavyBorderStyle uses heavy box-drawing characters
+	HeavyBorderStyle = BorderStyle{
+		TopLeft:          "┏",
+		TopRight:         "┓",
+		BottomLeft:       "┗",
+		BottomRight:      "┛",
+		Horizontal:       "━",
+		Vertical:         "┃",
+		TeeDown:          "┳",
+		TeeUp:            "┻",
+		TeeRight:         "┣",
+		TeeLeft:          "┫",
+		Cross:            "╋",
+		TitleTopLeft:     "┏",
+		TitleTopRight:    "┓",
+		TitleBottomLeft:  "┗",
+		TitleBottomRight: "┛",
+		TitleHorizontal:  "━",
+		TitleVertical:    "┃",
+	}
+
+	// DoubleBorderStyle uses double-line box-drawing characters
+	DoubleBorderStyle = BorderStyle{
+		TopLeft:          "╔",
+		TopRight:         "╗",
+		BottomLeft:       "╚",
+		BottomRight:      "╝",
+		Horizontal:       "═",
+		Vertical:         "║",
+		TeeDown:          "╦",
+		TeeUp:            "╩",
+		TeeRight:         "╠",
+		TeeLeft:          "╣",
+		Cross:            "╬",
+		TitleTopLeft:     "╔",
+		TitleTopRight:    "╗",
+		TitleBottomLeft:  "╚",
+		TitleBottomRight: "╝",
+		TitleHorizontal:  "═",
+		TitleVertical:    "║",
+	}
+
+	// ASCIIBorderStyle uses plain ASCII for terminals without box drawing
+	ASCIIBorderStyle = BorderStyle{
+		TopLeft:          "+",
+		TopRight:         "+",
+		BottomLeft:       "+",
+		BottomRight:      "+",
+		Horizontal:       "-",
+		Vertical:         "|",
+		TeeDown:          "+",
+		TeeUp:            "+",
+		TeeRight:         "+",
+		TeeLeft:          "+",
+		Cross:            "+",
+		TitleTopLeft:     "+",
+		TitleTopRight:    "+",
+		TitleBottomLeft:  "+",
+		TitleBottomRight: "+",
+		TitleHorizontal:  "=",
+		TitleVertical:    "|",
+	}
 )
 
+// borderStyles maps the names accepted by -border to their styles. "none"
+// is the zero style, which draws no lines at all.
+var borderStyles = map[string]BorderStyle{
+	"light":   LightBorderStyle,
+	"rounded": RoundedBorderStyle,
+	"heavy":   HeavyBorderStyle,
+	"double":  DoubleBorderStyle,
+	"ascii":   ASCIIBorderStyle,
+	"none":    {},
+}
+
 // TitleBar creates a title bar with borders
 type TitleBar struct {
 	Title       string
@@ -113,7 +219,7 @@ func (b *Box) RenderTop() string {
 	prefixLen := 3 // "┌─ "
 	suffixLen := 1 // "┐"
 
-	hLineLen := b.Width - len(b.Label) - prefixLen - suffixLen
+	hLineLen := b.Width - int(text.NewTerminal().Width(b.Label)) - prefixLen - suffixLen
 	return colorCode + labelPrefix + resetCode + b.Label + colorCode + strings.Repeat(b.Style.Horizontal, hLineLen) + labelSuffix + resetCode + "\n"
 }
 
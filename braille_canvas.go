@@ -0,0 +1,174 @@
+package main
+
+import "strings"
+
+// brailleDots maps a dot position within a 2x4 braille cell to its bit in
+// the Unicode braille pattern block (U+2800).
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// colorCanvas is a braille canvas where each character cell carries its own
+// color, so several series can share one plot area. Pixel coordinates have
+// their origin at the top left; each cell is 2 pixels wide and 4 pixels tall.
+// Cells without dots can hold a guide glyph, drawn dim, for gridlines, or
+// in a color of its own, for annotations. Label text is drawn over dots.
+type colorCanvas struct {
+	cols        int
+	rows        int
+	cells       []rune
+	colors      []string
+	guides      []rune
+	guideColors []string
+	labels      []bool
+}
+
+// newColorCanvas creates a canvas of cols x rows character cells.
+func newColorCanvas(cols, rows int) *colorCanvas {
+	if cols < 1 {
+		cols = 1
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	return &colorCanvas{
+		cols:        cols,
+		rows:        rows,
+		cells:       make([]rune, cols*rows),
+		colors:      make([]string, cols*rows),
+		guides:      make([]rune, cols*rows),
+		guideColors: make([]string, cols*rows),
+		labels:      make([]bool, cols*rows),
+	}
+}
+
+// PixelWidth returns the horizontal resolution in braille dots.
+func (c *colorCanvas) PixelWidth() int { return c.cols * 2 }
+
+// PixelHeight returns the vertical resolution in braille dots.
+func (c *colorCanvas) PixelHeight() int { return c.rows * 4 }
+
+// Set turns on a single dot. The most recent color drawn into a cell wins.
+func (c *colorCanvas) Set(x, y int, color string) {
+	if x < 0 || y < 0 || x >= c.PixelWidth() || y >= c.PixelHeight() {
+		return
+	}
+	i := (y/4)*c.cols + x/2
+	c.cells[i] |= brailleDots[y%4][x%2]
+	c.colors[i] = color
+}
+
+// Guide places a glyph in a character cell that is shown only while the
+// cell has no dots set. It leaves label text in place.
+func (c *colorCanvas) Guide(col, row int, r rune) {
+	if col < 0 || row < 0 || col >= c.cols || row >= c.rows || c.labels[row*c.cols+col] {
+		return
+	}
+	c.guides[row*c.cols+col] = r
+	c.guideColors[row*c.cols+col] = ""
+}
+
+// Mark places a guide glyph drawn in the given color rather than dim.
+func (c *colorCanvas) Mark(col, row int, r rune, color string) {
+	if col < 0 || row < 0 || col >= c.cols || row >= c.rows {
+		return
+	}
+	c.guides[row*c.cols+col] = r
+	c.guideColors[row*c.cols+col] = color
+}
+
+// Label places a glyph in the given color that is shown even over dots.
+func (c *colorCanvas) Label(col, row int, r rune, color string) {
+	c.Mark(col, row, r, color)
+	if col < 0 || row < 0 || col >= c.cols || row >= c.rows {
+		return
+	}
+	c.labels[row*c.cols+col] = true
+}
+
+// Line draws a straight line between two pixels.
+func (c *colorCanvas) Line(x0, y0, x1, y1 int, color string) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		c.Set(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// Lines returns the canvas as rows of braille characters. Runs of cells
+// with the same style share one escape sequence.
+func (c *colorCanvas) Lines() []string {
+	lines := make([]string, c.rows)
+	for row := range lines {
+		var b strings.Builder
+		current := ""
+		setStyle := func(style string) {
+			if style == current {
+				return
+			}
+			if current != "" {
+				b.WriteString(ansiReset)
+			}
+			b.WriteString(style)
+			current = style
+		}
+		for col := 0; col < c.cols; col++ {
+			i := row*c.cols + col
+			switch {
+			case c.labels[i]:
+				setStyle(ansiColor(c.guideColors[i]))
+				b.WriteRune(c.guides[i])
+			case c.cells[i] != 0:
+				setStyle(ansiColor(c.colors[i]))
+				b.WriteRune(0x2800 + c.cells[i])
+			case c.guides[i] != 0 && c.guideColors[i] != "":
+				setStyle(ansiColor(c.guideColors[i]))
+				b.WriteRune(c.guides[i])
+			case c.guides[i] != 0:
+				setStyle(ansiDim)
+				b.WriteRune(c.guides[i])
+			default:
+				setStyle("")
+				b.WriteRune(' ')
+			}
+		}
+		setStyle("")
+		lines[row] = b.String()
+	}
+	return lines
+}
+
+// Render returns the canvas with each row terminated by a newline.
+func (c *colorCanvas) Render() string {
+	return strings.Join(c.Lines(), "\n") + "\n"
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
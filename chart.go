@@ -0,0 +1,18 @@
+package main
+
+import "github.com/SCKelemen/dataviz"
+
+// chart is a visualization that viz-cli draws itself rather than through a
+// dataviz renderer, in both output formats.
+type chart interface {
+	Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string
+	SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string
+}
+
+// renderChart draws c in the format produced by r.
+func renderChart(r dataviz.Renderer, c chart, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) dataviz.Output {
+	if _, ok := r.(*dataviz.SVGRenderer); ok {
+		return dataviz.SVGOutput(c.SVG(bounds, config, opts))
+	}
+	return dataviz.TerminalOutput{Content: c.Terminal(bounds, config, opts)}
+}
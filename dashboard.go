@@ -1,55 +1,115 @@
+//go:build dashboard
+
 package main
 
 import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/SCKelemen/cli/renderer"
 	"github.com/SCKelemen/color"
 	"github.com/SCKelemen/dataviz"
 	design "github.com/SCKelemen/design-system"
 	"github.com/SCKelemen/layout"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 type tickMsg time.Time
 
-type viewMode int
+// snapshotMsg reports the files written for a snapshot, by their common
+// path without extension, or why they could not be.
+type snapshotMsg struct {
+	base string
+	err  error
+}
 
-const (
-	viewHeatmap viewMode = iota
-	viewLineGraph
-	viewBarChart
-	viewMulti
-)
+// noticeDuration is how long a notice replaces the controls in the footer.
+const noticeDuration = 5 * time.Second
 
 type dashboardModel struct {
 	width      int
 	height     int
 	ready      bool
 	counter    int
-	mode       viewMode
+	views      []dashboardView
+	mode       int // index of the view shown
 	data       *dashboardData
 	paused     bool
 	colorTheme string
+
+	snapshotDir string
+	notice      string
+	noticeAt    time.Time
+
+	alerts     *alerts
+	showAlerts bool // the alert history is open over the view
 }
 
 type dashboardData struct {
 	heatmap   dataviz.HeatmapData
 	lineGraph dataviz.LineGraphData
 	barChart  dataviz.BarChartData
+	window    timeRange // the part of lineGraph the metrics panels show
+	budget    float64   // error budget remaining, percent
+	quota     float64   // API quota used, percent
+	latency   float64   // p99 latency, ms
+	// Recent readings of the figures above, oldest first
+	budgetTrend, quotaTrend, latencyTrend []dataviz.TimeSeriesData
+
 	lastUpdate time.Time
 }
 
-func initialDashboardModel() dashboardModel {
-	return dashboardModel{
-		mode:       viewMulti,
-		data:       generateInitialData(),
-		colorTheme: "default",
+// The KPI strip shows the last kpiTrendLength readings of each figure,
+// taken every kpiTrendStep.
+const (
+	kpiTrendLength = 60
+	kpiTrendStep   = 5 * time.Second
+)
+
+func initialDashboardModel(snapshotDir string, panels []string, rules []alertRule, hook string) (dashboardModel, error) {
+	data := generateInitialData()
+	views, err := newDashboardViews(data, panels)
+	if err != nil {
+		return dashboardModel{}, err
+	}
+	watch, err := newAlerts(rules, hook, data)
+	if err != nil {
+		return dashboardModel{}, err
+	}
+	m := dashboardModel{
+		views:       views,
+		data:        data,
+		colorTheme:  "default",
+		snapshotDir: snapshotDir,
+		alerts:      watch,
 	}
+	// Start on the multi view.
+	for i, v := range views {
+		if len(v.panels) > 1 {
+			m.mode = i
+			break
+		}
+	}
+	return m, nil
+}
+
+// metricAt is the simulated metric at t: a daily cycle on a slow drift,
+// with noise.
+func metricAt(t time.Time) int {
+	day := float64(t.Unix()) / (24 * 60 * 60)
+	return int(50 + 20*math.Sin(day/3) + 15*math.Sin(day*2*math.Pi) + float64(rand.Intn(15)))
 }
 
 func generateInitialData() *dashboardData {
@@ -66,14 +126,13 @@ func generateInitialData() *dashboardData {
 		}
 	}
 
-	// Generate line graph data (last 30 days)
-	linePoints := make([]dataviz.TimeSeriesData, 30)
-	for i := 0; i < 30; i++ {
-		date := now.AddDate(0, 0, -30+i)
-		value := int(50 + 30*math.Sin(float64(i)/5) + float64(rand.Intn(20)))
+	// Generate the metrics history (last 30 days)
+	linePoints := make([]dataviz.TimeSeriesData, historyLength/historyStep+1)
+	for i := range linePoints {
+		date := now.Add(-historyLength + time.Duration(i)*historyStep)
 		linePoints[i] = dataviz.TimeSeriesData{
 			Date:  date,
-			Value: value,
+			Value: metricAt(date),
 		}
 	}
 
@@ -87,7 +146,7 @@ func generateInitialData() *dashboardData {
 		}
 	}
 
-	return &dashboardData{
+	data := &dashboardData{
 		heatmap: dataviz.HeatmapData{
 			Days:      heatmapDays,
 			StartDate: now.AddDate(0, 0, -365),
@@ -105,8 +164,16 @@ func generateInitialData() *dashboardData {
 			Color: "#FF9800",
 			Label: "Languages",
 		},
+		window:     timeRange{span: rangePresets["D"], live: true},
+		budget:     80,
+		quota:      45,
+		latency:    180,
 		lastUpdate: now,
 	}
+	for i := kpiTrendLength; i > 0; i-- {
+		data.drift(now.Add(-time.Duration(i) * kpiTrendStep))
+	}
+	return data
 }
 
 func (m dashboardModel) Init() tea.Cmd {
@@ -123,20 +190,25 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "q", "ctrl+c", "esc":
+		case "esc":
+			if m.showAlerts {
+				m.showAlerts = false
+				return m, nil
+			}
 			return m, tea.Quit
-		case "1":
-			m.mode = viewHeatmap
-		case "2":
-			m.mode = viewLineGraph
-		case "3":
-			m.mode = viewBarChart
-		case "4", "m":
-			m.mode = viewMulti
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "a":
+			m.showAlerts = !m.showAlerts
 		case "p", " ":
 			m.paused = !m.paused
 		case "r":
-			m.data = generateInitialData()
+			// Panels keep the data pointer, so refresh it in place.
+			*m.data = *generateInitialData()
+		case "s":
+			if m.ready {
+				return m, m.snapshot()
+			}
 		case "t":
 			// Toggle theme
 			if m.colorTheme == "default" {
@@ -144,7 +216,40 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				m.colorTheme = "default"
 			}
+		default:
+			for i, v := range m.views {
+				if slices.Contains(v.keys, msg.String()) {
+					m.mode = i
+					return m, nil
+				}
+			}
+			// Other keys go to the first panel of the view that uses them.
+			for _, p := range m.views[m.mode].panels {
+				if p.Update(msg) {
+					break
+				}
+			}
+		}
+
+	case tea.MouseMsg:
+		if p, ok := m.panelAt(msg.Y); ok {
+			p.Update(msg)
 		}
+		return m, nil
+
+	case snapshotMsg:
+		if msg.err != nil {
+			m.notice = fmt.Sprintf("Snapshot failed: %v", msg.err)
+		} else {
+			m.notice = fmt.Sprintf("Saved %s.{json,svg,ans}", msg.base)
+		}
+		m.noticeAt = time.Now()
+		return m, nil
+
+	case alertHookMsg:
+		m.notice = fmt.Sprintf("Alert hook failed: %v", msg.err)
+		m.noticeAt = time.Now()
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -159,6 +264,19 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.counter%5 == 0 {
 				m.updateData()
 			}
+			for _, v := range m.views {
+				for _, p := range v.panels {
+					p.Update(msg)
+				}
+			}
+			events := m.alerts.check(time.Time(msg))
+			for _, e := range events {
+				if e.firing {
+					m.notice = fmt.Sprintf("ALERT %s (%s = %g)", e.rule.spec, e.rule.metric, e.value)
+					m.noticeAt = time.Now()
+				}
+			}
+			return m, tea.Batch(tickCmd(), m.alerts.notify(events))
 		}
 		return m, tickCmd()
 	}
@@ -169,29 +287,71 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *dashboardModel) updateData() {
 	now := time.Now()
 
-	// Add new point to line graph (shift old data)
-	if len(m.data.lineGraph.Points) > 0 {
-		m.data.lineGraph.Points = append(m.data.lineGraph.Points[1:], dataviz.TimeSeriesData{
-			Date:  now,
-			Value: int(50 + 30*math.Sin(float64(m.counter)/5) + float64(rand.Intn(20))),
-		})
-	}
+	// Add new point to the history, dropping the oldest beyond the limit
+	points := append(m.data.lineGraph.Points, dataviz.TimeSeriesData{
+		Date:  now,
+		Value: metricAt(now),
+	})
+	m.data.lineGraph.Points = points[max(0, len(points)-maxHistoryPoints):]
 
 	// Update bar chart values slightly
 	for i := range m.data.barChart.Bars {
 		change := rand.Intn(11) - 5 // -5 to +5
 		m.data.barChart.Bars[i].Value += change
-		if m.data.barChart.Bars[i].Value < 10 {
-			m.data.barChart.Bars[i].Value = 10
-		}
-		if m.data.barChart.Bars[i].Value > 200 {
-			m.data.barChart.Bars[i].Value = 200
-		}
 	}
 
+	m.data.drift(now)
 	m.data.lastUpdate = now
 }
 
+// drift moves the service level figures on and records them as read at
+// now, keeping the last kpiTrendLength readings.
+func (d *dashboardData) drift(now time.Time) {
+	d.budget = math.Max(0, math.Min(100, d.budget+rand.Float64()*4-2.2))
+	d.quota = math.Max(0, math.Min(100, d.quota+rand.Float64()*3-1.2))
+	d.latency = math.Max(50, math.Min(400, d.latency+rand.Float64()*40-20))
+
+	record := func(trend []dataviz.TimeSeriesData, value float64) []dataviz.TimeSeriesData {
+		trend = append(trend, dataviz.TimeSeriesData{Date: now, Value: int(math.Round(value))})
+		return trend[max(0, len(trend)-kpiTrendLength):]
+	}
+	d.budgetTrend = record(d.budgetTrend, d.budget)
+	d.quotaTrend = record(d.quotaTrend, d.quota)
+	d.latencyTrend = record(d.latencyTrend, d.latency)
+}
+
+// kpis returns the cards of the KPI strip: the average of the metrics in
+// the time range against the range before it, and the service level
+// figures against their oldest reading.
+func (d *dashboardData) kpis() []kpiCard {
+	history := d.lineGraph.Points
+	average := func(points []dataviz.TimeSeriesData) float64 {
+		sum := 0.0
+		for _, p := range points {
+			sum += float64(p.Value)
+		}
+		return sum / float64(max(1, len(points)))
+	}
+	current := average(d.window.points(history, 0))
+	previous := average(timeRange{span: d.window.span, end: d.window.endAt(history).Add(-d.window.span)}.points(history, 0))
+	metrics := newKPICard("Metrics avg", fmt.Sprintf("%.1f", current), current, previous, d.window.points(history, kpiTrendLength))
+	metrics.Subtitle = "vs prior " + formatSpan(d.window.span)
+
+	// reading compares a figure with the first of its trend.
+	reading := func(title, value string, current float64, trend []dataviz.TimeSeriesData, lowerIsBetter bool) kpiCard {
+		card := newKPICard(title, value, current, float64(trend[0].Value), trend)
+		card.Subtitle = "vs " + formatSpan(trend[len(trend)-1].Date.Sub(trend[0].Date).Round(time.Minute)) + " ago"
+		card.LowerIsBetter = lowerIsBetter
+		return card
+	}
+	return []kpiCard{
+		metrics,
+		reading("Error budget", fmt.Sprintf("%.1f%%", d.budget), d.budget, d.budgetTrend, false),
+		reading("API quota", fmt.Sprintf("%.1f%%", d.quota), d.quota, d.quotaTrend, true),
+		reading("p99 latency", fmt.Sprintf("%.0f ms", d.latency), d.latency, d.latencyTrend, true),
+	}
+}
+
 func (m dashboardModel) View() string {
 	if !m.ready {
 		return "Initializing dashboard...\n\nPress any key to continue"
@@ -199,7 +359,37 @@ func (m dashboardModel) View() string {
 
 	screen := renderer.NewScreen(m.width, m.height)
 	ctx := layout.NewLayoutContext(float64(m.width), float64(m.height), 16)
+	config := m.renderConfig()
+
+	view := m.views[m.mode]
+	var output string
+	if len(view.panels) > 1 {
+		output = m.renderMultiView(screen, ctx, config, view)
+	} else {
+		output = m.renderSingleView(screen, ctx, config, view.Title(), view.names[0], view.panels[0])
+	}
+	if m.showAlerts {
+		output = m.alerts.overlay(output, m.width, m.height, m.colorTheme)
+	}
+	return output
+}
 
+// borderColor returns the border of the named panel: color, or while one
+// of its alert rules fires, red blinking with every tick.
+func (m dashboardModel) borderColor(panel, color string, config dataviz.RenderConfig) string {
+	if !m.alerts.firingOn(panel) {
+		return color
+	}
+	critical := statusColor(m.colorTheme, "critical")
+	if m.counter%2 == 1 {
+		background, _ := themeColors(config)
+		return mixColors(critical, background, 0.6)
+	}
+	return critical
+}
+
+// renderConfig returns the render settings of the current theme.
+func (m dashboardModel) renderConfig() dataviz.RenderConfig {
 	// Get theme with distinct colors
 	var tokens *design.DesignTokens
 	var accentColor string
@@ -211,27 +401,14 @@ func (m dashboardModel) View() string {
 		accentColor = "#2196F3" // Blue for default theme
 	}
 
-	config := dataviz.RenderConfig{
+	return dataviz.RenderConfig{
 		DesignTokens: tokens,
 		Color:        accentColor,
 		Theme:        m.colorTheme,
 	}
-
-	switch m.mode {
-	case viewHeatmap:
-		return m.renderSingleView(screen, ctx, config, "Contribution Heatmap", m.renderHeatmap)
-	case viewLineGraph:
-		return m.renderSingleView(screen, ctx, config, "Metrics Over Time", m.renderLineGraph)
-	case viewBarChart:
-		return m.renderSingleView(screen, ctx, config, "Language Usage", m.renderBarChart)
-	case viewMulti:
-		return m.renderMultiView(screen, ctx, config)
-	}
-
-	return ""
 }
 
-func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.LayoutContext, config dataviz.RenderConfig, title string, renderFunc func(dataviz.Bounds, dataviz.RenderConfig) string) string {
+func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.LayoutContext, config dataviz.RenderConfig, title, name string, panel Panel) string {
 	root := &layout.Node{
 		Style: layout.Style{
 			Display:       layout.DisplayFlex,
@@ -268,9 +445,10 @@ func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.La
 			Margin:  layout.Spacing{Top: layout.Ch(1)},
 		},
 	}
+	vizBorder, _ := color.ParseColor(m.borderColor(name, config.Color, config))
 	vizStyle := &renderer.Style{
 		Foreground:  &white,
-		BorderColor: &accent,
+		BorderColor: &vizBorder,
 	}
 	vizStyle.WithBorder(renderer.RoundedBorder)
 	vizStyled := renderer.NewStyledNode(vizNode, vizStyle)
@@ -282,7 +460,7 @@ func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.La
 		Width:  m.width - 4,
 		Height: m.height - 8,
 	}
-	vizContent := renderFunc(vizBounds, config)
+	vizContent := panel.Render(dataviz.NewTerminalRenderer(), vizBounds, config).String()
 	vizStyled.Content = "\n" + vizContent
 	rootStyled.AddChild(vizStyled)
 
@@ -295,7 +473,7 @@ func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.La
 		},
 	}
 	footerStyled := renderer.NewStyledNode(footerNode, nil)
-	footerStyled.Content = m.getControlsText()
+	footerStyled.Content = m.getFooterText()
 	rootStyled.AddChild(footerStyled)
 
 	constraints := layout.Tight(float64(m.width), float64(m.height))
@@ -305,14 +483,23 @@ func (m dashboardModel) renderSingleView(screen *renderer.Screen, ctx *layout.La
 	return screen.String()
 }
 
-func (m dashboardModel) renderMultiView(screen *renderer.Screen, ctx *layout.LayoutContext, config dataviz.RenderConfig) string {
+func (m dashboardModel) renderMultiView(screen *renderer.Screen, ctx *layout.LayoutContext, config dataviz.RenderConfig, view dashboardView) string {
+	strip := m.strip(view)
+	rows := []layout.GridTrack{layout.FixedTrack(layout.Ch(3))}
+	if h := strip.Height(); h > 0 {
+		rows = append(rows, layout.FixedTrack(layout.Ch(float64(h))))
+	}
+	for range view.panels {
+		rows = append(rows, layout.FractionTrack(1))
+	}
+	rows = append(rows, layout.FixedTrack(layout.Ch(2)))
 	root := &layout.Node{
 		Style: layout.Style{
 			Display:             layout.DisplayGrid,
 			Width:               layout.Vw(100),
 			Height:              layout.Vh(100),
 			GridTemplateColumns: []layout.GridTrack{layout.FractionTrack(1)},
-			GridTemplateRows:    []layout.GridTrack{layout.FixedTrack(layout.Ch(3)), layout.FractionTrack(1), layout.FractionTrack(1), layout.FractionTrack(1), layout.FixedTrack(layout.Ch(2))},
+			GridTemplateRows:    rows,
 			GridGap:             layout.Ch(1),
 			Padding:             layout.Uniform(layout.Ch(1)),
 		},
@@ -334,62 +521,43 @@ func (m dashboardModel) renderMultiView(screen *renderer.Screen, ctx *layout.Lay
 	}
 	headerStyle.WithBorder(renderer.RoundedBorder)
 	headerStyled := renderer.NewStyledNode(headerNode, headerStyle)
-	headerStyled.Content = fmt.Sprintf(" DataViz Dashboard • %dx%d • %s", m.width, m.height, m.getStatusText())
+	headerStyled.Content = fmt.Sprintf(" %s • %dx%d • %s", view.Title(), m.width, m.height, m.getStatusText())
 	rootStyled.AddChild(headerStyled)
 
-	// Heatmap panel
-	heatmapNode := &layout.Node{
-		Style: layout.Style{
-			Display: layout.DisplayBlock,
-		},
-	}
-	blue, _ := color.ParseColor("#2196F3")
-	heatmapStyle := &renderer.Style{
-		Foreground:  &white,
-		BorderColor: &blue,
-	}
-	heatmapStyle.WithBorder(renderer.RoundedBorder)
-	heatmapStyled := renderer.NewStyledNode(heatmapNode, heatmapStyle)
-	heatmapBounds := dataviz.Bounds{X: 0, Y: 0, Width: m.width - 4, Height: 10}
-	heatmapContent := m.renderHeatmap(heatmapBounds, config)
-	heatmapStyled.Content = " Contributions\n" + heatmapContent
-	rootStyled.AddChild(heatmapStyled)
-
-	// Line graph panel
-	lineNode := &layout.Node{
-		Style: layout.Style{
-			Display: layout.DisplayBlock,
-		},
-	}
-	green, _ := color.ParseColor("#4CAF50")
-	lineStyle := &renderer.Style{
-		Foreground:  &white,
-		BorderColor: &green,
+	// KPI strip
+	if strip.Height() > 0 {
+		stripNode := &layout.Node{
+			Style: layout.Style{
+				Display: layout.DisplayBlock,
+			},
+		}
+		stripStyled := renderer.NewStyledNode(stripNode, nil)
+		stripStyled.Content = strip.Render()
+		rootStyled.AddChild(stripStyled)
 	}
-	lineStyle.WithBorder(renderer.RoundedBorder)
-	lineStyled := renderer.NewStyledNode(lineNode, lineStyle)
-	lineBounds := dataviz.Bounds{X: 0, Y: 0, Width: m.width - 4, Height: (m.height - 10) / 3}
-	lineContent := m.renderLineGraph(lineBounds, config)
-	lineStyled.Content = " Metrics\n" + lineContent
-	rootStyled.AddChild(lineStyled)
 
-	// Bar chart panel
-	barNode := &layout.Node{
-		Style: layout.Style{
-			Display: layout.DisplayBlock,
-		},
-	}
-	orange, _ := color.ParseColor("#FF9800")
-	barStyle := &renderer.Style{
-		Foreground:  &white,
-		BorderColor: &orange,
+	// Panels, each in its own color
+	terminal := dataviz.NewTerminalRenderer()
+	_, panelRows := m.panelRows(view)
+	for i, panel := range view.panels {
+		panelNode := &layout.Node{
+			Style: layout.Style{
+				Display: layout.DisplayBlock,
+			},
+		}
+		border, _ := color.ParseColor(m.borderColor(view.names[i], panelColors[i%len(panelColors)], config))
+		panelStyle := &renderer.Style{
+			Foreground:  &white,
+			BorderColor: &border,
+		}
+		panelStyle.WithBorder(renderer.RoundedBorder)
+		panelStyled := renderer.NewStyledNode(panelNode, panelStyle)
+		// Inside the border and below the title
+		panelBounds := dataviz.Bounds{X: 0, Y: 0, Width: m.width - 4, Height: panelRows - 3}
+		panelContent := panel.Render(terminal, panelBounds, config).String()
+		panelStyled.Content = " " + panel.Title() + "\n" + panelContent
+		rootStyled.AddChild(panelStyled)
 	}
-	barStyle.WithBorder(renderer.RoundedBorder)
-	barStyled := renderer.NewStyledNode(barNode, barStyle)
-	barBounds := dataviz.Bounds{X: 0, Y: 0, Width: m.width - 4, Height: (m.height - 10) / 3}
-	barContent := m.renderBarChart(barBounds, config)
-	barStyled.Content = " Languages\n" + barContent
-	rootStyled.AddChild(barStyled)
 
 	// Controls footer
 	footerNode := &layout.Node{
@@ -398,7 +566,7 @@ func (m dashboardModel) renderMultiView(screen *renderer.Screen, ctx *layout.Lay
 		},
 	}
 	footerStyled := renderer.NewStyledNode(footerNode, nil)
-	footerStyled.Content = m.getControlsText()
+	footerStyled.Content = m.getFooterText()
 	rootStyled.AddChild(footerStyled)
 
 	constraints := layout.Tight(float64(m.width), float64(m.height))
@@ -408,38 +576,247 @@ func (m dashboardModel) renderMultiView(screen *renderer.Screen, ctx *layout.Lay
 	return screen.String()
 }
 
-func (m dashboardModel) renderHeatmap(bounds dataviz.Bounds, config dataviz.RenderConfig) string {
-	renderer := dataviz.NewTerminalRenderer()
-	output := renderer.RenderHeatmap(m.data.heatmap, bounds, config)
-	return output.String()
+func (m dashboardModel) getStatusText() string {
+	status := "Running"
+	if m.paused {
+		status = "Paused"
+	}
+	text := fmt.Sprintf("%s • %s theme • %ds", status, m.colorTheme, m.counter)
+	if n := m.alerts.count(); n > 0 {
+		text += fmt.Sprintf(" • ⚠ %d firing", n)
+	}
+	return text
+}
+
+func (m dashboardModel) getControlsText() string {
+	var b strings.Builder
+	for _, v := range m.views {
+		fmt.Fprintf(&b, " %s:%s", v.keys[0], v.name)
+	}
+	b.WriteString(" • p:Pause r:Refresh t:Theme s:Snapshot a:Alerts q:Quit\n ←/→:Pan +/-:Zoom H/D/W/M:1h/24h/7d/30d l:Live")
+	return b.String()
 }
 
-func (m dashboardModel) renderLineGraph(bounds dataviz.Bounds, config dataviz.RenderConfig) string {
-	renderer := dataviz.NewTerminalRenderer()
-	output := renderer.RenderLineGraph(m.data.lineGraph, bounds, config)
-	return output.String()
+// panelColors are the borders of the panels of the multi view, in order.
+var panelColors = []string{"#2196F3", "#4CAF50", "#FF9800", "#9C27B0", "#F44336", "#00BCD4"}
+
+// panelRows returns the first screen row of the panels of a view and the
+// rows each one takes. They fill the rows between the header, or the KPI
+// strip below it, and the footer, one row apart, as in the grid of the
+// multi view.
+func (m dashboardModel) panelRows(view dashboardView) (top, rows int) {
+	top = 5
+	if h := m.strip(view).Height(); h > 0 {
+		top += h + 1
+	}
+	n := len(view.panels)
+	return top, (m.height - 3 - top - n) / n
 }
 
-func (m dashboardModel) renderBarChart(bounds dataviz.Bounds, config dataviz.RenderConfig) string {
-	renderer := dataviz.NewTerminalRenderer()
-	output := renderer.RenderBarChart(m.data.barChart, bounds, config)
-	return output.String()
+// strip returns the KPI strip of a view, across the screen inside its
+// padding. Only the multi view has one.
+func (m dashboardModel) strip(view dashboardView) kpiStrip {
+	if len(view.panels) < 2 {
+		return kpiStrip{}
+	}
+	config := m.renderConfig()
+	cards := m.data.kpis()
+	for i := range cards {
+		cards[i].Color = config.Color
+		cards[i].TrendColor = config.Color
+	}
+	return kpiStrip{
+		Cards: cards,
+		Width: m.width - 2,
+		Style: RoundedBorderStyle,
+		Good:  statusColor(m.colorTheme, "good"),
+		Bad:   statusColor(m.colorTheme, "critical"),
+	}
 }
 
-func (m dashboardModel) getStatusText() string {
-	status := "Running"
-	if m.paused {
-		status = "Paused"
+// panelAt returns the panel of the current view on screen row y.
+func (m dashboardModel) panelAt(y int) (Panel, bool) {
+	panels := m.views[m.mode].panels
+	top, rows := m.panelRows(m.views[m.mode])
+	if y < top {
+		return nil, false
 	}
-	return fmt.Sprintf("%s • %s theme • %ds", status, m.colorTheme, m.counter)
+	i := (y - top) / (rows + 1)
+	if i >= len(panels) || (y-top)%(rows+1) == rows {
+		return nil, false
+	}
+	return panels[i], true
 }
 
-func (m dashboardModel) getControlsText() string {
-	return " 1:Heatmap 2:LineGraph 3:BarChart 4:Multi • p:Pause r:Refresh t:Theme q:Quit"
+// getFooterText returns the controls, or a recent notice in their place.
+func (m dashboardModel) getFooterText() string {
+	if m.notice != "" && time.Since(m.noticeAt) < noticeDuration {
+		return " " + m.notice
+	}
+	return m.getControlsText()
+}
+
+// Snapshots lay the view out in SVG with each terminal cell this many
+// pixels wide and high.
+const (
+	snapshotCellWidth  = 8
+	snapshotCellHeight = 16
+)
+
+// snapshot captures what is on screen: the data of its panels as JSON, the
+// same layout rendered as SVG, and the screen itself as ANSI text, without
+// the alert history. They are rendered now, before the data moves on, and
+// written by the returned command to the snapshot directory under one
+// timestamped name.
+func (m dashboardModel) snapshot() tea.Cmd {
+	now := time.Now()
+	view := m.views[m.mode]
+
+	type panelData struct {
+		Label string `json:"label,omitempty"`
+		Data  any    `json:"data"`
+	}
+	data := struct {
+		Title  string      `json:"title"`
+		Theme  string      `json:"theme"`
+		Time   time.Time   `json:"time"`
+		Panels []panelData `json:"panels"`
+	}{Title: view.Title(), Theme: m.colorTheme, Time: now}
+	for _, panel := range view.panels {
+		data.Panels = append(data.Panels, panelData{Label: panel.Title(), Data: panel.Data()})
+	}
+	encoded, err := json.MarshalIndent(data, "", "  ")
+
+	screen := m
+	screen.showAlerts = false
+	files := []struct{ ext, content string }{
+		{".json", string(encoded) + "\n"},
+		{".svg", m.snapshotSVG(view)},
+		{".ans", screen.View()},
+	}
+
+	return func() tea.Msg {
+		if err != nil {
+			return snapshotMsg{err: err}
+		}
+		if err := os.MkdirAll(m.snapshotDir, 0o755); err != nil {
+			return snapshotMsg{err: err}
+		}
+		base, err := claimSnapshotName(m.snapshotDir, now, files[0].ext)
+		if err != nil {
+			return snapshotMsg{err: err}
+		}
+		for _, f := range files {
+			if err := os.WriteFile(base+f.ext, []byte(f.content), 0o644); err != nil {
+				return snapshotMsg{err: err}
+			}
+		}
+		return snapshotMsg{base: base}
+	}
+}
+
+// claimSnapshotName returns the path, without extension, of a snapshot
+// taken at t: dir/dashboard-YYYYMMDD-HHMMSS, or with -2, -3 and so on after
+// it for later snapshots within the same second. The name is claimed by
+// creating its file with extension ext, which no earlier snapshot has.
+func claimSnapshotName(dir string, t time.Time, ext string) (string, error) {
+	stamp := filepath.Join(dir, "dashboard-"+t.Format("20060102-150405"))
+	base := stamp
+	for n := 2; ; n++ {
+		f, err := os.OpenFile(base+ext, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			return base, f.Close()
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+		base = fmt.Sprintf("%s-%d", stamp, n)
+	}
+}
+
+// snapshotSVG draws the view as the terminal lays it out: a bordered
+// header, the KPI strip of the multi view, the panels sharing the height
+// below it and the controls at the bottom, with the charts rendered
+// through the SVG renderer.
+func (m dashboardModel) snapshotSVG(view dashboardView) string {
+	config := m.renderConfig()
+	cw, ch := float64(snapshotCellWidth), float64(snapshotCellHeight)
+	background, text := themeColors(config)
+	doc := newSVGDocument(m.width*snapshotCellWidth, m.height*snapshotCellHeight, background)
+
+	// box draws a bordered box over whole cells, from its top row.
+	box := func(row, rows int, stroke string) {
+		doc.Add(svgRect{X: cw * 1.5, Y: ch * (float64(row) + 0.5), W: cw * float64(m.width-3), H: ch * float64(rows-1),
+			Fill: "none", Radius: 6, Stroke: stroke, StrokeWidth: 1.5})
+	}
+	line := func(row int, s string, bold bool) {
+		doc.Add(svgText{X: cw * 2, Y: ch * (float64(row) + 0.75), Text: s, Fill: text, Size: ch * 0.75, Mono: true, Bold: bold})
+	}
+
+	box(1, 3, config.Color)
+	line(2, fmt.Sprintf("%s • %dx%d • %s", view.Title(), m.width, m.height, m.getStatusText()), true)
+
+	// KPI strip, each card a box of its text lines
+	strip := m.strip(view)
+	if n := strip.perRow(); n > 0 {
+		width := strip.cardWidth()
+		for i, card := range strip.Cards {
+			row, col := 5+i/n*kpiHeight, 1+i%n*(width+1)
+			doc.Add(svgRect{X: cw * (float64(col) + 0.5), Y: ch * (float64(row) + 0.5), W: cw * float64(width-1), H: ch * float64(kpiHeight-1),
+				Fill: "none", Radius: 6, Stroke: card.Color, StrokeWidth: 1.5})
+			at := func(line, indent int, s, fill string, bold bool) {
+				doc.Add(svgText{X: cw * float64(col+indent), Y: ch * (float64(row+line) + 0.75), Text: s, Fill: cmp.Or(fill, text), Size: ch * 0.75, Mono: true, Bold: bold})
+			}
+			// The title sits on the top border, as in the terminal.
+			doc.Add(svgRect{X: cw * float64(col+3), Y: ch * float64(row), W: cw * float64(utf8.RuneCountInString(card.Title)), H: ch, Fill: cmp.Or(background, "#FFFFFF")})
+			change, changeColor := card.changeText(strip.Good, strip.Bad)
+			at(0, 3, card.Title, text, true)
+			at(1, 2, card.Value, text, true)
+			at(2, 2, change, changeColor, false)
+			at(3, 2, card.trendText(width-4), card.TrendColor, false)
+		}
+	}
+
+	multi := len(view.panels) > 1
+	top, rows := m.panelRows(view)
+	svg := dataviz.NewSVGRenderer()
+	for i, panel := range view.panels {
+		stroke := m.borderColor(view.names[i], config.Color, config)
+		chartTop := top + 1
+		if multi {
+			stroke = m.borderColor(view.names[i], panelColors[i%len(panelColors)], config)
+			line(chartTop, panel.Title(), true)
+			chartTop++
+		}
+		box(top, rows, stroke)
+		bounds := dataviz.Bounds{Width: (m.width - 4) * snapshotCellWidth, Height: (top + rows - 1 - chartTop) * snapshotCellHeight}
+		if bounds.Height > 0 {
+			content := panel.Render(svg, bounds, config).String()
+			doc.Add(svgGroup{X: cw * 2, Y: ch * float64(chartTop), Content: content})
+		}
+		top += rows + 1
+	}
+
+	for i, controls := range strings.Split(m.getControlsText(), "\n") {
+		line(m.height-3+i, strings.TrimSpace(controls), false)
+	}
+	return doc.String()
 }
 
 func main() {
-	p := tea.NewProgram(initialDashboardModel(), tea.WithAltScreen(), tea.WithMouseCellMotion())
+	snapshotDir := flag.String("snapshot-dir", ".", "Directory for snapshots saved with s")
+	panels := flag.String("panels", defaultPanels, "Comma-separated panels of the multi view")
+	var rules []alertRule
+	flag.Var(alertFlag{&rules}, "alert", "Alert rule, as in 'line-graph: line > 90 for 3 ticks'")
+	hook := flag.String("alert-hook", "", "Shell command run when an alert fires, with ALERT_RULE, ALERT_PANEL, ALERT_METRIC and ALERT_VALUE set")
+	flag.Parse()
+
+	model, err := initialDashboardModel(*snapshotDir, strings.Split(*panels, ","), rules, *hook)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
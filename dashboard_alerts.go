@@ -0,0 +1,299 @@
+//go:build dashboard
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// alertRule fires when a figure of a panel stays past a threshold for a
+// number of ticks in a row, as in "line-graph: line > 90 for 3 ticks".
+type alertRule struct {
+	spec   string
+	panel  string
+	metric string
+	op     string
+	value  float64
+	ticks  int
+}
+
+// alertCondition matches the condition of a rule: METRIC OP VALUE,
+// optionally followed by "for N ticks".
+var alertCondition = regexp.MustCompile(`^\s*([^<>=\s]+)\s*(>=|<=|>|<)\s*(\S+?)(?:\s+for\s+(\d+)\s+ticks?)?\s*$`)
+
+// parseAlertRule parses "PANEL: METRIC OP VALUE [for N ticks]", where OP
+// is one of >, >=, < and <=. Without a number of ticks, the rule fires on
+// the first tick past the threshold.
+func parseAlertRule(spec string) (alertRule, error) {
+	rule := alertRule{spec: strings.TrimSpace(spec), ticks: 1}
+	panel, condition, _ := strings.Cut(spec, ":")
+	rule.panel = strings.TrimSpace(panel)
+	m := alertCondition.FindStringSubmatch(condition)
+	if m == nil || rule.panel == "" {
+		return rule, fmt.Errorf("invalid alert %q (want 'PANEL: METRIC OP VALUE [for N ticks]')", spec)
+	}
+	rule.metric, rule.op = m[1], m[2]
+	value, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return rule, fmt.Errorf("invalid alert %q: %q is not a number", spec, m[3])
+	}
+	rule.value = value
+	if m[4] != "" {
+		if rule.ticks, _ = strconv.Atoi(m[4]); rule.ticks < 1 {
+			return rule, fmt.Errorf("invalid alert %q: alerts need at least 1 tick", spec)
+		}
+	}
+	return rule, nil
+}
+
+// breached reports whether v is past the rule's threshold.
+func (r alertRule) breached(v float64) bool {
+	switch r.op {
+	case ">":
+		return v > r.value
+	case ">=":
+		return v >= r.value
+	case "<":
+		return v < r.value
+	}
+	return v <= r.value
+}
+
+// alertFlag is a repeatable command line flag adding alert rules.
+type alertFlag struct {
+	list *[]alertRule
+}
+
+func (f alertFlag) String() string { return "" }
+
+func (f alertFlag) Set(spec string) error {
+	rule, err := parseAlertRule(spec)
+	if err != nil {
+		return err
+	}
+	*f.list = append(*f.list, rule)
+	return nil
+}
+
+// alertEvent is an entry of the alert history: a rule starting to fire,
+// or resolving, and the value it saw.
+type alertEvent struct {
+	at     time.Time
+	rule   alertRule
+	value  float64
+	firing bool
+}
+
+func (e alertEvent) String() string {
+	state := "RESOLVED"
+	if e.firing {
+		state = "FIRING  "
+	}
+	return fmt.Sprintf("%s %s %s (%s = %g)", e.at.Format("15:04:05"), state, e.rule.spec, e.rule.metric, e.value)
+}
+
+// maxAlertHistory is the number of events the alert history keeps.
+const maxAlertHistory = 100
+
+// alerts watches the rules against panels of their own, so they are
+// checked whichever view is on screen.
+type alerts struct {
+	rules   []alertRule
+	sources []alertSource // the panel of each rule
+	streak  []int         // ticks in a row each rule has been breached
+	firing  []bool
+	history []alertEvent // oldest first
+	hook    string
+}
+
+// newAlerts checks that every rule names a registered panel with the
+// figure it watches, and creates the panels to watch.
+func newAlerts(rules []alertRule, hook string, data *dashboardData) (*alerts, error) {
+	a := &alerts{
+		rules:  rules,
+		streak: make([]int, len(rules)),
+		firing: make([]bool, len(rules)),
+		hook:   hook,
+	}
+	for _, rule := range rules {
+		panel, err := newPanel(rule.panel, data)
+		if err != nil {
+			return nil, fmt.Errorf("alert %q: %w", rule.spec, err)
+		}
+		source, ok := panel.(alertSource)
+		metrics := map[string]float64{}
+		if ok {
+			metrics = source.Metrics()
+		}
+		if _, found := metrics[rule.metric]; !found {
+			names := make([]string, 0, len(metrics))
+			for name := range metrics {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				return nil, fmt.Errorf("alert %q: panel %s has no figures to watch", rule.spec, rule.panel)
+			}
+			return nil, fmt.Errorf("alert %q: panel %s has no figure %q (have %s)", rule.spec, rule.panel, rule.metric, strings.Join(names, ", "))
+		}
+		a.sources = append(a.sources, source)
+	}
+	return a, nil
+}
+
+// check counts a tick against every rule and returns the events of the
+// rules that started firing or resolved, which are added to the history.
+func (a *alerts) check(now time.Time) []alertEvent {
+	var events []alertEvent
+	for i, rule := range a.rules {
+		value := a.sources[i].Metrics()[rule.metric]
+		if rule.breached(value) {
+			a.streak[i]++
+		} else {
+			a.streak[i] = 0
+		}
+		if firing := a.streak[i] >= rule.ticks; firing != a.firing[i] {
+			a.firing[i] = firing
+			events = append(events, alertEvent{at: now, rule: rule, value: value, firing: firing})
+		}
+	}
+	a.history = append(a.history, events...)
+	a.history = a.history[max(0, len(a.history)-maxAlertHistory):]
+	return events
+}
+
+// firingOn reports whether any rule on the named panel is firing.
+func (a *alerts) firingOn(panel string) bool {
+	for i, rule := range a.rules {
+		if a.firing[i] && rule.panel == panel {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the number of rules firing.
+func (a *alerts) count() int {
+	n := 0
+	for _, firing := range a.firing {
+		if firing {
+			n++
+		}
+	}
+	return n
+}
+
+// alertHookTimeout bounds each run of the alert hook, so that a hung
+// command cannot pile up behind the alerts that follow.
+const alertHookTimeout = 10 * time.Second
+
+// alertHookMsg reports a notification hook that failed.
+type alertHookMsg struct {
+	err error
+}
+
+// bellOutput is the terminal the alert bell is written to.
+var bellOutput io.Writer = os.Stdout
+
+// notify rings the bell once for the events of rules that started firing,
+// and runs the hook for each of them with the rule in its environment.
+func (a *alerts) notify(events []alertEvent) tea.Cmd {
+	var fired []alertEvent
+	for _, e := range events {
+		if e.firing {
+			fired = append(fired, e)
+		}
+	}
+	if len(fired) == 0 {
+		return nil
+	}
+	hook := a.hook
+	return func() tea.Msg {
+		fmt.Fprint(bellOutput, "\a")
+		if hook == "" {
+			return nil
+		}
+		for _, e := range fired {
+			if err := runAlertHook(hook, e); err != nil {
+				return alertHookMsg{err: err}
+			}
+		}
+		return nil
+	}
+}
+
+// runAlertHook runs the hook for one event, stopping it after
+// alertHookTimeout.
+func runAlertHook(hook string, e alertEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), alertHookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook)
+	// Stop waiting for output from anything the hook left running.
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+e.rule.spec,
+		"ALERT_PANEL="+e.rule.panel,
+		"ALERT_METRIC="+e.rule.metric,
+		"ALERT_VALUE="+strconv.FormatFloat(e.value, 'g', -1, 64),
+	)
+	out, err := cmd.CombinedOutput()
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return fmt.Errorf("timed out after %v", alertHookTimeout)
+	}
+	if msg := strings.TrimSpace(string(out)); msg != "" {
+		err = fmt.Errorf("%w: %s", err, msg)
+	}
+	return err
+}
+
+// overlay draws the alert history, newest first, in a box over the middle
+// of screen, keeping the lines above and below it.
+func (a *alerts) overlay(screen string, width, height int, theme string) string {
+	lines := strings.Split(strings.TrimSuffix(screen, "\n"), "\n")
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	rows := max(1, height-8)
+	var content []string
+	for i := len(a.history) - 1; i >= 0 && len(content) < rows; i-- {
+		e := a.history[i]
+		line := e.String()
+		if e.firing {
+			line = colorize(line, statusColor(theme, "critical"))
+		}
+		content = append(content, line)
+	}
+	if len(content) == 0 {
+		content = append(content, "No alerts yet")
+	}
+	box := Box{
+		Label:       fmt.Sprintf("ALERT HISTORY (%d firing)", a.count()),
+		Width:       max(30, width-4),
+		BorderColor: ansiColor(statusColor(theme, "critical")),
+		Style:       RoundedBorderStyle,
+	}
+	boxLines := strings.Split(strings.TrimSuffix(box.RenderComplete(strings.Join(content, "\n")), "\n"), "\n")
+
+	top := max(0, (height-len(boxLines))/2)
+	for i, line := range boxLines {
+		if top+i < len(lines) {
+			lines[top+i] = "  " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
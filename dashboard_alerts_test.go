@@ -0,0 +1,143 @@
+//go:build dashboard
+
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseAlertRule(t *testing.T) {
+	tests := []struct {
+		spec          string
+		panel, metric string
+		op            string
+		value         float64
+		ticks         int
+	}{
+		{"line-graph: line > 90 for 3 ticks", "line-graph", "line", ">", 90, 3},
+		{" slo:budget<=10 ", "slo", "budget", "<=", 10, 1},
+		{"slo: latency >= 2.5e2 for 1 tick", "slo", "latency", ">=", 250, 1},
+		{"bar-chart: Go < -5", "bar-chart", "Go", "<", -5, 1},
+	}
+	for _, tt := range tests {
+		r, err := parseAlertRule(tt.spec)
+		if err != nil {
+			t.Errorf("parseAlertRule(%q): %v", tt.spec, err)
+			continue
+		}
+		if r.panel != tt.panel || r.metric != tt.metric || r.op != tt.op || r.value != tt.value || r.ticks != tt.ticks {
+			t.Errorf("parseAlertRule(%q) = %+v", tt.spec, r)
+		}
+	}
+}
+
+func TestParseAlertRuleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"line > 90",
+		": line > 90",
+		"line-graph: line = 90",
+		"line-graph: line > high",
+		"line-graph: line > 90 for 0 ticks",
+		"line-graph: line > 90 for a while",
+	} {
+		if _, err := parseAlertRule(spec); err == nil {
+			t.Errorf("parseAlertRule(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+// fixedSource is an alertSource with figures set by the test.
+type fixedSource map[string]float64
+
+func (s fixedSource) Metrics() map[string]float64 { return s }
+
+func TestAlertsCheck(t *testing.T) {
+	rule, _ := parseAlertRule("slo: latency > 200 for 2 ticks")
+	source := fixedSource{"latency": 250}
+	a := &alerts{rules: []alertRule{rule}, sources: []alertSource{source}, streak: []int{0}, firing: []bool{false}}
+
+	now := time.Now()
+	steps := []struct {
+		latency float64
+		event   string
+	}{
+		{250, ""},
+		{250, "FIRING"},
+		{300, ""},
+		{100, "RESOLVED"},
+		{250, ""},
+	}
+	for i, step := range steps {
+		source["latency"] = step.latency
+		events := a.check(now)
+		var got string
+		if len(events) == 1 {
+			got = strings.Fields(events[0].String())[1]
+		}
+		if got != step.event {
+			t.Errorf("tick %d: event %q, want %q", i, got, step.event)
+		}
+	}
+	if len(a.history) != 2 || a.firingOn("slo") || a.count() != 0 {
+		t.Errorf("history %v, firing %v", a.history, a.firing)
+	}
+}
+
+func TestNewAlertsChecksRules(t *testing.T) {
+	data := generateInitialData()
+	for _, spec := range []string{"radar: x > 1", "line-graph: nope > 1"} {
+		rule, _ := parseAlertRule(spec)
+		if _, err := newAlerts([]alertRule{rule}, "", data); err == nil {
+			t.Errorf("alert %q accepted", spec)
+		}
+	}
+}
+
+func TestRunAlertHook(t *testing.T) {
+	rule, _ := parseAlertRule("slo: budget < 10")
+	e := alertEvent{rule: rule, value: 4.5, firing: true}
+	if err := runAlertHook(`test "$ALERT_PANEL/$ALERT_METRIC/$ALERT_VALUE" = slo/budget/4.5`, e); err != nil {
+		t.Errorf("hook environment: %v", err)
+	}
+	err := runAlertHook("echo no route >&2; exit 3", e)
+	if err == nil || !strings.Contains(err.Error(), "no route") {
+		t.Errorf("failing hook: error %v, want its output", err)
+	}
+}
+
+func TestAlertRingsBellOnce(t *testing.T) {
+	var bell strings.Builder
+	defer func(w io.Writer) { bellOutput = w }(bellOutput)
+	bellOutput = &bell
+
+	rule, _ := parseAlertRule("slo: latency > 200")
+	a := &alerts{rules: []alertRule{rule}, sources: []alertSource{fixedSource{"latency": 250}}, streak: []int{0}, firing: []bool{false}}
+	for i := range 2 {
+		if cmd := a.notify(a.check(time.Now())); cmd != nil {
+			cmd()
+		}
+		if bell.String() != "\a" {
+			t.Errorf("tick %d: wrote %q, want one bell", i, bell.String())
+		}
+	}
+}
+
+func TestAlertViewHasNoBell(t *testing.T) {
+	rule, _ := parseAlertRule("line-graph: line > -1")
+	m, err := initialDashboardModel(t.TempDir(), []string{"heatmap", "line-graph"}, []alertRule{rule}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	for i := range 2 {
+		model, _ = model.Update(tickMsg(time.Now()))
+		if strings.Contains(model.View(), "\a") {
+			t.Errorf("tick %d: the view rings the bell", i)
+		}
+	}
+}
@@ -0,0 +1,548 @@
+//go:build dashboard
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Panel is one chart on the dashboard. Title heads it and may change as it
+// is used; Data is what it currently shows, saved with snapshots; Render
+// draws it into bounds with either the terminal or the SVG renderer.
+// Update lets it react to keys, to the mouse when the pointer is over it,
+// and to every tick, reporting whether it used the message, so that a key
+// goes to one panel only.
+type Panel interface {
+	Title() string
+	Data() any
+	Render(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output
+	Update(msg tea.Msg) bool
+}
+
+// alertSource is implemented by panels with figures that alert rules can
+// watch, by name, as in "line-graph: line > 90 for 3 ticks".
+type alertSource interface {
+	Metrics() map[string]float64
+}
+
+// panelFactory creates a panel drawing from the dashboard's data. The data
+// is updated in place, so panels may keep the pointer.
+type panelFactory func(data *dashboardData) Panel
+
+// panelRegistry holds the panels dashboards can be assembled from, by name.
+var panelRegistry = map[string]panelFactory{}
+
+// RegisterPanel makes a panel available to dashboards under name, as in
+// -panels. Registering a name twice panics, as defining a flag twice does.
+func RegisterPanel(name string, factory panelFactory) {
+	if _, ok := panelRegistry[name]; ok {
+		panic("dashboard panel registered twice: " + name)
+	}
+	panelRegistry[name] = factory
+}
+
+// newPanel creates the registered panel called name.
+func newPanel(name string, data *dashboardData) (Panel, error) {
+	factory, ok := panelRegistry[name]
+	if !ok {
+		names := make([]string, 0, len(panelRegistry))
+		for name := range panelRegistry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown panel %q (have %s)", name, strings.Join(names, ", "))
+	}
+	return factory(data), nil
+}
+
+// panelRenderFunc draws a panel with either the terminal or the SVG
+// renderer.
+type panelRenderFunc func(dataviz.Renderer, dataviz.Bounds, dataviz.RenderConfig) dataviz.Output
+
+// staticPanel is a Panel made of functions, for panels that only draw and
+// ignore messages. Its metrics, if any, are what alert rules watch.
+type staticPanel struct {
+	title   string
+	data    func() any
+	render  panelRenderFunc
+	metrics func() map[string]float64
+}
+
+func (p staticPanel) Title() string { return p.title }
+func (p staticPanel) Data() any     { return p.data() }
+func (p staticPanel) Update(tea.Msg) bool {
+	return false
+}
+
+func (p staticPanel) Metrics() map[string]float64 {
+	if p.metrics == nil {
+		return nil
+	}
+	return p.metrics()
+}
+
+func (p staticPanel) Render(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	return p.render(r, bounds, config)
+}
+
+func init() {
+	RegisterPanel("heatmap", func(d *dashboardData) Panel {
+		return staticPanel{"Contribution Heatmap", func() any { return d.heatmap }, d.renderHeatmap, d.contributionMetrics}
+	})
+	RegisterPanel("line-graph", func(d *dashboardData) Panel {
+		return metricsPanel{title: "Metrics Over Time", data: d}
+	})
+	RegisterPanel("bar-chart", func(d *dashboardData) Panel {
+		return staticPanel{"Language Usage", func() any { return d.barChart }, d.renderBarChart, d.languageMetrics}
+	})
+	RegisterPanel("area", func(d *dashboardData) Panel {
+		return metricsPanel{title: "Metrics Over Time", data: d, area: true}
+	})
+	RegisterPanel("scatter", func(d *dashboardData) Panel {
+		return staticPanel{"Daily Contributions", func() any { return d.heatmap }, d.renderScatter, d.contributionMetrics}
+	})
+	RegisterPanel("histogram", func(d *dashboardData) Panel {
+		return staticPanel{"Contributions per Day", func() any { return d.heatmap }, d.renderHistogram, d.contributionMetrics}
+	})
+	RegisterPanel("donut", func(d *dashboardData) Panel {
+		return staticPanel{"Language Share", func() any { return d.barChart }, d.renderDonut, d.languageMetrics}
+	})
+	RegisterPanel("slo", func(d *dashboardData) Panel {
+		return staticPanel{"Service Levels", func() any { return d.sloMetrics() }, d.renderSLO, d.sloMetrics}
+	})
+}
+
+// contributionMetrics are the figures of the contribution panels: today's
+// count.
+func (d *dashboardData) contributionMetrics() map[string]float64 {
+	days := d.heatmap.Days
+	if len(days) == 0 {
+		return map[string]float64{"today": 0}
+	}
+	return map[string]float64{"today": float64(days[len(days)-1].Count)}
+}
+
+// languageMetrics are the figures of the language panels: each language's
+// value, by its lowercased name.
+func (d *dashboardData) languageMetrics() map[string]float64 {
+	metrics := map[string]float64{}
+	for _, bar := range d.barChart.Bars {
+		metrics[strings.ToLower(bar.Label)] = float64(bar.Value)
+	}
+	return metrics
+}
+
+// sloMetrics are the service level figures.
+func (d *dashboardData) sloMetrics() map[string]float64 {
+	return map[string]float64{"budget": d.budget, "quota": d.quota, "latency": d.latency}
+}
+
+// dashboardView is a screen of the dashboard, shown by pressing one of its
+// keys. A view of one panel is titled after it. Names are the registered
+// names of its panels, in order.
+type dashboardView struct {
+	keys   []string
+	name   string
+	title  string
+	names  []string
+	panels []Panel
+}
+
+// dashboardViews lists the views by key and the panels they show. The
+// multi view, with no panels here, shows those given by -panels.
+var dashboardViews = []struct {
+	keys   []string
+	panels []string
+}{
+	{[]string{"1"}, []string{"heatmap"}},
+	{[]string{"2"}, []string{"line-graph"}},
+	{[]string{"3"}, []string{"bar-chart"}},
+	{[]string{"4", "m"}, nil},
+	{[]string{"5"}, []string{"area"}},
+	{[]string{"6"}, []string{"scatter"}},
+	{[]string{"7"}, []string{"histogram"}},
+	{[]string{"8"}, []string{"donut"}},
+	{[]string{"9"}, []string{"slo"}},
+}
+
+// defaultPanels are the panels of the multi view.
+const defaultPanels = "heatmap,line-graph,bar-chart"
+
+// newDashboardViews creates the views and their panels, with the multi
+// view showing the named panels.
+func newDashboardViews(data *dashboardData, multi []string) ([]dashboardView, error) {
+	var views []dashboardView
+	for _, spec := range dashboardViews {
+		view := dashboardView{keys: spec.keys}
+		names := spec.panels
+		if names == nil {
+			names = multi
+			view.name = "multi"
+			view.title = "DataViz Dashboard"
+		}
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			p, err := newPanel(name, data)
+			if err != nil {
+				return nil, err
+			}
+			view.names = append(view.names, name)
+			view.panels = append(view.panels, p)
+		}
+		if len(view.panels) == 0 {
+			return nil, fmt.Errorf("no panels for view %s", spec.keys[0])
+		}
+		if view.name == "" {
+			view.name = view.names[0]
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// Title returns the view's title, or its only panel's.
+func (v dashboardView) Title() string {
+	if v.title == "" && len(v.panels) == 1 {
+		return v.panels[0].Title()
+	}
+	return v.title
+}
+
+func (d *dashboardData) renderHeatmap(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	return r.RenderHeatmap(d.heatmap, bounds, config)
+}
+
+// renderBarChart draws the bars with the dataviz renderer while they fit
+// it, and from a zero baseline once any has drifted below zero.
+func (d *dashboardData) renderBarChart(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	in := barChartInput{BarChartData: d.barChart}
+	for _, bar := range d.barChart.Bars {
+		in.Bars = append(in.Bars, barValue{Label: bar.Label, Value: float64(bar.Value), Secondary: float64(bar.Secondary)})
+	}
+	if !in.fitsDataviz() {
+		return renderChart(r, in, bounds, config, defaultChartOptions())
+	}
+	return r.RenderBarChart(d.barChart, bounds, config)
+}
+
+// panelOptions are the chart options used by the panels that viz-cli draws
+// itself.
+func panelOptions() chartOptions {
+	opts := defaultChartOptions()
+	opts.Axes = true
+	return opts
+}
+
+func (d *dashboardData) renderScatter(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	// The last 90 days of the heatmap, one point per day.
+	days := d.heatmap.Days
+	days = days[max(0, len(days)-90):]
+	var points []scatterPoint
+	for _, d := range days {
+		points = append(points, scatterPoint{Date: d.Date, Y: float64(d.Count)})
+	}
+	return renderChart(r, scatterInput{Points: points}, bounds, config, panelOptions())
+}
+
+func (d *dashboardData) renderHistogram(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	var values []float64
+	for _, d := range d.heatmap.Days {
+		values = append(values, float64(d.Count))
+	}
+	return renderChart(r, histogramInput{Values: values}, bounds, config, panelOptions())
+}
+
+func (d *dashboardData) renderDonut(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	var slices []pieSlice
+	for _, b := range d.barChart.Bars {
+		slices = append(slices, pieSlice{Label: b.Label, Value: float64(b.Value)})
+	}
+	return renderChart(r, pieInput{Slices: slices, Donut: true}, bounds, config, panelOptions())
+}
+
+// renderSLO shows the error budget as a dial above the quota as a progress
+// bar and the latency as a bullet chart against its target.
+func (d *dashboardData) renderSLO(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	opts := panelOptions()
+	opts.Numbers.Decimals = 0
+	fl := func(v float64) *float64 { return &v }
+
+	budget := gaugeInput{
+		Label: "Error budget %", Value: d.budget, Style: "gauge",
+		Thresholds: gaugeThresholds{Warn: fl(50), Critical: fl(20)},
+	}
+	quota := gaugeInput{
+		Label: "Quota %", Value: d.quota, Style: "progress", Target: fl(80),
+		Thresholds: gaugeThresholds{Warn: fl(70), Critical: fl(90)},
+	}
+	latency := gaugeInput{
+		Label: "p99 ms", Value: d.latency, Max: fl(400), Style: "bullet", Target: fl(250),
+		Thresholds: gaugeThresholds{Warn: fl(200), Critical: fl(300)},
+	}
+
+	if _, ok := r.(*dataviz.SVGRenderer); ok {
+		// The dial takes the top half and the bars a quarter each.
+		dial := bounds
+		dial.Height = bounds.Height / 2
+		bar := bounds
+		bar.Height = bounds.Height / 4
+		doc := newSVGDocument(bounds.Width, bounds.Height, "")
+		doc.Add(
+			svgGroup{Content: budget.SVG(dial, config, opts)},
+			svgGroup{Y: float64(dial.Height), Content: quota.SVG(bar, config, opts)},
+			svgGroup{Y: float64(dial.Height + bar.Height), Content: latency.SVG(bar, config, opts)},
+		)
+		return dataviz.SVGOutput(doc.String())
+	}
+
+	// The two bar rows and the bullet scale take five lines with spacing.
+	dial := bounds
+	dial.Height = max(3, bounds.Height-5)
+	bar := bounds
+	bar.Height = 3
+
+	var b strings.Builder
+	b.WriteString(budget.Terminal(dial, config, opts))
+	b.WriteString("\n")
+	b.WriteString(quota.Terminal(bar, config, opts))
+	b.WriteString(latency.Terminal(bar, config, opts))
+	return dataviz.TerminalOutput{Content: b.String()}
+}
+
+// metricsPanel draws the range of the metrics history chosen by its time
+// range, as a line graph or a filled area. It pans with the arrow keys,
+// zooms with + and -, or the mouse wheel, and jumps to the preset ranges.
+// Panels share the range through the data, so every view stays in step.
+type metricsPanel struct {
+	title string
+	data  *dashboardData
+	area  bool
+}
+
+func (p metricsPanel) Title() string {
+	return p.title + " · " + p.data.window.text()
+}
+
+func (p metricsPanel) Data() any {
+	series := p.data.lineGraph
+	series.Points = p.data.window.points(p.data.lineGraph.Points, 0)
+	return series
+}
+
+func (p metricsPanel) Render(r dataviz.Renderer, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+	series := p.data.lineGraph
+	series.Points = p.data.window.points(series.Points, bounds.Width)
+	if !p.area {
+		return r.RenderLineGraph(series, bounds, config)
+	}
+	var points []timePoint
+	for _, pt := range series.Points {
+		points = append(points, timePoint{Date: pt.Date, Value: float64(pt.Value)})
+	}
+	data := areaChartInput{lineGraphInput{Points: points}}
+	data.Color = series.Color
+	return renderChart(r, data, bounds, config, panelOptions())
+}
+
+// Metrics returns the newest value of the history as "line", whichever
+// range the panel shows.
+func (p metricsPanel) Metrics() map[string]float64 {
+	points := p.data.lineGraph.Points
+	if len(points) == 0 {
+		return map[string]float64{"line": 0}
+	}
+	return map[string]float64{"line": float64(points[len(points)-1].Value)}
+}
+
+func (p metricsPanel) Update(msg tea.Msg) bool {
+	w, history := &p.data.window, p.data.lineGraph.Points
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch key := msg.String(); key {
+		case "left":
+			w.pan(history, -1)
+		case "right":
+			w.pan(history, 1)
+		case "+", "=":
+			w.zoom(history, 0.5)
+		case "-":
+			w.zoom(history, 2)
+		case "H", "D", "W", "M":
+			w.span = rangePresets[key]
+			w.live = true
+			w.clamp(history)
+		case "l", "end":
+			w.live = true
+		default:
+			return false
+		}
+		return true
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress {
+			return false
+		}
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			w.zoom(history, 0.5)
+		case tea.MouseButtonWheelDown:
+			w.zoom(history, 2)
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// The metrics history starts with historyLength of points historyStep
+// apart, and live updates extend it up to maxHistoryPoints.
+const (
+	historyStep      = 5 * time.Minute
+	historyLength    = 30 * 24 * time.Hour
+	maxHistoryPoints = 20000
+	minSpan          = 30 * time.Minute
+	maxSpan          = historyLength
+)
+
+// rangePresets are the line graph spans jumped to by key.
+var rangePresets = map[string]time.Duration{
+	"H": time.Hour,
+	"D": 24 * time.Hour,
+	"W": 7 * 24 * time.Hour,
+	"M": 30 * 24 * time.Hour,
+}
+
+// timeRange is the part of the metrics history that line graphs show: span
+// of it, ending at its newest point while live and at end while looking at
+// history.
+type timeRange struct {
+	span time.Duration
+	live bool
+	end  time.Time
+}
+
+// historyBounds returns the times of the oldest and newest points.
+func historyBounds(history []dataviz.TimeSeriesData) (first, last time.Time) {
+	if len(history) == 0 {
+		return
+	}
+	return history[0].Date, history[len(history)-1].Date
+}
+
+// endAt returns the end of the range in history.
+func (w timeRange) endAt(history []dataviz.TimeSeriesData) time.Time {
+	if w.live {
+		_, last := historyBounds(history)
+		return last
+	}
+	return w.end
+}
+
+// pan moves the range a quarter of its span back (-1) or forward (1).
+// Looking at history stops following the newest point; panning up to it
+// follows it again.
+func (w *timeRange) pan(history []dataviz.TimeSeriesData, direction int) {
+	w.end = w.endAt(history).Add(time.Duration(direction) * w.span / 4)
+	w.live = false
+	w.clamp(history)
+}
+
+// zoom scales the span by factor around the middle of the range, or
+// keeping the newest point in view while live.
+func (w *timeRange) zoom(history []dataviz.TimeSeriesData, factor float64) {
+	middle := w.endAt(history).Add(-w.span / 2)
+	w.span = time.Duration(float64(w.span) * factor)
+	w.clamp(history)
+	if !w.live {
+		w.end = middle.Add(w.span / 2)
+		w.clamp(history)
+	}
+}
+
+// clamp keeps the span between minSpan and maxSpan and the range within
+// the history, going live when it reaches the newest point.
+func (w *timeRange) clamp(history []dataviz.TimeSeriesData) {
+	first, last := historyBounds(history)
+	w.span = max(minSpan, min(w.span, maxSpan))
+	if w.live {
+		return
+	}
+	if earliest := first.Add(w.span); w.end.Before(earliest) {
+		w.end = earliest
+	}
+	if !w.end.Before(last) {
+		w.live = true
+	}
+}
+
+// points returns the points of history in the range, averaged into at
+// most n points when n is positive.
+func (w timeRange) points(history []dataviz.TimeSeriesData, n int) []dataviz.TimeSeriesData {
+	end := w.endAt(history)
+	start := end.Add(-w.span)
+	var points []dataviz.TimeSeriesData
+	for _, p := range history {
+		if !p.Date.Before(start) && !p.Date.After(end) {
+			points = append(points, p)
+		}
+	}
+	if n <= 0 || len(points) <= n {
+		return points
+	}
+
+	// Average the points falling into each of n equal slices of the range,
+	// dated at the last point of the slice.
+	var averaged []dataviz.TimeSeriesData
+	slice, sum, count := -1, 0, 0
+	flush := func(date time.Time) {
+		if count > 0 {
+			averaged = append(averaged, dataviz.TimeSeriesData{Date: date, Value: int(math.Round(float64(sum) / float64(count)))})
+		}
+	}
+	for i, p := range points {
+		s := min(n-1, int(int64(n)*int64(p.Date.Sub(start))/int64(w.span)))
+		if s != slice && i > 0 {
+			flush(points[i-1].Date)
+			sum, count = 0, 0
+		}
+		slice = s
+		sum += p.Value
+		count++
+	}
+	flush(points[len(points)-1].Date)
+	return averaged
+}
+
+// text describes the range: live while following the newest point, or
+// the time it ends at while looking at history.
+func (w timeRange) text() string {
+	span := formatSpan(w.span)
+	if w.live {
+		return "● LIVE " + span
+	}
+	return fmt.Sprintf("◌ HISTORY %s to %s", span, w.end.Format("Jan 2 15:04"))
+}
+
+// formatSpan writes a span in days when it is several whole days, and
+// otherwise in hours and minutes.
+func formatSpan(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d > day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh%dm", hours, minutes)
+}
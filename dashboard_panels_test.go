@@ -0,0 +1,159 @@
+//go:build dashboard
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// testHistory returns a day of points an hour apart, valued by their hour.
+func testHistory() []dataviz.TimeSeriesData {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	history := make([]dataviz.TimeSeriesData, 25)
+	for i := range history {
+		history[i] = dataviz.TimeSeriesData{Date: start.Add(time.Duration(i) * time.Hour), Value: i}
+	}
+	return history
+}
+
+func TestTimeRangePan(t *testing.T) {
+	history := testHistory()
+	_, last := historyBounds(history)
+	w := timeRange{span: 4 * time.Hour, live: true}
+
+	w.pan(history, -1)
+	if w.live || !w.end.Equal(last.Add(-time.Hour)) {
+		t.Errorf("after panning back: live %v, end %v, want history ending %v", w.live, w.end, last.Add(-time.Hour))
+	}
+	for i := 0; i < 200; i++ {
+		w.pan(history, -1)
+	}
+	if first, _ := historyBounds(history); !w.end.Equal(first.Add(w.span)) {
+		t.Errorf("panned past the oldest point: end %v", w.end)
+	}
+	for i := 0; i < 200 && !w.live; i++ {
+		w.pan(history, 1)
+	}
+	if !w.live {
+		t.Error("panning up to the newest point does not go live")
+	}
+}
+
+func TestTimeRangeZoom(t *testing.T) {
+	history := testHistory()
+	w := timeRange{span: 4 * time.Hour, live: true}
+	w.zoom(history, 0.5)
+	if w.span != 2*time.Hour || !w.live {
+		t.Errorf("zoomed in live: span %v, live %v", w.span, w.live)
+	}
+	for i := 0; i < 10; i++ {
+		w.zoom(history, 0.5)
+	}
+	if w.span != minSpan {
+		t.Errorf("span %v, want at least %v", w.span, minSpan)
+	}
+	for i := 0; i < 20; i++ {
+		w.zoom(history, 2)
+	}
+	if w.span != maxSpan {
+		t.Errorf("span %v, want at most %v", w.span, maxSpan)
+	}
+
+	// Zooming into history keeps the middle of the range.
+	_, last := historyBounds(history)
+	w = timeRange{span: 8 * time.Hour, end: last.Add(-8 * time.Hour)}
+	middle := w.end.Add(-w.span / 2)
+	w.zoom(history, 0.5)
+	if got := w.end.Add(-w.span / 2); !got.Equal(middle) {
+		t.Errorf("middle moved from %v to %v", middle, got)
+	}
+}
+
+func TestTimeRangePoints(t *testing.T) {
+	history := testHistory()
+	_, last := historyBounds(history)
+	w := timeRange{span: 4 * time.Hour, end: last.Add(-10 * time.Hour)}
+	points := w.points(history, 0)
+	if len(points) != 5 || points[0].Value != 10 || points[4].Value != 14 {
+		t.Errorf("points = %v, want hours 10 to 14", points)
+	}
+	averaged := w.points(history, 2)
+	if len(averaged) != 2 || averaged[0].Value != 11 || averaged[1].Value != 13 {
+		t.Errorf("averaged = %v, want 11 and 13", averaged)
+	}
+}
+
+func TestFormatSpan(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Minute, "30m"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h30m"},
+		{24 * time.Hour, "24h"},
+		{7 * 24 * time.Hour, "7d"},
+	}
+	for _, tt := range tests {
+		if got := formatSpan(tt.d); got != tt.want {
+			t.Errorf("formatSpan(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestNewPanel(t *testing.T) {
+	data := generateInitialData()
+	for _, name := range []string{"heatmap", "line-graph", "bar-chart", "area", "scatter", "histogram", "donut", "slo"} {
+		p, err := newPanel(name, data)
+		if err != nil {
+			t.Errorf("newPanel(%q): %v", name, err)
+			continue
+		}
+		if p.Title() == "" || p.Data() == nil {
+			t.Errorf("%s: untitled or without data", name)
+		}
+		if src, ok := p.(alertSource); !ok || len(src.Metrics()) == 0 {
+			t.Errorf("%s: no metrics for alert rules", name)
+		}
+	}
+	if _, err := newPanel("radar", data); err == nil {
+		t.Error("unknown panel created")
+	}
+}
+
+func TestRegisterPanelTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a panel name twice did not panic")
+		}
+	}()
+	RegisterPanel("heatmap", func(d *dashboardData) Panel { return nil })
+}
+
+func TestNewDashboardViews(t *testing.T) {
+	data := generateInitialData()
+	views, err := newDashboardViews(data, []string{"slo", " donut"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != len(dashboardViews) {
+		t.Fatalf("%d views, want %d", len(views), len(dashboardViews))
+	}
+	multi := views[3]
+	if multi.name != "multi" || !slices.Equal(multi.names, []string{"slo", "donut"}) || multi.Title() != "DataViz Dashboard" {
+		t.Errorf("multi view = %s %q %q", multi.name, multi.names, multi.Title())
+	}
+	if views[0].name != "heatmap" || views[0].Title() != views[0].panels[0].Title() {
+		t.Errorf("single view = %s %q", views[0].name, views[0].Title())
+	}
+
+	for _, multi := range [][]string{{"heatmap", "nope"}, nil} {
+		if _, err := newDashboardViews(data, multi); err == nil {
+			t.Errorf("multi view of %q created", multi)
+		}
+	}
+}
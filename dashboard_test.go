@@ -0,0 +1,64 @@
+//go:build dashboard
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestClaimSnapshotName(t *testing.T) {
+	dir := t.TempDir()
+	at := time.Date(2024, 3, 10, 14, 30, 5, 0, time.Local)
+	want := []string{"dashboard-20240310-143005", "dashboard-20240310-143005-2", "dashboard-20240310-143005-3"}
+	for _, name := range want {
+		base, err := claimSnapshotName(dir, at, ".json")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base != filepath.Join(dir, name) {
+			t.Errorf("claimSnapshotName = %s, want %s", base, name)
+		}
+	}
+}
+
+func TestSnapshotScreen(t *testing.T) {
+	rule, _ := parseAlertRule("line-graph: line > -1")
+	m, err := initialDashboardModel(t.TempDir(), []string{"heatmap", "line-graph"}, []alertRule{rule}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	model, _ = model.Update(tickMsg(time.Now()))
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	m = model.(dashboardModel)
+	if !m.showAlerts {
+		t.Fatal("alert history closed")
+	}
+
+	var bases []string
+	for range 2 {
+		msg := m.snapshot()().(snapshotMsg)
+		if msg.err != nil {
+			t.Fatal(msg.err)
+		}
+		bases = append(bases, msg.base)
+	}
+	if bases[0] == bases[1] {
+		t.Errorf("two snapshots saved as %s", bases[0])
+	}
+
+	ans, err := os.ReadFile(bases[0] + ".ans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := m
+	plain.showAlerts = false
+	if string(ans) != plain.View() {
+		t.Errorf("snapshot screen is not the view without the alert history:\n%q", ans)
+	}
+}
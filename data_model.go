@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// The dataviz types store values as ints. viz-cli reads its input into the
+// float types below so that fractional and negative values survive, and
+// converts to dataviz types only when handing data to a dataviz renderer.
+
+// timePoint is a dated value.
+type timePoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// barValue is a single bar. Secondary is stacked on top of Value when the
+// chart is stacked; Values holds one value per segment of a segmented
+// chart.
+type barValue struct {
+	Label     string    `json:"label"`
+	Value     float64   `json:"value"`
+	Secondary float64   `json:"secondary"`
+	Values    []float64 `json:"values"`
+}
+
+// heatmapDay is one day of a heatmap.
+type heatmapDay struct {
+	Date  time.Time `json:"date"`
+	Count float64   `json:"count"`
+}
+
+// integral reports whether every value is a whole number.
+func integral(values []float64) bool {
+	for _, v := range values {
+		if v != math.Trunc(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonNegative reports whether no value is below zero.
+func nonNegative(values []float64) bool {
+	for _, v := range values {
+		if v < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// scaledInts converts values to ints for dataviz renderers, which scale
+// their output relative to the largest value. Whole numbers pass through
+// unchanged; otherwise all values are multiplied by a common factor so the
+// relative shape survives rounding.
+func scaledInts(values []float64) []int {
+	factor := 1.0
+	if !integral(values) {
+		largest := 0.0
+		for _, v := range values {
+			largest = math.Max(largest, math.Abs(v))
+		}
+		if largest > 0 {
+			factor = 10000 / largest
+		}
+	}
+	ints := make([]int, len(values))
+	for i, v := range values {
+		ints[i] = int(math.Round(v * factor))
+	}
+	return ints
+}
+
+func pointValues(points []timePoint) []float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+// toTimeSeries converts points for dataviz, rescaling fractional values.
+func toTimeSeries(points []timePoint) []dataviz.TimeSeriesData {
+	ints := scaledInts(pointValues(points))
+	series := make([]dataviz.TimeSeriesData, len(points))
+	for i, p := range points {
+		series[i] = dataviz.TimeSeriesData{Date: p.Date, Value: ints[i]}
+	}
+	return series
+}
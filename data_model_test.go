@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func TestScaledInts(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   []int
+	}{
+		{[]float64{1, -2, 3}, []int{1, -2, 3}},
+		{[]float64{0.5, -0.25, 1}, []int{5000, -2500, 10000}},
+		{[]float64{0, 0}, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		got := scaledInts(tt.values)
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("scaledInts(%v) = %v, want %v", tt.values, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLineGraphFitsDataviz(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		values []float64
+		want   bool
+	}{
+		{"whole", []float64{1, 2, 3}, true},
+		{"fractional", []float64{1, 2.5}, false},
+		{"negative", []float64{3, -4, 5}, false},
+	}
+	for _, tt := range tests {
+		var in lineGraphInput
+		for i, v := range tt.values {
+			in.Points = append(in.Points, timePoint{Date: day.AddDate(0, 0, i), Value: v})
+		}
+		if got := in.fitsDataviz(); got != tt.want {
+			t.Errorf("%s: fitsDataviz() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBarLanesFromZero(t *testing.T) {
+	var in barChartInput
+	if err := json.Unmarshal([]byte(`{"bars": [
+		{"label": "up", "value": 2.5},
+		{"label": "down", "value": -4}]}`), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.fitsDataviz() {
+		t.Error("fractional and negative bars fit the dataviz renderer")
+	}
+	l := in.layout(dataviz.RenderConfig{}, defaultChartOptions())
+	if l.lo != -4 || l.hi != 2.5 {
+		t.Errorf("range = %v..%v, want -4..2.5", l.lo, l.hi)
+	}
+	for i, want := range [][2]float64{{0, 2.5}, {-4, 0}} {
+		p := l.lanes[i][0].Pieces[0]
+		if p.From != want[0] || p.To != want[1] {
+			t.Errorf("bar %d spans %v..%v, want %v..%v", i, p.From, p.To, want[0], want[1])
+		}
+	}
+}
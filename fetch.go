@@ -0,0 +1,157 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxFetchBytes bounds the size of a fetched data file.
+const maxFetchBytes = 64 << 20
+
+// fetchOptions configures how -data is read when it is a URL. Select
+// applies to local data as well.
+type fetchOptions struct {
+	Headers  []string
+	Timeout  time.Duration
+	CacheDir string
+	Select   string
+}
+
+// defaultCacheDir is where the last successful response for each URL is
+// kept, or "" when the system has no cache directory.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "viz-cli")
+}
+
+// headerFlag is a repeatable command line flag holding "Name: value"
+// request headers.
+type headerFlag struct {
+	list *[]string
+}
+
+func (f headerFlag) String() string { return "" }
+
+func (f headerFlag) Set(header string) error {
+	name, _, ok := strings.Cut(header, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("invalid header %q (want 'Name: value')", header)
+	}
+	*f.list = append(*f.list, header)
+	return nil
+}
+
+// isURL reports whether a -data value names an HTTP resource.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// unavailableError is a fetch failure that says nothing about the request
+// itself: the server could not be reached, timed out or failed with a 5xx
+// status.
+type unavailableError struct {
+	err error
+}
+
+func (e unavailableError) Error() string { return e.err.Error() }
+func (e unavailableError) Unwrap() error { return e.err }
+
+// fetchData downloads JSON or CSV data. A successful response is cached;
+// when the source is unavailable, the cached copy is used instead, with a
+// warning, so that charts keep rendering through an outage. Other errors,
+// such as a 4xx status for a wrong URL or missing credentials, are
+// returned as they are.
+func fetchData(url string, opts fetchOptions) ([]byte, error) {
+	data, err := fetch(url, opts)
+	cache := cachePath(url, opts)
+	if err == nil {
+		if cache != "" && os.MkdirAll(filepath.Dir(cache), 0o700) == nil {
+			writeOutput(cache, string(data))
+		}
+		return data, nil
+	}
+	var unavailable unavailableError
+	if cache == "" || !errors.As(err, &unavailable) {
+		return nil, err
+	}
+	info, statErr := os.Stat(cache)
+	if statErr != nil {
+		return nil, err
+	}
+	cached, readErr := os.ReadFile(cache)
+	if readErr != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %v; using the copy cached at %s\n", err, info.ModTime().Format("2006-01-02 15:04"))
+	return cached, nil
+}
+
+// fetch makes one GET request, failing on any status other than 2xx.
+// Transport errors, timeouts and 5xx statuses are unavailableErrors.
+func fetch(url string, opts fetchOptions) ([]byte, error) {
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, text/csv;q=0.9, */*;q=0.1")
+	for _, header := range opts.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, unavailableError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("fetching %s: %s", url, resp.Status)
+		if resp.StatusCode >= 500 {
+			return nil, unavailableError{err}
+		}
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes+1))
+	if err != nil {
+		return nil, unavailableError{fmt.Errorf("fetching %s: %w", url, err)}
+	}
+	if len(data) > maxFetchBytes {
+		return nil, fmt.Errorf("fetching %s: response larger than %d bytes", url, maxFetchBytes)
+	}
+	return data, nil
+}
+
+// cachePath names the cache file for a URL. Headers are part of the key,
+// since they may select a different user's view of the same resource.
+func cachePath(url string, opts fetchOptions) string {
+	if opts.CacheDir == "" {
+		return ""
+	}
+	h := sha256.New()
+	h.Write([]byte(url))
+	for _, header := range opts.Headers {
+		h.Write([]byte{0})
+		h.Write([]byte(header))
+	}
+	return filepath.Join(opts.CacheDir, hex.EncodeToString(h.Sum(nil)[:16])+".data")
+}
@@ -0,0 +1,148 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" || r.Header.Get("X-Team") != "web: ops" {
+			http.Error(w, "missing headers", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"value": 1}`))
+	}))
+	defer srv.Close()
+
+	var headers []string
+	f := headerFlag{&headers}
+	for _, h := range []string{"Authorization: Bearer abc", "X-Team:web: ops"} {
+		if err := f.Set(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := fetch(srv.URL, fetchOptions{Headers: headers})
+	if err != nil || string(data) != `{"value": 1}` {
+		t.Errorf("fetch = %q, %v", data, err)
+	}
+	if err := f.Set("no colon"); err == nil {
+		t.Error("header without a colon accepted")
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := fetch(srv.URL, fetchOptions{Timeout: 20 * time.Millisecond})
+	if err == nil {
+		t.Fatal("slow response did not time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timed out after %v", elapsed)
+	}
+}
+
+func TestFetchDataCacheFallback(t *testing.T) {
+	var status atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if code := int(status.Load()); code != http.StatusOK {
+			if code == 0 {
+				time.Sleep(200 * time.Millisecond)
+				return
+			}
+			w.WriteHeader(code)
+			return
+		}
+		w.Write([]byte(`{"value": 7}`))
+	}))
+	defer srv.Close()
+
+	opts := fetchOptions{Timeout: 50 * time.Millisecond, CacheDir: t.TempDir()}
+	status.Store(http.StatusOK)
+	if _, err := fetchData(srv.URL, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		status   int
+		fallback bool
+	}{
+		{"server error", http.StatusServiceUnavailable, true},
+		{"timeout", 0, true},
+		{"not found", http.StatusNotFound, false},
+		{"unauthorized", http.StatusUnauthorized, false},
+	}
+	for _, tt := range tests {
+		status.Store(int32(tt.status))
+		data, err := fetchData(srv.URL, opts)
+		switch {
+		case tt.fallback && (err != nil || string(data) != `{"value": 7}`):
+			t.Errorf("%s: got %q, %v, want the cached copy", tt.name, data, err)
+		case !tt.fallback && err == nil:
+			t.Errorf("%s: got %q, want an error", tt.name, data)
+		}
+	}
+
+	// Headers are part of the cache key.
+	status.Store(http.StatusServiceUnavailable)
+	opts.Headers = []string{"Authorization: Bearer other"}
+	if _, err := fetchData(srv.URL, opts); err == nil {
+		t.Error("another user's request fell back to the cached copy")
+	}
+}
+
+func TestReadDataSelect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"series": [{"values": [1, 2]}, {"values": [3]}]}}`))
+	}))
+	defer srv.Close()
+
+	tests := []struct{ expr, want string }{
+		{"$.data.series[0].values", `[1,2]`},
+		{"data.series[-1]", `{"values":[3]}`},
+		{`$["data"].series[*].values[0]`, `[1,3]`},
+		{"points=$.data.series[1].values", `{"points":[3]}`},
+	}
+	for _, tt := range tests {
+		data, err := readData(srv.URL, fetchOptions{Select: tt.expr})
+		if err != nil || string(data) != tt.want {
+			t.Errorf("-select %s = %s, %v, want %s", tt.expr, data, err, tt.want)
+		}
+	}
+}
+
+func TestSelectDataErrors(t *testing.T) {
+	doc := []byte(`{"items": [1, 2], "name": "x"}`)
+	tests := []struct{ expr, want string }{
+		{"items[2]", "has no element 2"},
+		{"items.first", "is not an object"},
+		{"name[0]", "is not an array"},
+		{"missing", `has no field "missing"`},
+		{"items[x]", "bad index"},
+		{"items[0", "unclosed"},
+		{"items..a", "empty field name"},
+	}
+	for _, tt := range tests {
+		_, err := selectData(doc, tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("-select %s: error %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+	if _, err := selectData([]byte("a,b\n1,2\n"), "a"); err == nil {
+		t.Error("-select on CSV data succeeded")
+	}
+}
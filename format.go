@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numberFormat turns values into labels for axis ticks, bar labels,
+// tooltips and stat cards. It is parsed from the -number-format flag:
+//
+//	plain        shortest representation, no grouping (default)
+//	grouped[:N]  locale digit grouping, e.g. 1,234,567
+//	si[:N]       SI suffixes, e.g. 1.2k, 3.4M
+//	bytes[:N]    binary byte units, e.g. 1.5 KiB, 3.2 MiB
+//	percent[:N]  fractions as percentages, e.g. 0.25 -> 25%
+//	currency[:C] money in currency C (USD, EUR, GBP, JPY, ...)
+//	fixed:N      exactly N decimals
+//
+// N is the number of decimals; when omitted it is chosen automatically.
+type numberFormat struct {
+	Kind     string
+	Decimals int // -1 chooses automatically
+	Currency string
+	Locale   locale
+}
+
+// locale holds the separators used when formatting numbers.
+type locale struct {
+	Group   string
+	Decimal string
+	// CurrencyAfter places currency symbols after the number.
+	CurrencyAfter bool
+}
+
+var locales = map[string]locale{
+	"en": {Group: ",", Decimal: "."},
+	"de": {Group: ".", Decimal: ",", CurrencyAfter: true},
+	"es": {Group: ".", Decimal: ",", CurrencyAfter: true},
+	"fr": {Group: " ", Decimal: ",", CurrencyAfter: true},
+	"it": {Group: ".", Decimal: ",", CurrencyAfter: true},
+	"nl": {Group: ".", Decimal: ","},
+	"sv": {Group: " ", Decimal: ",", CurrencyAfter: true},
+	"ch": {Group: "'", Decimal: "."},
+	"ja": {Group: ",", Decimal: "."},
+	"in": {Group: ",", Decimal: "."},
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"CHF": "CHF ",
+	"INR": "₹",
+	"SEK": "kr",
+}
+
+// parseLocale looks up a locale by language tag such as "de" or "de-DE".
+func parseLocale(tag string) (locale, error) {
+	if tag == "" {
+		return locales["en"], nil
+	}
+	lang := strings.ToLower(strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0])
+	l, ok := locales[lang]
+	if !ok {
+		return locale{}, fmt.Errorf("unsupported locale %q", tag)
+	}
+	return l, nil
+}
+
+// parseNumberFormat parses a -number-format value.
+func parseNumberFormat(spec, localeTag string) (numberFormat, error) {
+	loc, err := parseLocale(localeTag)
+	if err != nil {
+		return numberFormat{}, err
+	}
+	f := numberFormat{Kind: "plain", Decimals: -1, Locale: loc}
+	if spec == "" {
+		return f, nil
+	}
+
+	kind, arg, hasArg := strings.Cut(spec, ":")
+	f.Kind = kind
+	switch kind {
+	case "plain", "grouped", "si", "bytes", "percent", "fixed":
+		if hasArg {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return numberFormat{}, fmt.Errorf("invalid decimals %q in number format %q", arg, spec)
+			}
+			f.Decimals = n
+		} else if kind == "fixed" {
+			return numberFormat{}, fmt.Errorf("number format %q needs a decimal count, e.g. fixed:2", spec)
+		}
+	case "currency":
+		f.Currency = "USD"
+		if hasArg {
+			f.Currency = strings.ToUpper(arg)
+		}
+	default:
+		return numberFormat{}, fmt.Errorf("unknown number format %q", spec)
+	}
+	return f, nil
+}
+
+// Format renders v according to the format.
+func (f numberFormat) Format(v float64) string {
+	return f.format(v, f.Decimals)
+}
+
+// FormatTick renders an axis tick, picking enough decimals to tell ticks
+// step apart when the format does not fix them.
+func (f numberFormat) FormatTick(v, step float64) string {
+	decimals := f.Decimals
+	if decimals < 0 && f.Kind != "si" && f.Kind != "bytes" {
+		decimals = 0
+		if f.Kind == "percent" {
+			step *= 100
+		}
+		if step > 0 && step < 1 {
+			decimals = int(math.Ceil(-math.Log10(step) - 1e-9))
+		}
+	}
+	return f.format(v, decimals)
+}
+
+func (f numberFormat) format(v float64, decimals int) string {
+	switch f.Kind {
+	case "grouped", "fixed":
+		if decimals < 0 {
+			decimals = autoDecimals(v)
+		}
+		return f.group(v, decimals)
+	case "si":
+		return f.scaled(v, decimals, 1000, []string{"", "k", "M", "G", "T", "P", "E"}, "")
+	case "bytes":
+		return f.scaled(v, decimals, 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}, " ")
+	case "percent":
+		if decimals < 0 {
+			decimals = autoDecimals(v * 100)
+		}
+		return f.group(v*100, decimals) + "%"
+	case "currency":
+		return f.money(v, decimals)
+	default:
+		if decimals >= 0 {
+			return f.decimalPoint(strconv.FormatFloat(v, 'f', decimals, 64))
+		}
+		return f.decimalPoint(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+}
+
+// scaled divides v by base until it fits below base and appends the unit.
+func (f numberFormat) scaled(v float64, decimals int, base float64, units []string, sep string) string {
+	i := 0
+	for math.Abs(v) >= base && i < len(units)-1 {
+		v /= base
+		i++
+	}
+	if decimals < 0 {
+		decimals = 1
+		if i == 0 || math.Abs(v) >= 100 || v == math.Trunc(v) {
+			decimals = 0
+		}
+	}
+	s := f.decimalPoint(strconv.FormatFloat(v, 'f', decimals, 64))
+	if units[i] == "" {
+		return s
+	}
+	return s + sep + units[i]
+}
+
+func (f numberFormat) money(v float64, decimals int) string {
+	if decimals < 0 {
+		decimals = 2
+		if f.Currency == "JPY" {
+			decimals = 0
+		}
+	}
+	symbol, ok := currencySymbols[f.Currency]
+	if !ok {
+		symbol = f.Currency + " "
+	}
+	sign := ""
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	amount := f.group(v, decimals)
+	if f.Locale.CurrencyAfter {
+		return sign + amount + " " + strings.TrimSpace(symbol)
+	}
+	return sign + symbol + amount
+}
+
+// group formats v with a fixed number of decimals and locale separators.
+func (f numberFormat) group(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(f.Locale.Group)
+		}
+		b.WriteRune(digit)
+	}
+	if frac != "" {
+		b.WriteString(f.Locale.Decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+func (f numberFormat) decimalPoint(s string) string {
+	if f.Locale.Decimal == "" || f.Locale.Decimal == "." {
+		return s
+	}
+	return strings.Replace(s, ".", f.Locale.Decimal, 1)
+}
+
+// autoDecimals keeps two significant decimals for small fractional values
+// and none for whole numbers.
+func autoDecimals(v float64) int {
+	if v == math.Trunc(v) {
+		return 0
+	}
+	if math.Abs(v) >= 100 {
+		return 0
+	}
+	if math.Abs(v) >= 1 {
+		return 2
+	}
+	return 3
+}
+
+// dateFormat renders dates for axis ticks and tooltips. It is parsed from
+// the -date-format flag:
+//
+//	auto       chosen from the span of the data (default)
+//	iso        2006-01-02, with the time when it is not midnight
+//	rfc3339    full RFC 3339 timestamp
+//	relative   "3d ago", "in 2h"
+//	<layout>   any Go time layout, e.g. "Jan 2 15:04"
+type dateFormat struct {
+	Kind   string
+	Layout string
+	// Now is the reference point for relative dates.
+	Now time.Time
+}
+
+func parseDateFormat(spec string) dateFormat {
+	switch spec {
+	case "", "auto":
+		return dateFormat{Kind: "auto"}
+	case "iso", "rfc3339", "relative":
+		return dateFormat{Kind: spec, Now: time.Now()}
+	default:
+		return dateFormat{Kind: "layout", Layout: spec}
+	}
+}
+
+// Format renders t, using fallback as the layout for the auto format.
+func (f dateFormat) Format(t time.Time, fallback string) string {
+	switch f.Kind {
+	case "iso":
+		if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+			return t.Format("2006-01-02")
+		}
+		return t.Format("2006-01-02 15:04")
+	case "rfc3339":
+		return t.Format(time.RFC3339)
+	case "relative":
+		return relativeTime(t, f.Now)
+	case "layout":
+		return t.Format(f.Layout)
+	default:
+		return t.Format(fallback)
+	}
+}
+
+// relativeTime describes t relative to now in the largest whole unit.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	suffix := " ago"
+	prefix := ""
+	if d < 0 {
+		d = -d
+		suffix, prefix = "", "in "
+	}
+	var amount string
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		amount = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		amount = fmt.Sprintf("%dmo", int(d/(30*24*time.Hour)))
+	default:
+		amount = fmt.Sprintf("%dy", int(d/(365*24*time.Hour)))
+	}
+	return prefix + amount + suffix
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNumberFormat(t *testing.T) {
+	tests := []struct {
+		spec, locale string
+		v            float64
+		want         string
+	}{
+		{"", "", 1234.5, "1234.5"},
+		{"grouped", "", 1234567, "1,234,567"},
+		{"grouped:2", "de", -1234.5, "-1.234,50"},
+		{"grouped:2", "fr-FR", 1234567.25, "1\u202f234\u202f567,25"},
+		{"si", "", 1234, "1.2k"},
+		{"si", "", 3400000, "3.4M"},
+		{"si:2", "", 999, "999.00"},
+		{"bytes", "", 1536, "1.5 KiB"},
+		{"bytes", "", 512, "512 B"},
+		{"percent", "", 0.25, "25%"},
+		{"percent:1", "", 0.1234, "12.3%"},
+		{"currency", "", -1234.5, "-$1,234.50"},
+		{"currency:EUR", "de", 1234.5, "1.234,50 €"},
+		{"currency:JPY", "", 1500, "¥1,500"},
+		{"currency:NOK", "", 10, "NOK 10.00"},
+		{"fixed:3", "", 2, "2.000"},
+		{"grouped", "", -0.0001, "0.000"},
+	}
+	for _, tt := range tests {
+		f, err := parseNumberFormat(tt.spec, tt.locale)
+		if err != nil {
+			t.Errorf("parseNumberFormat(%q, %q): %v", tt.spec, tt.locale, err)
+			continue
+		}
+		if got := f.Format(tt.v); got != tt.want {
+			t.Errorf("%q in %q: Format(%v) = %q, want %q", tt.spec, tt.locale, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseNumberFormatErrors(t *testing.T) {
+	for _, tt := range []struct{ spec, locale string }{
+		{"fixed", ""},
+		{"si:x", ""},
+		{"grouped:-1", ""},
+		{"roman", ""},
+		{"", "xx"},
+	} {
+		if _, err := parseNumberFormat(tt.spec, tt.locale); err == nil {
+			t.Errorf("parseNumberFormat(%q, %q) succeeded, want an error", tt.spec, tt.locale)
+		}
+	}
+}
+
+func TestFormatTick(t *testing.T) {
+	plain, _ := parseNumberFormat("", "")
+	percent, _ := parseNumberFormat("percent", "")
+	tests := []struct {
+		f       numberFormat
+		v, step float64
+		want    string
+	}{
+		{plain, 20, 10, "20"},
+		{plain, 0.5, 0.05, "0.50"},
+		{plain, 1, 0.1, "1.0"},
+		{percent, 0.2, 0.1, "20%"},
+		{percent, 0.025, 0.005, "2.5%"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.FormatTick(tt.v, tt.step); got != tt.want {
+			t.Errorf("%s: FormatTick(%v, %v) = %q, want %q", tt.f.Kind, tt.v, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		t    time.Time
+		want string
+	}{
+		{"auto", now, "Mar 10"},
+		{"iso", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), "2024-03-10"},
+		{"iso", now, "2024-03-10 12:00"},
+		{"rfc3339", now, "2024-03-10T12:00:00Z"},
+		{"relative", now.Add(-3 * 24 * time.Hour), "3d ago"},
+		{"relative", now.Add(2 * time.Hour), "in 2h"},
+		{"relative", now.Add(-30 * time.Second), "now"},
+		{"2006/01/02", now, "2024/03/10"},
+	}
+	for _, tt := range tests {
+		f := parseDateFormat(tt.spec)
+		f.Now = now
+		if got := f.Format(tt.t, "Jan 2"); got != tt.want {
+			t.Errorf("%q: Format(%v) = %q, want %q", tt.spec, tt.t, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,451 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// gaugeThresholds are the values at which a gauge turns to warn and
+// critical. When critical is below warn, low values are the bad ones, as
+// for a remaining error budget; otherwise high values are, as for quota
+// usage.
+type gaugeThresholds struct {
+	Warn     *float64 `json:"warn"`
+	Critical *float64 `json:"critical"`
+}
+
+// gaugeInput is the JSON accepted by -type gauge, progress and bullet. Max
+// defaults to 100.
+type gaugeInput struct {
+	Label      string          `json:"label"`
+	Value      float64         `json:"value"`
+	Min        float64         `json:"min"`
+	Max        *float64        `json:"max"`
+	Target     *float64        `json:"target"`
+	Thresholds gaugeThresholds `json:"thresholds"`
+
+	// Style is "gauge", "progress" or "bullet", taken from -type.
+	Style string `json:"-"`
+}
+
+// gaugeBand is a range of the scale with one status, or no status when
+// there are no thresholds.
+type gaugeBand struct {
+	From, To float64
+	Status   string
+}
+
+// scale returns the range of the gauge.
+func (in gaugeInput) scale() valueScale {
+	hi := 100.0
+	if in.Max != nil {
+		hi = *in.Max
+	}
+	if hi <= in.Min {
+		hi = in.Min + 1
+	}
+	return valueScale{Min: in.Min, Max: hi}
+}
+
+// bands splits the scale at the thresholds.
+func (in gaugeInput) bands() []gaugeBand {
+	s := in.scale()
+	type cut struct {
+		at     float64
+		status string // status from this value up
+	}
+	warn, critical := in.Thresholds.Warn, in.Thresholds.Critical
+	base := "good"
+	var cuts []cut
+	switch {
+	case warn == nil && critical == nil:
+		return []gaugeBand{{From: s.Min, To: s.Max}}
+	case warn != nil && critical != nil && *critical < *warn:
+		base = "critical"
+		cuts = []cut{{*critical, "warn"}, {*warn, "good"}}
+	default:
+		if warn != nil {
+			cuts = append(cuts, cut{*warn, "warn"})
+		}
+		if critical != nil {
+			cuts = append(cuts, cut{*critical, "critical"})
+		}
+	}
+
+	var bands []gaugeBand
+	from, status := s.Min, base
+	for _, c := range cuts {
+		at := math.Max(s.Min, math.Min(s.Max, c.at))
+		if at > from {
+			bands = append(bands, gaugeBand{From: from, To: at, Status: status})
+			from = at
+		}
+		status = c.status
+	}
+	if s.Max > from {
+		bands = append(bands, gaugeBand{From: from, To: s.Max, Status: status})
+	}
+	return bands
+}
+
+// bandColor returns the theme color of a band's status, or the chart color
+// for a gauge without thresholds.
+func bandColor(b gaugeBand, config dataviz.RenderConfig) string {
+	if b.Status == "" {
+		return config.Color
+	}
+	return statusColor(config.Theme, b.Status)
+}
+
+// bandAt returns the band that v falls in; values beyond the scale fall in
+// the first or last band.
+func bandAt(bands []gaugeBand, v float64) gaugeBand {
+	for _, b := range bands {
+		if v < b.To {
+			return b
+		}
+	}
+	return bands[len(bands)-1]
+}
+
+// valueColor returns the color of the band the value falls in.
+func (in gaugeInput) valueColor(config dataviz.RenderConfig) string {
+	return bandColor(bandAt(in.bands(), in.Value), config)
+}
+
+// Terminal implements chart.
+func (in gaugeInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	switch in.Style {
+	case "progress":
+		return in.progressTerminal(bounds, config, opts)
+	case "bullet":
+		return in.bulletTerminal(bounds, config, opts)
+	default:
+		return in.dialTerminal(bounds, config, opts)
+	}
+}
+
+// SVG implements chart.
+func (in gaugeInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	switch in.Style {
+	case "progress":
+		return in.progressSVG(bounds, config, opts)
+	case "bullet":
+		return in.bulletSVG(bounds, config, opts)
+	default:
+		return in.dialSVG(bounds, config, opts)
+	}
+}
+
+// barLayout splits a line into a label gutter, a bar and the value text,
+// for the one-line progress and bullet renderings.
+func (in gaugeInput) barLayout(width int, opts chartOptions) (label string, gutter, cols int, value string) {
+	value = opts.Numbers.Format(in.Value)
+	if in.Label != "" {
+		label = in.Label
+		if runes := []rune(label); len(runes) > width/3 {
+			label = string(runes[:max(0, width/3-1)]) + "…"
+		}
+		gutter = utf8.RuneCountInString(label) + 1
+	}
+	cols = max(1, width-gutter-1-utf8.RuneCountInString(value))
+	return label, gutter, cols, value
+}
+
+// progressTerminal draws a single line: label, a bar in the status color
+// over a dim track, a target marker and the value.
+func (in gaugeInput) progressTerminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	label, gutter, cols, value := in.barLayout(bounds.Width, opts)
+	s := in.scale()
+	cells := barCells(s.Ratio(in.Value), cols, -1)
+	target := -1
+	if in.Target != nil {
+		target = int(math.Round(s.Ratio(*in.Target) * float64(cols-1)))
+	}
+
+	var b strings.Builder
+	if label != "" {
+		b.WriteString(label + strings.Repeat(" ", gutter-utf8.RuneCountInString(label)))
+	}
+	color := in.valueColor(config)
+	for col := 0; col < cols; col++ {
+		switch {
+		case col == target:
+			b.WriteString(ansiBold + "┃" + ansiReset)
+		case cells[col] != "":
+			run := col
+			for run < cols && cells[run] != "" && run != target {
+				run++
+			}
+			b.WriteString(colorize(strings.Join(cells[col:run], ""), color))
+			col = run - 1
+		default:
+			b.WriteString(ansiDim + "░" + ansiReset)
+		}
+	}
+	b.WriteString(" " + value + "\n")
+	return b.String()
+}
+
+// bulletTerminal draws the threshold bands as cell backgrounds with the
+// value as a heavy line across them and the target as a vertical mark,
+// followed by a scale when there is room.
+func (in gaugeInput) bulletTerminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	label, gutter, cols, value := in.barLayout(bounds.Width, opts)
+	s := in.scale()
+	background, _ := themeColors(config)
+	if background == "" {
+		background = "#000000"
+	}
+	bands := in.bands()
+	end := int(math.Round(s.Ratio(in.Value) * float64(cols)))
+	target := -1
+	if in.Target != nil {
+		target = int(math.Round(s.Ratio(*in.Target) * float64(cols-1)))
+	}
+
+	var b strings.Builder
+	if label != "" {
+		b.WriteString(label + strings.Repeat(" ", gutter-utf8.RuneCountInString(label)))
+	}
+	for col := 0; col < cols; col++ {
+		v := s.Min + (float64(col)+0.5)/float64(cols)*(s.Max-s.Min)
+		bg := mixColors(background, bandColor(bandAt(bands, v), config), 0.35)
+		glyph := " "
+		switch {
+		case col == target:
+			glyph = "┃"
+		case col < end:
+			glyph = "━"
+		}
+		b.WriteString(ansiBackground(bg) + ansiColor(contrastText(bg)) + glyph + ansiReset)
+	}
+	b.WriteString(" " + value + "\n")
+
+	if bounds.Height >= 3 {
+		for _, line := range xAxisLines(gutter, cols, s.Ticks(maxXTicks(cols), opts.Numbers)) {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// dialTerminal draws a half circle dial in braille: the arc is filled in
+// the status color up to the value and shows the threshold bands dimmed
+// beyond it. The value and label are centered below.
+func (in gaugeInput) dialTerminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	rows := bounds.Height - 2
+	if in.Label == "" {
+		rows++
+	}
+	if rows < 2 || bounds.Width < 4 {
+		return in.progressTerminal(bounds, config, opts)
+	}
+	canvas := newColorCanvas(bounds.Width, rows)
+	pw, ph := canvas.PixelWidth(), canvas.PixelHeight()
+	radius := math.Min(float64(pw)/2-1, float64(ph-1))
+	cx, cy := float64(pw)/2, float64(ph-1)
+
+	s := in.scale()
+	filled := s.Ratio(in.Value)
+	color := in.valueColor(config)
+	background, _ := themeColors(config)
+	if background == "" {
+		background = "#000000"
+	}
+	bands := in.bands()
+	target := -1.0
+	if in.Target != nil {
+		target = s.Ratio(*in.Target)
+	}
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			dx, dy := float64(x)+0.5-cx, cy-float64(y)
+			r := math.Hypot(dx, dy) / radius
+			if dy < 0 || r > 1 || r < 0.65 {
+				continue
+			}
+			t := 1 - math.Atan2(dy, dx)/math.Pi
+			switch {
+			case target >= 0 && math.Abs(t-target) < 0.012:
+				canvas.Set(x, y, contrastText(background))
+			case t <= filled:
+				canvas.Set(x, y, color)
+			case (x+y)%2 == 0:
+				// Stipple the unfilled part so it reads as a track.
+				band := bandAt(bands, s.Min+t*(s.Max-s.Min))
+				canvas.Set(x, y, mixColors(background, bandColor(band, config), 0.5))
+			}
+		}
+	}
+
+	center := func(text string) string {
+		pad := max(0, (bounds.Width-utf8.RuneCountInString(text))/2)
+		return strings.Repeat(" ", pad) + text
+	}
+	var b strings.Builder
+	for _, line := range canvas.Lines() {
+		b.WriteString(line + "\n")
+	}
+	// The scale ends go under the ends of the arc, the value between them.
+	lo, hi := opts.Numbers.Format(s.Min), opts.Numbers.Format(s.Max)
+	value := opts.Numbers.Format(in.Value)
+	left := max(0, int((cx-radius)/2))
+	right := min(bounds.Width, int(math.Ceil((cx+radius)/2)))
+	valueStart := max(0, (bounds.Width-utf8.RuneCountInString(value))/2)
+	valueEnd := valueStart + utf8.RuneCountInString(value)
+	var ends [2]string
+	if left+utf8.RuneCountInString(lo) < valueStart && right-utf8.RuneCountInString(hi) > valueEnd {
+		ends[0] = strings.Repeat(" ", left) + lo + strings.Repeat(" ", valueStart-left-utf8.RuneCountInString(lo))
+		ends[1] = strings.Repeat(" ", right-utf8.RuneCountInString(hi)-valueEnd) + hi
+	} else {
+		ends[0] = strings.Repeat(" ", valueStart)
+	}
+	b.WriteString(ends[0] + colorize(ansiBold+value, color) + ends[1] + "\n")
+	if in.Label != "" {
+		b.WriteString(center(in.Label) + "\n")
+	}
+	return b.String()
+}
+
+// ringArc returns the outline of a ring segment of the dial between ratios
+// from and to, with the dial running from the left (0) over the top to the
+// right (1).
+func ringArc(cx, cy, inner, outer, from, to float64) []svgPoint {
+	steps := max(1, int(math.Ceil((to-from)*90)))
+	point := func(t, r float64) svgPoint {
+		a := math.Pi * (1 - t)
+		return svgPoint{X: cx + r*math.Cos(a), Y: cy - r*math.Sin(a)}
+	}
+	points := make([]svgPoint, 0, 2*steps+2)
+	for i := 0; i <= steps; i++ {
+		points = append(points, point(from+(to-from)*float64(i)/float64(steps), outer))
+	}
+	for i := steps; i >= 0; i-- {
+		points = append(points, point(from+(to-from)*float64(i)/float64(steps), inner))
+	}
+	return points
+}
+
+// dialSVG draws the dial with a faint band track, the value arc, a target
+// mark and the value, label and scale ends as text.
+func (in gaugeInput) dialSVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	s := in.scale()
+	radius := math.Max(10, math.Min(float64(bounds.Width)/2-svgPadding, float64(bounds.Height)-2*svgPadding-40))
+	cx, cy := float64(bounds.Width)/2, svgPadding+radius
+	inner := radius * 0.72
+
+	for _, band := range in.bands() {
+		doc.Add(svgPolygon{
+			Points:  ringArc(cx, cy, inner, radius, s.Ratio(band.From), s.Ratio(band.To)),
+			Fill:    bandColor(band, config),
+			Opacity: 0.25,
+			Title:   band.Status,
+		})
+	}
+	value := opts.Numbers.Format(in.Value)
+	if filled := s.Ratio(in.Value); filled > 0 {
+		doc.Add(svgPolygon{Points: ringArc(cx, cy, inner, radius, 0, filled), Fill: in.valueColor(config), Title: value})
+	}
+	if in.Target != nil {
+		t := s.Ratio(*in.Target)
+		a := math.Pi * (1 - t)
+		doc.Add(svgLine{
+			X1: cx + (inner-6)*math.Cos(a), Y1: cy - (inner-6)*math.Sin(a),
+			X2: cx + (radius+6)*math.Cos(a), Y2: cy - (radius+6)*math.Sin(a),
+			Stroke: textColor, Width: 2,
+		})
+	}
+
+	doc.Add(
+		svgText{X: cx, Y: cy - 6, Text: value, Fill: textColor, Size: math.Max(12, radius*0.3), Anchor: "middle", Bold: true},
+		svgText{X: cx - (inner+radius)/2, Y: cy + 16, Text: opts.Numbers.Format(s.Min), Fill: textColor, Size: 11, Anchor: "middle"},
+		svgText{X: cx + (inner+radius)/2, Y: cy + 16, Text: opts.Numbers.Format(s.Max), Fill: textColor, Size: 11, Anchor: "middle"},
+	)
+	if in.Label != "" {
+		doc.Add(svgText{X: cx, Y: cy + 34, Text: in.Label, Fill: textColor, Size: 14, Anchor: "middle"})
+	}
+	return doc.String()
+}
+
+// barArea returns the horizontal extent of the bar in the SVG progress and
+// bullet charts, leaving room for the label on the left and the value on
+// the right.
+func (in gaugeInput) barArea(bounds dataviz.Bounds, value string) (x, w float64) {
+	x = svgPadding
+	if in.Label != "" {
+		x += float64(utf8.RuneCountInString(in.Label))*7.8 + 12
+	}
+	w = float64(bounds.Width) - svgPadding - x - float64(utf8.RuneCountInString(value))*7.8 - 12
+	return x, math.Max(10, w)
+}
+
+// progressSVG draws a rounded track with the value filled in the status
+// color and a target mark.
+func (in gaugeInput) progressSVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	s := in.scale()
+	value := opts.Numbers.Format(in.Value)
+	x, w := in.barArea(bounds, value)
+	const h = 14.0
+	y := float64(bounds.Height)/2 - h/2
+	doc.Add(svgRect{X: x, Y: y, W: w, H: h, Fill: textColor, Opacity: 0.15, Radius: h / 2})
+	if filled := s.Ratio(in.Value) * w; filled > 0 {
+		doc.Add(svgRect{X: x, Y: y, W: filled, H: h, Fill: in.valueColor(config), Radius: h / 2, Title: value})
+	}
+	if in.Target != nil {
+		tx := x + s.Ratio(*in.Target)*w
+		doc.Add(svgLine{X1: tx, Y1: y - 4, X2: tx, Y2: y + h + 4, Stroke: textColor, Width: 2})
+	}
+	if in.Label != "" {
+		doc.Add(svgText{X: svgPadding, Y: y + h - 2, Text: in.Label, Fill: textColor, Size: 13})
+	}
+	doc.Add(svgText{X: float64(bounds.Width) - svgPadding, Y: y + h - 2, Text: value, Fill: textColor, Size: 13, Anchor: "end", Bold: true})
+	return doc.String()
+}
+
+// bulletSVG draws the bands as shaded rectangles, the value as a narrower
+// bar over them, the target as a line across and a scale below.
+func (in gaugeInput) bulletSVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	s := in.scale()
+	value := opts.Numbers.Format(in.Value)
+	x, w := in.barArea(bounds, value)
+	h := math.Min(30, float64(bounds.Height)-2*svgPadding-20)
+	y := float64(bounds.Height)/2 - h/2 - 8
+
+	for _, band := range in.bands() {
+		doc.Add(svgRect{
+			X: x + s.Ratio(band.From)*w, Y: y,
+			W: (s.Ratio(band.To) - s.Ratio(band.From)) * w, H: h,
+			Fill: bandColor(band, config), Opacity: 0.35, Title: band.Status,
+		})
+	}
+	doc.Add(svgRect{X: x, Y: y + h/3, W: s.Ratio(in.Value) * w, H: h / 3, Fill: textColor, Title: value})
+	if in.Target != nil {
+		tx := x + s.Ratio(*in.Target)*w
+		doc.Add(svgLine{X1: tx, Y1: y + h/6, X2: tx, Y2: y + 5*h/6, Stroke: textColor, Width: 3})
+	}
+	for _, t := range s.Ticks(maxSVGTicks(w), opts.Numbers) {
+		tx := x + t.Ratio*w
+		doc.Add(
+			svgLine{X1: tx, Y1: y + h, X2: tx, Y2: y + h + 4, Stroke: textColor, Width: 1, Opacity: 0.4},
+			svgText{X: tx, Y: y + h + 16, Text: t.Label, Fill: textColor, Size: 10, Anchor: "middle"},
+		)
+	}
+	if in.Label != "" {
+		doc.Add(svgText{X: svgPadding, Y: y + h/2 + 4, Text: in.Label, Fill: textColor, Size: 13})
+	}
+	doc.Add(svgText{X: float64(bounds.Width) - svgPadding, Y: y + h/2 + 4, Text: value, Fill: textColor, Size: 13, Anchor: "end", Bold: true})
+	return doc.String()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGaugeBands(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []gaugeBand
+	}{
+		{"no thresholds", `{"value": 5}`, []gaugeBand{{0, 100, ""}}},
+		{"high is bad", `{"value": 5, "thresholds": {"warn": 70, "critical": 90}}`,
+			[]gaugeBand{{0, 70, "good"}, {70, 90, "warn"}, {90, 100, "critical"}}},
+		{"low is bad", `{"value": 5, "thresholds": {"warn": 50, "critical": 20}}`,
+			[]gaugeBand{{0, 20, "critical"}, {20, 50, "warn"}, {50, 100, "good"}}},
+		{"clamped to the scale", `{"value": 5, "min": 10, "max": 60, "thresholds": {"warn": 5, "critical": 80}}`,
+			[]gaugeBand{{10, 60, "warn"}}},
+		{"only critical", `{"value": 5, "max": 10, "thresholds": {"critical": 8}}`,
+			[]gaugeBand{{0, 8, "good"}, {8, 10, "critical"}}},
+	}
+	for _, tt := range tests {
+		var in gaugeInput
+		if err := json.Unmarshal([]byte(tt.json), &in); err != nil {
+			t.Fatal(err)
+		}
+		got := in.bands()
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: bands = %+v, want %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: bands = %+v, want %+v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestBandAt(t *testing.T) {
+	bands := []gaugeBand{{0, 70, "good"}, {70, 90, "warn"}, {90, 100, "critical"}}
+	for v, want := range map[float64]string{-5: "good", 69.9: "good", 70: "warn", 95: "critical", 150: "critical"} {
+		if got := bandAt(bands, v).Status; got != want {
+			t.Errorf("bandAt(%v) = %q, want %q", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitOptions configures the git subcommand.
+type gitOptions struct {
+	Repo     string
+	Author   string
+	Since    string
+	Until    string
+	NoMerges bool
+	By       string
+	Bucket   string
+	Top      int
+	Paths    []string
+}
+
+// gitBuckets are the -bucket sizes of the line graph.
+var gitBuckets = map[string]bool{"day": true, "week": true, "month": true}
+
+// gitCommit is one commit of the history with the files it changed.
+// Binary files are listed with no added or deleted lines.
+type gitCommit struct {
+	Date    time.Time
+	Author  string
+	Files   []string
+	Added   int
+	Deleted int
+}
+
+// gitLog reads the commit history of the repository by running git log,
+// which only reads the local object store. Lazy fetching of objects that
+// are missing from a partial clone is turned off, so it never goes to the
+// network.
+func gitLog(opts gitOptions) ([]gitCommit, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found: %w", err)
+	}
+	args := []string{"-C", opts.Repo, "-c", "core.quotepath=off", "log",
+		"--numstat", "--no-renames", "--no-ext-diff", "--no-textconv",
+		"--format=%x1e%aI%x09%aN"}
+	if opts.Author != "" {
+		args = append(args, "--author="+opts.Author)
+	}
+	if opts.Since != "" {
+		args = append(args, "--since="+opts.Since)
+	}
+	if opts.Until != "" {
+		args = append(args, "--until="+opts.Until)
+	}
+	if opts.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	args = append(args, "--")
+	args = append(args, opts.Paths...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_NO_LAZY_FETCH=1", "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	commits, parseErr := parseGitLog(out)
+	io.Copy(io.Discard, out)
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git log: %s", msg)
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return commits, parseErr
+}
+
+// parseGitLog reads git log output in the format gitLog asks for: a header
+// line per commit, starting with a record separator, followed by a numstat
+// line per changed file.
+func parseGitLog(r io.Reader) ([]gitCommit, error) {
+	var commits []gitCommit
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if header, ok := strings.CutPrefix(line, "\x1e"); ok {
+			date, author, _ := strings.Cut(header, "\t")
+			t, err := time.Parse(time.RFC3339, date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid commit date %q", date)
+			}
+			commits = append(commits, gitCommit{Date: t, Author: author})
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || len(commits) == 0 {
+			continue
+		}
+		c := &commits[len(commits)-1]
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		c.Added += added
+		c.Deleted += deleted
+		c.Files = append(c.Files, fields[2])
+	}
+	return commits, scanner.Err()
+}
+
+// gitDay is the calendar day of a commit in its author's time zone, so a
+// late-night commit counts on the day its author made it.
+func gitDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// gitBucket truncates a commit's day to the start of its week, beginning
+// on Monday, or of its month.
+func gitBucket(t time.Time, bucket string) time.Time {
+	day := gitDay(t)
+	switch bucket {
+	case "week":
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// gitNext returns the start of the bucket after t.
+func gitNext(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// topDir returns the top-level directory of a path, or "(root)" for files
+// at the top of the repository.
+func topDir(path string) string {
+	if dir, _, ok := strings.Cut(path, "/"); ok {
+		return dir
+	}
+	return "(root)"
+}
+
+// gitCounts counts commits per author or per top-level directory. A commit
+// that touches several directories counts once for each of them.
+func gitCounts(commits []gitCommit, by string) map[string]int {
+	counts := map[string]int{}
+	for _, c := range commits {
+		if by == "author" {
+			counts[c.Author]++
+			continue
+		}
+		seen := map[string]bool{}
+		for _, f := range c.Files {
+			if dir := topDir(f); !seen[dir] {
+				seen[dir] = true
+				counts[dir]++
+			}
+		}
+	}
+	return counts
+}
+
+// gitChartData returns the history as the JSON input of the chart type:
+// commits per day as a calendar, lines added and deleted per bucket over
+// time, the commit count with its trend as a stat card, or the commits per
+// author or directory as a table, drawn as bars, slices or rows.
+func gitChartData(commits []gitCommit, vizType string, opts gitOptions) ([]byte, error) {
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits match")
+	}
+	first, last := commits[0].Date, commits[0].Date
+	for _, c := range commits {
+		if gitDay(c.Date).Before(gitDay(first)) {
+			first = c.Date
+		}
+		if gitDay(c.Date).After(gitDay(last)) {
+			last = c.Date
+		}
+	}
+
+	switch vizType {
+	case "heatmap":
+		perDay := map[time.Time]float64{}
+		for _, c := range commits {
+			perDay[gitDay(c.Date)]++
+		}
+		var days []heatmapDay
+		for day := gitDay(first); !day.After(gitDay(last)); day = day.AddDate(0, 0, 1) {
+			days = append(days, heatmapDay{Date: day, Count: perDay[day]})
+		}
+		return json.Marshal(map[string]any{"type": "weeks", "days": days})
+
+	case "line-graph", "area", "sparkline", "stat-card":
+		added, deleted, count := map[time.Time]float64{}, map[time.Time]float64{}, map[time.Time]float64{}
+		for _, c := range commits {
+			b := gitBucket(c.Date, opts.Bucket)
+			added[b] += float64(c.Added)
+			deleted[b] += float64(c.Deleted)
+			count[b]++
+		}
+		var addedPoints, deletedPoints, changedPoints, countPoints []timePoint
+		end := gitBucket(last, opts.Bucket)
+		for b := gitBucket(first, opts.Bucket); !b.After(end); b = gitNext(b, opts.Bucket) {
+			addedPoints = append(addedPoints, timePoint{Date: b, Value: added[b]})
+			deletedPoints = append(deletedPoints, timePoint{Date: b, Value: deleted[b]})
+			changedPoints = append(changedPoints, timePoint{Date: b, Value: added[b] + deleted[b]})
+			countPoints = append(countPoints, timePoint{Date: b, Value: count[b]})
+		}
+		switch vizType {
+		case "stat-card":
+			return json.Marshal(map[string]any{"title": "Commits", "value": len(commits), "subtitle": "trend per " + opts.Bucket, "trendData": countPoints})
+		case "sparkline":
+			return json.Marshal(map[string]any{"points": changedPoints})
+		}
+		return json.Marshal(map[string]any{"series": []lineSeries{
+			{Label: "added", Points: addedPoints},
+			{Label: "deleted", Points: deletedPoints},
+		}})
+
+	case "bar-chart", "pie", "donut", "table":
+		counts := gitCounts(commits, opts.By)
+		keys := make([]string, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return cmp.Or(counts[keys[j]]-counts[keys[i]], strings.Compare(keys[i], keys[j])) < 0
+		})
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{opts.By, "commits"})
+		others := 0
+		for i, key := range keys {
+			if opts.Top > 0 && i >= opts.Top {
+				others += counts[key]
+				continue
+			}
+			w.Write([]string{key, strconv.Itoa(counts[key])})
+		}
+		if others > 0 {
+			w.Write([]string{fmt.Sprintf("%d others", len(keys)-opts.Top), strconv.Itoa(others)})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+	return nil, fmt.Errorf("git draws heatmap, line-graph, area, sparkline, stat-card, bar-chart, pie, donut and table, not %s", vizType)
+}
+
+// gitActivity runs the git subcommand, charting the commit history of a
+// local repository. Arguments after the options limit it to those paths.
+func gitActivity(args []string) int {
+	fs := flag.NewFlagSet("git", flag.ExitOnError)
+	var opts gitOptions
+	fs.StringVar(&opts.Repo, "repo", ".", "Repository directory")
+	fs.StringVar(&opts.Author, "author", "", "Only commits by authors matching this pattern")
+	fs.StringVar(&opts.Since, "since", "", "Only commits after this date")
+	fs.StringVar(&opts.Until, "until", "", "Only commits before this date")
+	fs.BoolVar(&opts.NoMerges, "no-merges", false, "Leave out merge commits")
+	fs.StringVar(&opts.By, "per", "author", "Count bars per author or dir")
+	fs.StringVar(&opts.Bucket, "bucket", "day", "Time bucket size")
+	fs.IntVar(&opts.Top, "top", 10, "Number of bars before the rest are combined")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	cfg, err := parseFlags(fs, args)
+	if err == nil && opts.By != "author" && opts.By != "dir" {
+		err = fmt.Errorf("invalid -per %q (want author or dir)", opts.By)
+	}
+	if err == nil && !gitBuckets[opts.Bucket] {
+		err = fmt.Errorf("invalid -bucket %q (want day, week or month)", opts.Bucket)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !flagSet(fs, "type") {
+		cfg.vizType = "heatmap"
+	}
+	cfg = terminalGraphics(cfg)
+	opts.Paths = fs.Args()
+
+	commits, err := gitLog(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		return 1
+	}
+	data, err := gitChartData(commits, cfg.vizType, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	output, err := render(cfg, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := writeOutput(cfg.output, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitLogOutput is git log output in the format gitLog asks for.
+const gitLogOutput = "\x1e2024-03-04T23:30:00-08:00\tAda Lovelace\n" +
+	"\n" +
+	"10\t2\tcmd/main.go\n" +
+	"3\t0\tREADME.md\n" +
+	"-\t-\tdocs/logo.png\n" +
+	"\x1e2024-03-01T09:00:00+01:00\tGrace Hopper\n" +
+	"\x1e2024-02-26T10:00:00Z\tAda Lovelace\n" +
+	"\n" +
+	"1\t1\tcmd/flags.go\n" +
+	"4\t0\tdocs/a b.md\n"
+
+func TestParseGitLog(t *testing.T) {
+	commits, err := parseGitLog(strings.NewReader(gitLogOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("%d commits, want 3", len(commits))
+	}
+	c := commits[0]
+	if c.Author != "Ada Lovelace" || c.Added != 13 || c.Deleted != 2 || len(c.Files) != 3 || c.Files[2] != "docs/logo.png" {
+		t.Errorf("first commit = %+v", c)
+	}
+	if len(commits[1].Files) != 0 {
+		t.Errorf("merge commit lists files %q", commits[1].Files)
+	}
+	if commits[2].Files[1] != "docs/a b.md" {
+		t.Errorf("file with a space = %q", commits[2].Files[1])
+	}
+
+	if _, err := parseGitLog(strings.NewReader("\x1eyesterday\tAda\n")); err == nil {
+		t.Error("invalid date accepted")
+	}
+}
+
+func TestGitBucket(t *testing.T) {
+	// Late on Monday evening in the author's zone, Tuesday in UTC.
+	monday := time.Date(2024, 3, 4, 23, 30, 0, 0, time.FixedZone("PST", -8*3600))
+	tests := []struct {
+		bucket string
+		t      time.Time
+		want   time.Time
+	}{
+		{"day", monday, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"week", monday, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"week", time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"month", monday, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := gitBucket(tt.t, tt.bucket); !got.Equal(tt.want) {
+			t.Errorf("gitBucket(%v, %s) = %v, want %v", tt.t, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestGitCounts(t *testing.T) {
+	commits, _ := parseGitLog(strings.NewReader(gitLogOutput))
+	byDir := gitCounts(commits, "dir")
+	want := map[string]int{"cmd": 2, "docs": 2, "(root)": 1}
+	if len(byDir) != len(want) {
+		t.Errorf("per directory = %v, want %v", byDir, want)
+	}
+	for dir, n := range want {
+		if byDir[dir] != n {
+			t.Errorf("%s: %d commits, want %d", dir, byDir[dir], n)
+		}
+	}
+	if byAuthor := gitCounts(commits, "author"); byAuthor["Ada Lovelace"] != 2 || byAuthor["Grace Hopper"] != 1 {
+		t.Errorf("per author = %v", byAuthor)
+	}
+}
+
+func TestGitChartData(t *testing.T) {
+	commits, _ := parseGitLog(strings.NewReader(gitLogOutput))
+
+	out, err := gitChartData(commits, "table", gitOptions{By: "author", Top: 1})
+	if err != nil || string(out) != "author,commits\nAda Lovelace,2\n1 others,1\n" {
+		t.Errorf("table = %q, %v", out, err)
+	}
+
+	out, err = gitChartData(commits, "heatmap", gitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var heat heatmapInput
+	if err := json.Unmarshal(out, &heat); err != nil {
+		t.Fatal(err)
+	}
+	if len(heat.Days) != 8 || heat.Days[0].Count != 1 || heat.Days[1].Count != 0 {
+		t.Errorf("heatmap days = %+v, want Feb 26 to Mar 4 with the gaps filled", heat.Days)
+	}
+
+	out, err = gitChartData(commits, "line-graph", gitOptions{Bucket: "week"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines lineGraphInput
+	if err := json.Unmarshal(out, &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines.Series) != 2 || len(lines.Series[0].Points) != 2 || lines.Series[0].Points[1].Value != 13 {
+		t.Errorf("weekly series = %+v", lines.Series)
+	}
+
+	if _, err := gitChartData(nil, "table", gitOptions{}); err == nil {
+		t.Error("empty history charted")
+	}
+}
@@ -25,6 +25,8 @@ require (
 	github.com/SCKelemen/design-system v0.1.0
 	github.com/SCKelemen/layout v1.1.0
 	github.com/charmbracelet/bubbletea v1.3.10
+	github.com/mattn/go-sqlite3 v1.14.33
+	golang.org/x/sys v0.39.0
 )
 
 require (
@@ -49,7 +51,6 @@ require (
 	github.com/muesli/termenv v0.16.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	golang.org/x/sys v0.39.0 // indirect
 	golang.org/x/term v0.38.0 // indirect
 	golang.org/x/text v0.32.0 // indirect
 )
@@ -0,0 +1,324 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// imageFormats are the -format values that draw the SVG chart as an image
+// inline in the terminal, by the graphics protocol of the same name.
+var imageFormats = map[string]bool{"sixel": true, "kitty": true, "iterm": true}
+
+// Images are drawn over -width by -height terminal cells. Where the
+// terminal does not report the size of its cells in pixels, they are
+// taken to be defaultCellWidth by defaultCellHeight.
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// kittyChunkSize is the largest piece of base64 data sent in one kitty
+// graphics command.
+const kittyChunkSize = 4096
+
+// graphicsQueryTimeout is how long the terminal has to answer the query
+// for the graphics protocols it supports.
+const graphicsQueryTimeout = 200 * time.Millisecond
+
+// graphicsQuery asks the terminal for a kitty graphics reply, its iTerm2
+// cell size and, last, its primary device attributes. Every terminal
+// answers the device attributes, so the replies it knows how to give
+// arrive before them.
+const graphicsQuery = "\x1b_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\" + "\x1b]1337;ReportCellSize\a" + "\x1b[c"
+
+// deviceAttributes matches the primary device attributes reply.
+var deviceAttributes = regexp.MustCompile(`\x1b\[\?([0-9;]*)c`)
+
+// graphicsSupport reports which graphics protocols a terminal's replies to
+// graphicsQuery show it to support. Sixel support is attribute 4 of the
+// device attributes.
+func graphicsSupport(reply string) map[string]bool {
+	support := map[string]bool{
+		"kitty": strings.Contains(reply, "\x1b_Gi=31;OK"),
+		"iterm": strings.Contains(reply, "\x1b]1337;ReportCellSize="),
+	}
+	if m := deviceAttributes.FindStringSubmatch(reply); m != nil {
+		for _, attr := range strings.Split(m[1], ";") {
+			if attr == "4" {
+				support["sixel"] = true
+			}
+		}
+	}
+	return support
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalGraphics falls back to terminal text when the rasterizer an
+// image format needs is not installed, or when the chart would be drawn on
+// a terminal that does not support the graphics protocol of the format, or
+// does not answer the query for it in time. Output to a file or a pipe is
+// encoded as asked.
+func terminalGraphics(cfg Config) Config {
+	if !imageFormats[cfg.format] {
+		return cfg
+	}
+	if _, err := exec.LookPath(cfg.rasterizer); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; drawing text instead\n", missingRasterizerError{cfg.rasterizer})
+		cfg.format = "terminal"
+		return cfg
+	}
+	if (cfg.output != "" && cfg.output != "-") || !isTerminal(os.Stdout) {
+		return cfg
+	}
+	reply, err := queryTerminal(graphicsQuery, deviceAttributes, graphicsQueryTimeout)
+	if err == nil && graphicsSupport(reply)[cfg.format] {
+		return cfg
+	}
+	fmt.Fprintf(os.Stderr, "Terminal does not show %s graphics, drawing text instead\n", cfg.format)
+	cfg.format = "terminal"
+	return cfg
+}
+
+// missingRasterizerError is returned when the SVG to PNG converter is not
+// installed.
+type missingRasterizerError struct {
+	rasterizer string
+}
+
+func (e missingRasterizerError) Error() string {
+	return fmt.Sprintf("PNG output needs %s installed", e.rasterizer)
+}
+
+// rasterizeSVG converts an SVG document to PNG with an external converter,
+// which reads SVG on stdin and writes PNG to stdout.
+func rasterizeSVG(ctx context.Context, rasterizer string, svg []byte) ([]byte, error) {
+	path, err := exec.LookPath(rasterizer)
+	if err != nil {
+		return nil, missingRasterizerError{rasterizer}
+	}
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(svg)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("converting to PNG: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// renderImage draws the chart with the SVG renderer over the pixels of
+// -width by -height terminal cells, converts it to PNG and encodes it for
+// the graphics protocol of the format. Without the converter it warns and
+// draws terminal text instead.
+func renderImage(cfg Config, data []byte, config dataviz.RenderConfig) (string, error) {
+	cellWidth, cellHeight, ok := cellPixels()
+	if !ok {
+		cellWidth, cellHeight = defaultCellWidth, defaultCellHeight
+	}
+	bounds := dataviz.Bounds{Width: cfg.width * cellWidth, Height: cfg.height * cellHeight}
+	svg, err := renderSVG(cfg.vizType, data, bounds, config, cfg.chart)
+	if err != nil {
+		return "", err
+	}
+	pngData, err := rasterizeSVG(context.Background(), cfg.rasterizer, []byte(svg.String()))
+	var missing missingRasterizerError
+	if errors.As(err, &missing) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; drawing text instead\n", err)
+		text, err := renderTerminal(cfg.vizType, data, dataviz.Bounds{Width: cfg.width, Height: cfg.height}, config, cfg.chart)
+		if err != nil {
+			return "", err
+		}
+		return text.String(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch cfg.format {
+	case "kitty":
+		return encodeKitty(pngData, cfg.width, cfg.height), nil
+	case "iterm":
+		return encodeITerm(pngData, cfg.width, cfg.height), nil
+	}
+	img, err := png.Decode(bytes.NewReader(pngData))
+	if err != nil {
+		return "", fmt.Errorf("reading PNG: %w", err)
+	}
+	background, _ := themeColors(config)
+	return encodeSixel(img, background), nil
+}
+
+// encodeKitty writes a PNG image as kitty graphics commands that show it
+// over cols by rows cells, in chunks of kittyChunkSize. The terminal is
+// asked not to reply, so nothing is left on its input.
+func encodeKitty(pngData []byte, cols, rows int) string {
+	encoded := base64.StdEncoding.EncodeToString(pngData)
+	var b strings.Builder
+	for i := 0; i == 0 || i < len(encoded); i += kittyChunkSize {
+		chunk := encoded[i:min(i+kittyChunkSize, len(encoded))]
+		more := 0
+		if i+kittyChunkSize < len(encoded) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,q=2,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// encodeITerm writes a PNG image as an iTerm2 inline image stretched over
+// cols by rows cells.
+func encodeITerm(pngData []byte, cols, rows int) string {
+	return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=0:%s\a\n",
+		len(pngData), cols, rows, base64.StdEncoding.EncodeToString(pngData))
+}
+
+// encodeSixel writes an image as sixels. The image is laid over the
+// background color, white when it is not set, and drawn in its own colors
+// when it has at most 256, as charts mostly do. Otherwise the 256 most
+// used colors stand in for the rest, without dithering, which would blur
+// the edges of lines and bars.
+func encodeSixel(img image.Image, background string) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	opaque := image.NewRGBA(image.Rect(0, 0, width, height))
+	var bg color.Color = color.White
+	if r, g, b, ok := parseHex(background); ok {
+		bg = color.RGBA{uint8(r), uint8(g), uint8(b), 0xff}
+	}
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, bounds.Min, draw.Over)
+	paletted := image.NewPaletted(opaque.Bounds(), sixelPalette(opaque))
+	index := map[color.RGBA]uint8{}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := opaque.RGBAAt(x, y)
+			i, ok := index[c]
+			if !ok {
+				i = uint8(paletted.Palette.Index(c))
+				index[c] = i
+			}
+			paletted.Pix[y*paletted.Stride+x] = i
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i, c := range paletted.Palette {
+		r, g, b2, _ := c.RGBA()
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, percent(r), percent(g), percent(b2))
+	}
+
+	// Each band of six rows is drawn once per color in it, returning to
+	// the start of the band in between.
+	row := make([]byte, width)
+	for top := 0; top < height; top += 6 {
+		first := true
+		for c := range paletted.Palette {
+			found := false
+			for x := 0; x < width; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && top+dy < height; dy++ {
+					if paletted.Pix[(top+dy)*paletted.Stride+x] == uint8(c) {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+				found = found || bits != 0
+			}
+			if !found {
+				continue
+			}
+			if !first {
+				b.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(&b, "#%d", c)
+			writeSixelRuns(&b, row)
+		}
+		b.WriteByte('-')
+	}
+	b.WriteString("\x1b\\\n")
+	return b.String()
+}
+
+// writeSixelRuns writes a row of sixels, with repeats of more than three
+// of the same sixel run-length encoded. Trailing empty sixels are left out.
+func writeSixelRuns(b *strings.Builder, row []byte) {
+	end := len(row)
+	for end > 0 && row[end-1] == '?' {
+		end--
+	}
+	for i := 0; i < end; {
+		j := i
+		for j < end && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(b, "!%d%c", n, row[i])
+		} else {
+			b.Write(row[i:j])
+		}
+		i = j
+	}
+}
+
+// sixelPalette returns the colors of an image, most used first, up to the
+// 256 color registers of a sixel image.
+func sixelPalette(img *image.RGBA) color.Palette {
+	counts := map[color.RGBA]int{}
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			counts[img.RGBAAt(x, y)]++
+		}
+	}
+	colors := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		a, b := colors[i], colors[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		return uint32(a.R)<<16|uint32(a.G)<<8|uint32(a.B) < uint32(b.R)<<16|uint32(b.G)<<8|uint32(b.B)
+	})
+	palette := make(color.Palette, 0, 256)
+	for _, c := range colors[:min(256, len(colors))] {
+		palette = append(palette, c)
+	}
+	return palette
+}
+
+// percent scales a 16-bit color channel to the 0 to 100 of sixel colors.
+func percent(v uint32) int {
+	return int((v*100 + 0x7fff) / 0xffff)
+}
@@ -0,0 +1,63 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"os"
+	"regexp"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// queryTerminal writes query to the controlling terminal and returns what
+// it replies until end matches or timeout passes. The terminal is put in
+// non-canonical mode without echo while it replies, so the reply is
+// neither shown nor left for the shell.
+func queryTerminal(query string, end *regexp.Regexp, timeout time.Duration) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fd := int(tty.Fd())
+	saved, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return "", err
+	}
+	raw := *saved
+	raw.Lflag &^= unix.ICANON | unix.ECHO
+	raw.Cc[unix.VMIN] = 0
+	raw.Cc[unix.VTIME] = 1 // reads give up after a tenth of a second
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return "", err
+	}
+	defer unix.IoctlSetTermios(fd, unix.TCSETS, saved)
+
+	if _, err := tty.WriteString(query); err != nil {
+		return "", err
+	}
+	var reply []byte
+	buf := make([]byte, 256)
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		n, err := unix.Read(fd, buf)
+		if err != nil && err != unix.EINTR {
+			return string(reply), err
+		}
+		reply = append(reply, buf[:max(n, 0)]...)
+		if end.Match(reply) {
+			break
+		}
+	}
+	return string(reply), nil
+}
+
+// cellPixels returns the size in pixels of a cell of the terminal on
+// stdout, when the terminal reports it.
+func cellPixels() (width, height int, ok bool) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 || ws.Xpixel == 0 || ws.Ypixel == 0 {
+		return 0, 0, false
+	}
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row), true
+}
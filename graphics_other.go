@@ -0,0 +1,21 @@
+//go:build !linux && !dashboard && !simpledashboard
+
+package main
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// queryTerminal is only implemented on Linux; elsewhere image formats fall
+// back to terminal text when drawn on a terminal.
+func queryTerminal(query string, end *regexp.Regexp, timeout time.Duration) (string, error) {
+	return "", errors.New("terminal queries are not supported on this platform")
+}
+
+// cellPixels is only implemented on Linux; elsewhere images are drawn for
+// cells of the default size.
+func cellPixels() (width, height int, ok bool) {
+	return 0, 0, false
+}
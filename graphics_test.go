@@ -0,0 +1,178 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with the golden file testdata/name, or with
+// -update rewrites the file.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s:\n got %q\nwant %q", path, got, want)
+	}
+}
+
+func TestEncodeKitty(t *testing.T) {
+	tests := []struct {
+		golden string
+		data   []byte
+	}{
+		{"kitty-one-chunk.golden", []byte("abc")},
+		{"kitty-empty.golden", nil},
+		// 3075 bytes are 4100 base64 characters, a full chunk and four more.
+		{"kitty-two-chunks.golden", []byte(strings.Repeat("abc", 1025))},
+	}
+	for _, tt := range tests {
+		checkGolden(t, tt.golden, encodeKitty(tt.data, 4, 2))
+	}
+}
+
+func TestEncodeITerm(t *testing.T) {
+	checkGolden(t, "iterm.golden", encodeITerm([]byte("abc"), 4, 2))
+}
+
+func TestEncodeSixel(t *testing.T) {
+	red := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for _, p := range []image.Point{{1, 0}, {0, 1}, {1, 1}} {
+		red.Set(p.X, p.Y, color.White)
+	}
+	red.Set(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	tests := []struct {
+		golden     string
+		img        image.Image
+		background string
+	}{
+		// White is the most used color, so it takes register 0.
+		{"sixel-two-colors.golden", red, ""},
+		{"sixel-background.golden", image.NewRGBA(image.Rect(0, 0, 1, 1)), "#000000"},
+		// Seven rows take a full band of six and one of a single row.
+		{"sixel-two-bands.golden", image.NewRGBA(image.Rect(0, 0, 1, 7)), "#000000"},
+	}
+	for _, tt := range tests {
+		checkGolden(t, tt.golden, encodeSixel(tt.img, tt.background))
+	}
+}
+
+func TestWriteSixelRuns(t *testing.T) {
+	tests := []struct{ row, want string }{
+		{"@@@@@??", "!5@"},
+		{"AAAB", "AAAB"},
+		{"~~~~B?C", "!4~B?C"},
+		{"???", ""},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		writeSixelRuns(&b, []byte(tt.row))
+		if got := b.String(); got != tt.want {
+			t.Errorf("writeSixelRuns(%q) = %q, want %q", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestSixelPalette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 1))
+	for x := 0; x < 300; x++ {
+		img.SetRGBA(x, 0, color.RGBA{uint8(x), uint8(x >> 8), 0, 0xff})
+	}
+	img.SetRGBA(299, 0, color.RGBA{0, 0, 0, 0xff})
+	palette := sixelPalette(img)
+	if len(palette) != 256 {
+		t.Fatalf("%d colors, want 256", len(palette))
+	}
+	if palette[0] != (color.RGBA{0, 0, 0, 0xff}) {
+		t.Errorf("first color = %v, want the most used, black", palette[0])
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for _, tt := range []struct {
+		v    uint32
+		want int
+	}{{0, 0}, {0xffff, 100}, {0x8080, 50}} {
+		if got := percent(tt.v); got != tt.want {
+			t.Errorf("percent(%#x) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestGraphicsSupport(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  []string
+	}{
+		{"\x1b[?62;4;22c", []string{"sixel"}},
+		{"\x1b_Gi=31;OK\x1b\\\x1b[?62;22c", []string{"kitty"}},
+		{"\x1b]1337;ReportCellSize=17.0;8.0\x1b\\\x1b[?1;2;4c", []string{"iterm", "sixel"}},
+		{"\x1b[?62;44c", nil},
+	}
+	for _, tt := range tests {
+		support := graphicsSupport(tt.reply)
+		for _, format := range []string{"kitty", "iterm", "sixel"} {
+			want := false
+			for _, w := range tt.want {
+				want = want || w == format
+			}
+			if support[format] != want {
+				t.Errorf("%q: %s support = %v, want %v", tt.reply, format, support[format], want)
+			}
+		}
+	}
+}
+
+func TestRenderImageWithoutRasterizer(t *testing.T) {
+	cfg, err := parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"-type", "gauge", "-format", "kitty", "-rasterizer", "no-such-rasterizer", "-width", "30", "-height", "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"value": 40, "max": 100}`)
+	got, err := render(cfg, data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	cfg.format = "terminal"
+	want, err := render(cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("without a rasterizer drew\n%q\nwant the terminal text\n%q", got, want)
+	}
+}
+
+func TestTerminalGraphicsWithoutRasterizer(t *testing.T) {
+	cfg, err := parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{
+		"-type", "gauge", "-format", "sixel", "-rasterizer", "no-such-rasterizer", "-output", "chart.six"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := terminalGraphics(cfg).format; got != "terminal" {
+		t.Errorf("format %q without a rasterizer, want terminal", got)
+	}
+	cfg.rasterizer = "cat"
+	if got := terminalGraphics(cfg).format; got != "sixel" {
+		t.Errorf("format %q with a rasterizer, writing to a file, want sixel", got)
+	}
+}
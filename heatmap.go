@@ -0,0 +1,579 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// heatmapInput is the JSON accepted by -type heatmap. Type selects the
+// layout: "weeks" (the default), "linear" or "day-hour". Buckets are the
+// lower bounds of explicit color levels; Quantiles instead splits the
+// non-zero counts into that many equally full levels. Scale "log" shades
+// the automatic levels logarithmically. Labels adds month or hour labels
+// above the cells and weekday labels beside them, and Legend a row mapping
+// colors to counts. Annotations mark the days they fall on or cover.
+type heatmapInput struct {
+	dataviz.HeatmapData
+	Days        []heatmapDay `json:"days"`
+	Buckets     []float64    `json:"buckets"`
+	Quantiles   int          `json:"quantiles"`
+	Scale       string       `json:"scale"`
+	Labels      bool         `json:"labels"`
+	Legend      bool         `json:"legend"`
+	Annotations []annotation `json:"annotations"`
+}
+
+// heatmapOptions are the heatmap settings from the command line. They take
+// precedence over the matching JSON fields.
+type heatmapOptions struct {
+	Layout    string
+	Buckets   []float64
+	Quantiles int
+	Scale     string
+	Legend    bool
+}
+
+// heatmapLayouts are the values accepted for the layout.
+var heatmapLayouts = map[string]bool{"weeks": true, "linear": true, "day-hour": true}
+
+// apply merges the command line settings into the input and checks the
+// result.
+func (in *heatmapInput) apply(opts heatmapOptions, axes bool, annotations []annotation) error {
+	in.Annotations = append(in.Annotations, annotations...)
+	if opts.Layout != "" {
+		in.Type = opts.Layout
+	}
+	if len(opts.Buckets) > 0 {
+		in.Buckets, in.Quantiles = opts.Buckets, 0
+	}
+	if opts.Quantiles > 0 {
+		in.Buckets, in.Quantiles = nil, opts.Quantiles
+	}
+	if opts.Scale != "" {
+		in.Scale = opts.Scale
+	}
+	in.Legend = in.Legend || opts.Legend
+	in.Labels = in.Labels || axes
+
+	if in.Type != "" && !heatmapLayouts[in.Type] {
+		return fmt.Errorf("unknown heatmap layout %q (want weeks, linear or day-hour)", in.Type)
+	}
+	if in.Scale != "" && in.Scale != "linear" && in.Scale != "log" {
+		return fmt.Errorf("unknown heatmap scale %q (want linear or log)", in.Scale)
+	}
+	if in.Quantiles < 0 {
+		return fmt.Errorf("heatmap quantiles must be positive, got %d", in.Quantiles)
+	}
+	for i := 1; i < len(in.Buckets); i++ {
+		if in.Buckets[i] <= in.Buckets[i-1] {
+			return fmt.Errorf("heatmap buckets must be increasing, got %v", in.Buckets)
+		}
+	}
+	return nil
+}
+
+// fitsDataviz reports whether the dataviz renderer can draw the input: it
+// knows only its own two layouts with relative shading and no labels.
+func (in heatmapInput) fitsDataviz() bool {
+	return in.Type != "day-hour" && len(in.Buckets) == 0 && in.Quantiles == 0 &&
+		in.Scale != "log" && !in.Labels && !in.Legend && len(in.Annotations) == 0
+}
+
+// toDataviz converts the input for the dataviz renderer. dataviz shades
+// cells by their share of the largest count, so negative counts are shifted
+// up to start at zero and fractional counts are rescaled to whole numbers,
+// keeping each cell's relative intensity.
+func (in heatmapInput) toDataviz() dataviz.HeatmapData {
+	data := in.HeatmapData
+
+	counts := make([]float64, len(in.Days))
+	lowest := 0.0
+	for i, day := range in.Days {
+		counts[i] = day.Count
+		lowest = min(lowest, day.Count)
+	}
+	for i := range counts {
+		counts[i] -= lowest
+	}
+
+	ints := scaledInts(counts)
+	data.Days = make([]dataviz.ContributionDay, len(in.Days))
+	for i, day := range in.Days {
+		data.Days[i] = dataviz.ContributionDay{Date: day.Date, Count: ints[i]}
+	}
+	return data
+}
+
+// heatmapCell is one cell of the grid. Cells outside the date range are
+// not present and are left blank. Date is the day the cell stands for, or
+// zero in the day-hour layout.
+type heatmapCell struct {
+	Date    time.Time
+	Value   float64
+	Present bool
+	Title   string
+}
+
+// heatmapGrid is a heatmap laid out in rows and columns, with an optional
+// label per row and labels for some columns.
+type heatmapGrid struct {
+	cells     [][]heatmapCell
+	rowLabels []string
+	colLabels map[int]string
+
+	// stretch lets cells widen to fill the width instead of keeping two
+	// columns per cell and dropping the oldest columns that do not fit.
+	stretch bool
+}
+
+// columns returns the number of columns in the grid.
+func (g heatmapGrid) columns() int {
+	if len(g.cells) == 0 {
+		return 0
+	}
+	return len(g.cells[0])
+}
+
+// dayOf truncates t to midnight, keeping its location.
+func dayOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// dateRange returns the first and last day to show: the explicit start and
+// end dates when given, otherwise the range of the data.
+func (in heatmapInput) dateRange() (start, end time.Time) {
+	for i, d := range in.Days {
+		if i == 0 || d.Date.Before(start) {
+			start = d.Date
+		}
+		if i == 0 || d.Date.After(end) {
+			end = d.Date
+		}
+	}
+	if !in.StartDate.IsZero() {
+		start = in.StartDate
+	}
+	if !in.EndDate.IsZero() {
+		end = in.EndDate
+	}
+	return dayOf(start), dayOf(end)
+}
+
+// dailyTotals sums the counts of each day.
+func (in heatmapInput) dailyTotals() map[time.Time]float64 {
+	totals := make(map[time.Time]float64, len(in.Days))
+	for _, d := range in.Days {
+		totals[dayOf(d.Date)] += d.Count
+	}
+	return totals
+}
+
+// grid lays out the input according to its type.
+func (in heatmapInput) grid(opts chartOptions) heatmapGrid {
+	switch in.Type {
+	case "day-hour":
+		return in.dayHourGrid(opts)
+	case "linear":
+		return in.linearGrid(opts)
+	default:
+		return in.weeksGrid(opts)
+	}
+}
+
+// weeksGrid lays out days in columns of weeks starting on Sunday, with
+// months labelled at the first week that starts in them.
+func (in heatmapInput) weeksGrid(opts chartOptions) heatmapGrid {
+	start, end := in.dateRange()
+	totals := in.dailyTotals()
+	first := start.AddDate(0, 0, -int(start.Weekday()))
+	weeks := int(end.Sub(first).Hours()/24)/7 + 1
+
+	g := heatmapGrid{
+		cells:     make([][]heatmapCell, 7),
+		rowLabels: []string{"", "Mon", "", "Wed", "", "Fri", ""},
+		colLabels: map[int]string{},
+	}
+	for row := range g.cells {
+		g.cells[row] = make([]heatmapCell, weeks)
+	}
+	for week := 0; week < weeks; week++ {
+		sunday := first.AddDate(0, 0, 7*week)
+		if week == 0 || sunday.Day() <= 7 {
+			g.colLabels[week] = sunday.AddDate(0, 0, 6).Format("Jan")
+		}
+		for row := 0; row < 7; row++ {
+			day := sunday.AddDate(0, 0, row)
+			if day.Before(start) || day.After(end) {
+				continue
+			}
+			g.cells[row][week] = heatmapCell{
+				Date:    day,
+				Value:   totals[day],
+				Present: true,
+				Title:   fmt.Sprintf("%s: %s", opts.Dates.Format(day, "Jan 2, 2006"), opts.Numbers.Format(totals[day])),
+			}
+		}
+	}
+	return g
+}
+
+// linearGrid lays out days in a single row, labelling the first day of
+// each month.
+func (in heatmapInput) linearGrid(opts chartOptions) heatmapGrid {
+	start, end := in.dateRange()
+	totals := in.dailyTotals()
+	days := int(end.Sub(start).Hours()/24) + 1
+
+	g := heatmapGrid{cells: [][]heatmapCell{make([]heatmapCell, days)}, colLabels: map[int]string{}}
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i)
+		if i == 0 || day.Day() == 1 {
+			g.colLabels[i] = day.Format("Jan")
+		}
+		g.cells[0][i] = heatmapCell{
+			Date:    day,
+			Value:   totals[day],
+			Present: true,
+			Title:   fmt.Sprintf("%s: %s", opts.Dates.Format(day, "Jan 2, 2006"), opts.Numbers.Format(totals[day])),
+		}
+	}
+	return g
+}
+
+// dayHourGrid sums the counts by weekday and hour into seven rows, Monday
+// first, of 24 hours each.
+func (in heatmapInput) dayHourGrid(opts chartOptions) heatmapGrid {
+	g := heatmapGrid{
+		cells:     make([][]heatmapCell, 7),
+		rowLabels: make([]string, 7),
+		colLabels: map[int]string{},
+		stretch:   true,
+	}
+	for row := range g.cells {
+		g.cells[row] = make([]heatmapCell, 24)
+		g.rowLabels[row] = time.Weekday((row + 1) % 7).String()[:3]
+	}
+	for _, d := range in.Days {
+		g.cells[(int(d.Date.Weekday())+6)%7][d.Date.Hour()].Value += d.Count
+	}
+	for row := range g.cells {
+		for hour := range g.cells[row] {
+			cell := &g.cells[row][hour]
+			cell.Present = true
+			cell.Title = fmt.Sprintf("%s %02d:00: %s", g.rowLabels[row], hour, opts.Numbers.Format(cell.Value))
+		}
+	}
+	for hour := 0; hour < 24; hour += 3 {
+		g.colLabels[hour] = fmt.Sprintf("%02d", hour)
+	}
+	return g
+}
+
+// heatmapScale assigns counts to color levels. Level 0 is drawn as an
+// empty cell and levels 1 to levels as increasingly strong shades.
+type heatmapScale struct {
+	levels int
+
+	// bounds are the lower bounds of levels 1 to levels for bucketed
+	// scales; without them the levels split lo..hi evenly.
+	bounds []float64
+	lo, hi float64
+	log    bool
+}
+
+// automaticLevels is the number of shaded levels without explicit buckets.
+const automaticLevels = 4
+
+// newHeatmapScale builds the scale for the cell values.
+func (in heatmapInput) newHeatmapScale(values []float64) heatmapScale {
+	if len(in.Buckets) > 0 {
+		return heatmapScale{levels: len(in.Buckets), bounds: in.Buckets}
+	}
+	if in.Quantiles > 0 {
+		var nonzero []float64
+		for _, v := range values {
+			if v != 0 {
+				nonzero = append(nonzero, v)
+			}
+		}
+		if len(nonzero) > 0 {
+			sort.Float64s(nonzero)
+			var bounds []float64
+			for i := 0; i < in.Quantiles; i++ {
+				b := nonzero[i*len(nonzero)/in.Quantiles]
+				if len(bounds) == 0 || b > bounds[len(bounds)-1] {
+					bounds = append(bounds, b)
+				}
+			}
+			return heatmapScale{levels: len(bounds), bounds: bounds}
+		}
+	}
+
+	s := heatmapScale{levels: automaticLevels, log: in.Scale == "log"}
+	for i, v := range values {
+		if i == 0 || v > s.hi {
+			s.hi = v
+		}
+		s.lo = min(s.lo, v)
+	}
+	return s
+}
+
+// ratio maps v in lo..hi onto 0..1, logarithmically for a log scale.
+func (s heatmapScale) ratio(v float64) float64 {
+	if s.hi <= s.lo {
+		return 0
+	}
+	if s.log {
+		return math.Log1p(v-s.lo) / math.Log1p(s.hi-s.lo)
+	}
+	return (v - s.lo) / (s.hi - s.lo)
+}
+
+// value is the inverse of ratio.
+func (s heatmapScale) value(r float64) float64 {
+	if s.log {
+		return s.lo + math.Expm1(r*math.Log1p(s.hi-s.lo))
+	}
+	return s.lo + r*(s.hi-s.lo)
+}
+
+// level returns the color level of v.
+func (s heatmapScale) level(v float64) int {
+	if s.bounds != nil {
+		return sort.Search(len(s.bounds), func(i int) bool { return s.bounds[i] > v })
+	}
+	if v <= s.lo {
+		return 0
+	}
+	return max(1, min(s.levels, int(math.Ceil(s.ratio(v)*float64(s.levels)))))
+}
+
+// color returns the shade of a level.
+func (s heatmapScale) color(level int, config dataviz.RenderConfig) string {
+	if level == 0 {
+		background, text := themeColors(config)
+		if background == "" {
+			background = "#FFFFFF"
+		}
+		return mixColors(background, text, 0.1)
+	}
+	return heatColor(float64(level)/float64(s.levels), config)
+}
+
+// legend describes the counts each level stands for.
+func (s heatmapScale) legend(config dataviz.RenderConfig, nf numberFormat) []legendEntry {
+	entries := make([]legendEntry, s.levels+1)
+	for level := range entries {
+		var label string
+		switch {
+		case s.bounds == nil && level == 0:
+			label = nf.Format(s.lo)
+		case s.bounds == nil:
+			step := (s.hi - s.lo) / float64(s.levels)
+			from := s.value(float64(level-1) / float64(s.levels))
+			to := s.value(float64(level) / float64(s.levels))
+			label = nf.FormatTick(from, step) + "–" + nf.FormatTick(to, step)
+		case level == 0:
+			label = "<" + nf.Format(s.bounds[0])
+		case level == s.levels:
+			label = "≥" + nf.Format(s.bounds[level-1])
+		default:
+			label = nf.Format(s.bounds[level-1]) + "–" + nf.Format(s.bounds[level])
+		}
+		entries[level] = legendEntry{Label: label, Color: s.color(level, config)}
+	}
+	return entries
+}
+
+// cellValues returns the values of the present cells.
+func (g heatmapGrid) cellValues() []float64 {
+	var values []float64
+	for _, row := range g.cells {
+		for _, c := range row {
+			if c.Present {
+				values = append(values, c.Value)
+			}
+		}
+	}
+	return values
+}
+
+// Terminal implements chart. Cells are two columns wide, or up to three
+// for the day-hour layout. Weeks and days that do not fit are dropped from
+// the start so the most recent ones stay visible.
+func (in heatmapInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	g := in.grid(opts)
+	cols := g.columns()
+	if cols == 0 {
+		return ""
+	}
+	scale := in.newHeatmapScale(g.cellValues())
+
+	gutter := 0
+	if in.Labels && g.rowLabels != nil {
+		gutter = 4
+	}
+	avail := max(1, bounds.Width-gutter)
+	cellWidth := 2
+	if g.stretch {
+		cellWidth = max(1, min(3, avail/cols))
+	}
+	from := max(0, cols-avail/cellWidth)
+	if g.stretch {
+		from = 0
+		cols = min(cols, avail/cellWidth)
+	}
+
+	glyph := "█"
+	if cellWidth > 1 {
+		glyph = strings.Repeat("█", cellWidth-1) + " "
+	}
+
+	var b strings.Builder
+	if in.Labels {
+		line := []rune(strings.Repeat(" ", gutter+(cols-from)*cellWidth))
+		next := 0
+		for col := from; col < cols; col++ {
+			label, ok := g.colLabels[col]
+			at := gutter + (col-from)*cellWidth
+			if !ok || at < next || at+len(label) > len(line) {
+				continue
+			}
+			copy(line[at:], []rune(label))
+			next = at + len(label) + 1
+		}
+		b.WriteString(strings.TrimRight(string(line), " ") + "\n")
+	}
+	for row, cells := range g.cells {
+		if gutter > 0 {
+			b.WriteString(fmt.Sprintf("%-*s", gutter, g.rowLabels[row]))
+		}
+		for _, c := range cells[from:cols] {
+			if !c.Present {
+				b.WriteString(strings.Repeat(" ", cellWidth))
+				continue
+			}
+			color := scale.color(scale.level(c.Value), config)
+			a, ok := in.annotationOn(c)
+			switch {
+			case !ok:
+				b.WriteString(colorize(glyph, color))
+			case a.kind() == "range":
+				// The range shows in the gap after the cell, so it is
+				// invisible at one column per cell.
+				b.WriteString(ansiColor(color) + ansiBackground(a.color(config)) + glyph + ansiReset)
+			default:
+				_, size := utf8.DecodeRuneInString(glyph)
+				b.WriteString(ansiColor(a.color(config)) + ansiBackground(color) + "◆" + ansiReset)
+				if rest := glyph[size:]; rest != "" {
+					b.WriteString(colorize(rest, color))
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+	for _, line := range terminalLegend(in.legend(scale, config, opts), bounds.Width) {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// annotationOn returns the annotation marking a cell, if any. Cells of the
+// day-hour layout stand for many days and are never marked.
+func (in heatmapInput) annotationOn(c heatmapCell) (annotation, bool) {
+	if c.Date.IsZero() {
+		return annotation{}, false
+	}
+	return annotationOn(c.Date, in.Annotations)
+}
+
+// legend returns the color legend, when requested, followed by the
+// labelled annotations.
+func (in heatmapInput) legend(scale heatmapScale, config dataviz.RenderConfig, opts chartOptions) []legendEntry {
+	var entries []legendEntry
+	if in.Legend {
+		entries = scale.legend(config, opts.Numbers)
+	}
+	return append(entries, annotationLegend(in.Annotations, config)...)
+}
+
+// SVG implements chart. Cells are square and as large as fit, up to 20
+// pixels, with the legend below them on the right.
+func (in heatmapInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	g := in.grid(opts)
+	cols, rows := g.columns(), len(g.cells)
+	if cols == 0 {
+		return doc.String()
+	}
+	scale := in.newHeatmapScale(g.cellValues())
+
+	left, top, bottom := svgPadding, svgPadding, svgPadding
+	if in.Labels {
+		top += 14
+		if g.rowLabels != nil {
+			left += 28
+		}
+	}
+	legend := in.legend(scale, config, opts)
+	if len(legend) > 0 {
+		bottom += 22
+	}
+	size := math.Min((float64(bounds.Width)-left-svgPadding)/float64(cols), (float64(bounds.Height)-top-bottom)/float64(rows))
+	size = math.Max(2, math.Min(20, size))
+	gap := math.Max(1, size/8)
+
+	for row, cells := range g.cells {
+		for col, c := range cells {
+			if !c.Present {
+				continue
+			}
+			rect := svgRect{
+				X: left + float64(col)*size, Y: top + float64(row)*size,
+				W: size - gap, H: size - gap, Radius: gap,
+				Fill: scale.color(scale.level(c.Value), config), Title: c.Title,
+			}
+			if a, ok := in.annotationOn(c); ok {
+				rect.Stroke, rect.StrokeWidth = a.color(config), 2
+				if a.kind() == "range" {
+					rect.StrokeWidth = 1
+				}
+				if a.Label != "" {
+					rect.Title += " · " + a.Label
+				}
+			}
+			doc.Add(rect)
+		}
+	}
+
+	if in.Labels {
+		next := 0.0
+		for col := 0; col < cols; col++ {
+			label, ok := g.colLabels[col]
+			x := left + float64(col)*size
+			if !ok || x < next {
+				continue
+			}
+			doc.Add(svgText{X: x, Y: top - 4, Text: label, Fill: textColor, Size: 10})
+			next = x + float64(len(label))*6 + 6
+		}
+		for row, label := range g.rowLabels {
+			if label != "" {
+				doc.Add(svgText{X: left - 4, Y: top + float64(row)*size + size*0.7, Text: label, Fill: textColor, Size: 10, Anchor: "end"})
+			}
+		}
+	}
+	if len(legend) > 0 {
+		svgLegend(doc, legend, left+float64(cols)*size, top+float64(rows)*size+18, textColor)
+	}
+	return doc.String()
+}
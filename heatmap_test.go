@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestHeatmapApply(t *testing.T) {
+	in := heatmapInput{Buckets: []float64{1, 5}, Scale: "linear"}
+	if err := in.apply(heatmapOptions{Quantiles: 3, Scale: "log", Layout: "day-hour"}, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if in.Buckets != nil || in.Quantiles != 3 || in.Scale != "log" || in.Type != "day-hour" || !in.Labels {
+		t.Errorf("command line settings not applied: %+v", in)
+	}
+	if in.fitsDataviz() {
+		t.Error("day-hour heatmap fits the dataviz renderer")
+	}
+
+	for _, bad := range []heatmapInput{
+		{Buckets: []float64{5, 1}},
+		{Scale: "sqrt"},
+		{Quantiles: -1},
+	} {
+		if err := bad.apply(heatmapOptions{}, false, nil); err == nil {
+			t.Errorf("apply accepted %+v", bad)
+		}
+	}
+	if err := (&heatmapInput{}).apply(heatmapOptions{Layout: "spiral"}, false, nil); err == nil {
+		t.Error("apply accepted an unknown layout")
+	}
+}
+
+func TestHeatmapScaleLevels(t *testing.T) {
+	values := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8}
+	tests := []struct {
+		name string
+		in   heatmapInput
+		want map[float64]int
+	}{
+		{"linear", heatmapInput{}, map[float64]int{0: 0, 1: 1, 2: 1, 3: 2, 8: 4}},
+		{"buckets", heatmapInput{Buckets: []float64{1, 5}}, map[float64]int{0: 0, 1: 1, 4: 1, 5: 2, 100: 2}},
+		{"quantiles", heatmapInput{Quantiles: 2}, map[float64]int{0: 0, 1: 1, 4: 1, 5: 2, 8: 2}},
+		{"log", heatmapInput{Scale: "log"}, map[float64]int{0: 0, 1: 2, 2: 2, 3: 3, 8: 4}},
+	}
+	for _, tt := range tests {
+		scale := tt.in.newHeatmapScale(values)
+		for v, want := range tt.want {
+			if got := scale.level(v); got != want {
+				t.Errorf("%s: level(%v) = %d, want %d", tt.name, v, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// histogramInput is the JSON accepted by -type histogram. The values to
+// bin are given directly, or taken from dated points. Bins defaults to
+// Sturges' rule for the number of values.
+type histogramInput struct {
+	Label  string      `json:"label"`
+	Color  string      `json:"color"`
+	Values []float64   `json:"values"`
+	Points []timePoint `json:"points"`
+	Bins   int         `json:"bins"`
+}
+
+// histogramBins is the binned distribution. Bin i covers
+// [Start+i*Step, Start+(i+1)*Step); the last bin also includes its end.
+type histogramBins struct {
+	Start  float64
+	Step   float64
+	Counts []int
+}
+
+// edge returns the lower edge of bin i.
+func (h histogramBins) edge(i int) float64 {
+	return h.Start + float64(i)*h.Step
+}
+
+// maxCount returns the largest bin count.
+func (h histogramBins) maxCount() int {
+	largest := 0
+	for _, c := range h.Counts {
+		largest = max(largest, c)
+	}
+	return largest
+}
+
+// bin sorts the values into bins with round edges. ok is false when there
+// are no values.
+func (in histogramInput) bin() (histogramBins, bool) {
+	values := append(append([]float64(nil), in.Values...), pointValues(in.Points)...)
+	if len(values) == 0 {
+		return histogramBins{}, false
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	bins := in.Bins
+	if bins <= 0 {
+		bins = int(math.Ceil(math.Log2(float64(len(values))))) + 1
+	}
+
+	if hi == lo {
+		lo, hi = lo-0.5, hi+0.5
+	}
+	start, end, step := niceRange(lo, hi, bins+1)
+	n := max(1, int(math.Round((end-start)/step)))
+	h := histogramBins{Start: start, Step: step, Counts: make([]int, n)}
+	for _, v := range values {
+		// The largest value can fall exactly on the last edge; keep it in
+		// the last bin rather than adding an empty one.
+		i := min(n-1, int((v-start)/step))
+		h.Counts[i]++
+	}
+	return h, true
+}
+
+// color returns the fill color of the bars.
+func (in histogramInput) color(config dataviz.RenderConfig) string {
+	if in.Color != "" {
+		return in.Color
+	}
+	return config.Color
+}
+
+// edgeTicks labels the bin edges along the x-axis.
+func (h histogramBins) edgeTicks(maxTicks int, opts chartOptions) []axisTick {
+	n := len(h.Counts)
+	ticks := make([]axisTick, n+1)
+	for i := range ticks {
+		ticks[i] = axisTick{Ratio: float64(i) / float64(n), Label: opts.Numbers.FormatTick(h.edge(i), h.Step)}
+	}
+	return thinTicks(ticks, maxTicks)
+}
+
+// Terminal implements chart. Bins are drawn as vertical columns shaded in
+// eighths, with a one column gap between bins when there is room.
+func (in histogramInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	h, ok := in.bin()
+	if !ok {
+		return ""
+	}
+	color := in.color(config)
+	legend := terminalLegend(seriesLegend([]lineSeries{{Label: in.Label, Color: color}}), bounds.Width)
+	frame := newPlotFrame(bounds.Width, bounds.Height-len(legend), opts.Axes)
+	scale := newValueScale(0, float64(h.maxCount()), opts, maxYTicks(frame.rows))
+	frame.setYTicks(scale.Ticks(maxYTicks(frame.rows), opts.Numbers))
+	frame.xTicks = h.edgeTicks(maxXTicks(frame.cols), opts)
+
+	n := len(h.Counts)
+	gap := frame.cols/n >= 3
+	eighths := float64(frame.rows * 8)
+	columns := make([][]band, frame.cols)
+	for col := range columns {
+		i := min(n-1, col*n/frame.cols)
+		if gap && (col+1)*n/frame.cols != i && col != frame.cols-1 {
+			continue
+		}
+		columns[col] = []band{{From: 0, To: scale.Ratio(float64(h.Counts[i])) * eighths, Color: color}}
+	}
+
+	var b strings.Builder
+	for _, line := range frame.decorate(renderColumns(columns, frame.rows)) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	for _, line := range legend {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SVG implements chart.
+func (in histogramInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	h, ok := in.bin()
+	if !ok {
+		return doc.String()
+	}
+	color := in.color(config)
+	legend := seriesLegend([]lineSeries{{Label: in.Label, Color: color}})
+	area := newSVGPlotArea(bounds, len(legend) > 0, opts.Axes)
+	scale := newValueScale(0, float64(h.maxCount()), opts, maxSVGTicks(area.H))
+	area.drawAxes(doc, scale.Ticks(maxSVGTicks(area.H), opts.Numbers), h.edgeTicks(maxSVGTicks(area.W), opts), opts, textColor)
+	if len(legend) > 0 {
+		svgLegend(doc, legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+	}
+
+	n := len(h.Counts)
+	width := area.W / float64(n)
+	for i, count := range h.Counts {
+		height := scale.Ratio(float64(count)) * area.H
+		doc.Add(svgRect{
+			X:     area.X + float64(i)*width + 0.5,
+			Y:     area.Y + area.H - height,
+			W:     math.Max(0, width-1),
+			H:     height,
+			Fill:  color,
+			Title: fmt.Sprintf("%s – %s: %d", opts.Numbers.Format(h.edge(i)), opts.Numbers.Format(h.edge(i+1)), count),
+		})
+	}
+	return doc.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestHistogramBin(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    histogramInput
+		start float64
+		step  float64
+		want  []int
+	}{
+		{"round edges", histogramInput{Values: []float64{1, 2, 3, 4, 9, 10}, Bins: 5}, 0, 2, []int{1, 2, 1, 0, 2}},
+		{"sturges", histogramInput{Values: []float64{0, 5, 10, 15}}, 0, 5, []int{1, 1, 2}},
+		{"single value", histogramInput{Values: []float64{7, 7}}, 6.5, 0.5, []int{0, 2}},
+		{"negative", histogramInput{Values: []float64{-3, -1, 1}, Bins: 2}, -4, 2, []int{1, 1, 1}},
+	}
+	for _, tt := range tests {
+		h, ok := tt.in.bin()
+		if !ok {
+			t.Errorf("%s: no bins", tt.name)
+			continue
+		}
+		if h.Start != tt.start || h.Step != tt.step || !equalInts(h.Counts, tt.want) {
+			t.Errorf("%s: bins from %v by %v = %v, want from %v by %v = %v",
+				tt.name, h.Start, h.Step, h.Counts, tt.start, tt.step, tt.want)
+		}
+	}
+	if _, ok := (histogramInput{}).bin(); ok {
+		t.Error("binned no values")
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
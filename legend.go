@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// legendEntry is one labelled color swatch in a chart legend.
+type legendEntry struct {
+	Label string
+	Color string
+}
+
+// terminalLegend lays out legend entries as "● label" items, wrapping onto
+// as many lines as needed to stay within width columns.
+func terminalLegend(entries []legendEntry, width int) []string {
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	for _, e := range entries {
+		itemWidth := 2 + utf8.RuneCountInString(e.Label)
+		if lineWidth > 0 && lineWidth+2+itemWidth > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+		}
+		if lineWidth > 0 {
+			line.WriteString("  ")
+			lineWidth += 2
+		}
+		line.WriteString(colorize("●", e.Color))
+		line.WriteString(" ")
+		line.WriteString(e.Label)
+		lineWidth += itemWidth
+	}
+	if lineWidth > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// svgLegend adds a horizontal legend whose right edge is at x, with the
+// text baseline at y.
+func svgLegend(doc *svgDocument, entries []legendEntry, x, y float64, textColor string) {
+	const (
+		fontSize = 11.0
+		swatch   = 10.0
+		gap      = 16.0
+	)
+	// Approximate the rendered width so the legend can be right aligned.
+	total := 0.0
+	for i, e := range entries {
+		if i > 0 {
+			total += gap
+		}
+		total += swatch + 4 + float64(utf8.RuneCountInString(e.Label))*fontSize*0.6
+	}
+
+	cx := x - total
+	for _, e := range entries {
+		doc.Add(
+			svgRect{X: cx, Y: y - swatch + 1, W: swatch, H: swatch, Fill: e.Color, Radius: 2},
+			svgText{X: cx + swatch + 4, Y: y, Text: e.Label, Fill: textColor, Size: fontSize},
+		)
+		cx += swatch + 4 + float64(utf8.RuneCountInString(e.Label))*fontSize*0.6 + gap
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// lineSeries is one named series of a multi-series line graph.
+type lineSeries struct {
+	Label  string      `json:"label"`
+	Color  string      `json:"color"`
+	Points []timePoint `json:"points"`
+}
+
+// lineGraphInput is the JSON accepted by -type line-graph. A single series
+// can still be given with the top-level points field; series takes
+// precedence when present.
+type lineGraphInput struct {
+	dataviz.LineGraphData
+	Points      []timePoint  `json:"points"`
+	Series      []lineSeries `json:"series"`
+	Annotations []annotation `json:"annotations"`
+}
+
+// fitsDataviz reports whether the input is a single series of whole
+// numbers that the dataviz line graph can draw without losing precision.
+// The dataviz line graph scales from zero up, so negative values are
+// drawn by viz-cli.
+func (in lineGraphInput) fitsDataviz() bool {
+	values := pointValues(in.Points)
+	return len(in.Series) == 0 && len(in.Annotations) == 0 && integral(values) && nonNegative(values)
+}
+
+// toDataviz converts a single-series input for the dataviz renderer.
+func (in lineGraphInput) toDataviz() dataviz.LineGraphData {
+	data := in.LineGraphData
+	data.Points = toTimeSeries(in.Points)
+	return data
+}
+
+// resolvedSeries returns the series with points sorted by date and any
+// missing colors filled in from the theme palette.
+func (in lineGraphInput) resolvedSeries(theme string) []lineSeries {
+	series := make([]lineSeries, len(in.Series))
+	for i, s := range in.Series {
+		s.Points = sortedPoints(s.Points)
+		if s.Color == "" {
+			s.Color = paletteColor(theme, i)
+		}
+		series[i] = s
+	}
+	return series
+}
+
+// series returns the series to draw: the input series, or the top-level
+// points as a single series.
+func (in lineGraphInput) series(config dataviz.RenderConfig) []lineSeries {
+	if len(in.Series) > 0 {
+		return in.resolvedSeries(config.Theme)
+	}
+	return in.singleSeries(config.Color)
+}
+
+// Terminal implements chart.
+func (in lineGraphInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	return renderLineSeriesTerminal(in.series(config), in.Annotations, bounds, config, opts)
+}
+
+// SVG implements chart.
+func (in lineGraphInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	return renderLineSeriesSVG(in.series(config), in.Annotations, bounds, config, opts)
+}
+
+// seriesExtent is the combined date and value range of a set of series.
+type seriesExtent struct {
+	start, end time.Time
+	min, max   float64
+}
+
+func extentOf(series []lineSeries) (seriesExtent, bool) {
+	var ext seriesExtent
+	found := false
+	for _, s := range series {
+		for _, p := range s.Points {
+			v := p.Value
+			if !found {
+				ext = seriesExtent{start: p.Date, end: p.Date, min: v, max: v}
+				found = true
+				continue
+			}
+			if p.Date.Before(ext.start) {
+				ext.start = p.Date
+			}
+			if p.Date.After(ext.end) {
+				ext.end = p.Date
+			}
+			ext.min = math.Min(ext.min, v)
+			ext.max = math.Max(ext.max, v)
+		}
+	}
+	return ext, found
+}
+
+// xRatio positions a date within the extent, from 0 (start) to 1 (end).
+func (e seriesExtent) xRatio(t time.Time) float64 {
+	span := e.end.Sub(e.start)
+	if span <= 0 {
+		return 0.5
+	}
+	return float64(t.Sub(e.start)) / float64(span)
+}
+
+// seriesLegend returns legend entries for the series, or nil when a single
+// unlabelled series would make a legend redundant.
+func seriesLegend(series []lineSeries) []legendEntry {
+	if len(series) == 1 && series[0].Label == "" {
+		return nil
+	}
+	entries := make([]legendEntry, len(series))
+	for i, s := range series {
+		label := s.Label
+		if label == "" {
+			label = fmt.Sprintf("Series %d", i+1)
+		}
+		entries[i] = legendEntry{Label: label, Color: s.Color}
+	}
+	return entries
+}
+
+// renderLineSeriesTerminal draws every series as a braille line in its own
+// color, with optional axes and gridlines, annotations and a legend below
+// the plot.
+func renderLineSeriesTerminal(series []lineSeries, annotations []annotation, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	ext, ok := extentOf(series)
+	if !ok {
+		return ""
+	}
+	ext = ext.withAnnotations(annotations)
+
+	legend := terminalLegend(seriesLegend(series), bounds.Width)
+	frame := newPlotFrame(bounds.Width, bounds.Height-len(legend), opts.Axes)
+	scale := newValueScale(ext.min, ext.max, opts, maxYTicks(frame.rows))
+	frame.setYTicks(scale.Ticks(maxYTicks(frame.rows), opts.Numbers))
+	frame.xTicks = dateTicks(ext.start, ext.end, maxXTicks(frame.cols), opts.Dates)
+
+	canvas := newColorCanvas(frame.cols, frame.rows)
+	shadeRangesTerminal(canvas, ext, annotations, config)
+	if opts.Grid {
+		frame.drawGrid(canvas)
+	}
+	if scale.Min < 0 && scale.Max > 0 {
+		zero := frame.row(scale.Ratio(0))
+		for col := 0; col < frame.cols; col++ {
+			canvas.Guide(col, zero, '─')
+		}
+	}
+	maxX := float64(canvas.PixelWidth() - 1)
+	maxY := float64(canvas.PixelHeight() - 1)
+
+	for _, s := range series {
+		prevX, prevY := -1, -1
+		for _, p := range s.Points {
+			x := int(math.Round(ext.xRatio(p.Date) * maxX))
+			y := int(math.Round((1 - scale.Ratio(p.Value)) * maxY))
+			if prevX < 0 {
+				canvas.Set(x, y, s.Color)
+			} else {
+				canvas.Line(prevX, prevY, x, y, s.Color)
+			}
+			prevX, prevY = x, y
+		}
+	}
+	annotateTerminal(canvas, ext, scale, annotations, config)
+
+	var b strings.Builder
+	for _, line := range frame.decorate(canvas.Lines()) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	for _, line := range legend {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderLineSeriesSVG draws every series as a polyline with optional axes
+// and gridlines, annotations, and a legend in the top right corner.
+func renderLineSeriesSVG(series []lineSeries, annotations []annotation, bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	ext, ok := extentOf(series)
+	if !ok {
+		return doc.String()
+	}
+	ext = ext.withAnnotations(annotations)
+
+	legend := seriesLegend(series)
+	area := newSVGPlotArea(bounds, len(legend) > 0, opts.Axes)
+	scale := newValueScale(ext.min, ext.max, opts, maxSVGTicks(area.H))
+	xTicks := dateTicks(ext.start, ext.end, maxSVGTicks(area.W), opts.Dates)
+	shadeRangesSVG(doc, area, ext, annotations, config)
+	area.drawAxes(doc, scale.Ticks(maxSVGTicks(area.H), opts.Numbers), xTicks, opts, textColor)
+	if len(legend) > 0 {
+		svgLegend(doc, legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+	}
+	if scale.Min < 0 && scale.Max > 0 {
+		zero := area.Y + (1-scale.Ratio(0))*area.H
+		doc.Add(svgLine{X1: area.X, Y1: zero, X2: area.X + area.W, Y2: zero, Stroke: textColor, Width: 1, Opacity: 0.5})
+	}
+
+	for i, s := range series {
+		line := svgPolyline{Stroke: s.Color, Width: 2}
+		var markers []svgShape
+		for _, p := range s.Points {
+			pt := svgPoint{
+				X: area.X + ext.xRatio(p.Date)*area.W,
+				Y: area.Y + (1-scale.Ratio(p.Value))*area.H,
+			}
+			line.Points = append(line.Points, pt)
+			tooltip := opts.Dates.Format(p.Date, "Jan 2 2006 15:04") + ": " + opts.Numbers.Format(p.Value)
+			if len(legend) > 0 {
+				tooltip = legend[i].Label + " · " + tooltip
+			}
+			markers = append(markers, svgCircle{CX: pt.X, CY: pt.Y, R: 2.5, Fill: s.Color, Title: tooltip})
+		}
+		doc.Add(line)
+		doc.Add(markers...)
+	}
+	annotateSVG(doc, area, ext, scale, annotations, config, opts)
+	return doc.String()
+}
+
+// singleSeries wraps the classic single-series line graph input so it can
+// use the same renderer as multi-series data.
+func (in lineGraphInput) singleSeries(fallbackColor string) []lineSeries {
+	color := in.Color
+	if color == "" {
+		color = fallbackColor
+	}
+	return []lineSeries{{Label: in.Label, Color: color, Points: sortedPoints(in.Points)}}
+}
+
+// sortedPoints returns a copy of points in date order.
+func sortedPoints(points []timePoint) []timePoint {
+	sorted := append([]timePoint(nil), points...)
+	sort.SliceStable(sorted, func(a, b int) bool { return sorted[a].Date.Before(sorted[b].Date) })
+	return sorted
+}
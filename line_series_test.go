@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLineGraphInputSeries(t *testing.T) {
+	var in lineGraphInput
+	err := json.Unmarshal([]byte(`{"series": [
+		{"label": "a", "points": [
+			{"date": "2024-01-02T00:00:00Z", "value": 5},
+			{"date": "2024-01-01T00:00:00Z", "value": -2}]},
+		{"label": "b", "color": "#123456", "points": [
+			{"date": "2024-01-03T00:00:00Z", "value": 9.5}]}]}`), &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := in.resolvedSeries("nord")
+	if got, want := series[0].Color, paletteColor("nord", 0); got != want {
+		t.Errorf("series 0 color = %q, want palette color %q", got, want)
+	}
+	if series[1].Color != "#123456" {
+		t.Errorf("series 1 color = %q, want the color given", series[1].Color)
+	}
+	if !series[0].Points[0].Date.Before(series[0].Points[1].Date) {
+		t.Errorf("points not sorted by date: %v", series[0].Points)
+	}
+
+	ext, ok := extentOf(series)
+	if !ok {
+		t.Fatal("no extent")
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	want := seriesExtent{start: day(1), end: day(3), min: -2, max: 9.5}
+	if !ext.start.Equal(want.start) || !ext.end.Equal(want.end) || ext.min != want.min || ext.max != want.max {
+		t.Errorf("extent = %+v, want %+v", ext, want)
+	}
+	if r := ext.xRatio(day(2)); r != 0.5 {
+		t.Errorf("xRatio(middle day) = %v, want 0.5", r)
+	}
+}
+
+func TestPaletteColorCycles(t *testing.T) {
+	n := len(themePalettes["default"])
+	if paletteColor("default", n) != paletteColor("default", 0) {
+		t.Error("palette does not cycle")
+	}
+	if paletteColor("unknown", 1) != themePalettes["default"][1] {
+		t.Error("unknown theme does not fall back to the default palette")
+	}
+}
+
+func TestMixColors(t *testing.T) {
+	tests := []struct {
+		a, b string
+		t    float64
+		want string
+	}{
+		{"#000000", "#FFFFFF", 0, "#000000"},
+		{"#000000", "#FFFFFF", 1, "#FFFFFF"},
+		{"#000000", "#FFFFFF", 0.5, "#808080"},
+		{"bad", "#102030", 0.5, "#102030"},
+		{"bad", "worse", 0.5, ""},
+	}
+	for _, tt := range tests {
+		if got := mixColors(tt.a, tt.b, tt.t); got != tt.want {
+			t.Errorf("mixColors(%q, %q, %v) = %q, want %q", tt.a, tt.b, tt.t, got, tt.want)
+		}
+	}
+}
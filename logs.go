@@ -0,0 +1,541 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logTimeFormats are the named -time-format values, each with the pattern
+// that finds such a timestamp in a line.
+var logTimeFormats = map[string]struct {
+	layout  string
+	pattern string
+}{
+	"RFC3339":     {time.RFC3339Nano, `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`},
+	"RFC1123":     {time.RFC1123, `[A-Z][a-z]{2}, \d{2} [A-Z][a-z]{2} \d{4} \d{2}:\d{2}:\d{2} [A-Z]{3,4}`},
+	"common":      {"02/Jan/2006:15:04:05 -0700", `\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`},
+	"syslog":      {time.Stamp, `[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`},
+	"unix":        {"", `\b\d{10}(?:\.\d+)?\b`},
+	"unix-millis": {"", `\b\d{13}\b`},
+}
+
+// logBuckets are the -bucket sizes.
+var logBuckets = map[string]bool{"minute": true, "hour": true, "day": true}
+
+// logOptions configures the logs subcommand.
+type logOptions struct {
+	Pattern    string
+	TimeFormat string
+	TimeRegex  string
+	Bucket     string
+	Follow     bool
+}
+
+// logCounter counts matching lines per time bucket, and per value of the
+// pattern's capture groups when it has any.
+type logCounter struct {
+	pattern   *regexp.Regexp
+	timeRegex *regexp.Regexp
+	parseTime func(string) (time.Time, error)
+	bucket    string
+	counts    map[string]map[time.Time]float64
+	matched   int
+	undated   int
+}
+
+// newLogCounter compiles the options. A -time-format that is not a named
+// format is a Go time layout, and the pattern that finds it is derived
+// from the layout unless -time-regex gives one.
+func newLogCounter(opts logOptions) (*logCounter, error) {
+	c := &logCounter{bucket: opts.Bucket, counts: map[string]map[time.Time]float64{}}
+	if !logBuckets[c.bucket] {
+		return nil, fmt.Errorf("unknown bucket %q (want minute, hour or day)", c.bucket)
+	}
+	var err error
+	if c.pattern, err = regexp.Compile(opts.Pattern); err != nil {
+		return nil, fmt.Errorf("invalid -pattern: %w", err)
+	}
+
+	timePattern := opts.TimeRegex
+	switch format, named := logTimeFormats[opts.TimeFormat]; {
+	case opts.TimeFormat == "unix" || opts.TimeFormat == "unix-millis":
+		millis := opts.TimeFormat == "unix-millis"
+		c.parseTime = func(s string) (time.Time, error) {
+			v, err := strconv.ParseFloat(s, 64)
+			if millis {
+				return time.UnixMilli(int64(v)), err
+			}
+			return time.Unix(0, int64(v*1e9)), err
+		}
+		timePattern = cmp.Or(timePattern, format.pattern)
+	case named:
+		c.parseTime = layoutParser(format.layout, opts.TimeFormat == "syslog")
+		timePattern = cmp.Or(timePattern, format.pattern)
+	default:
+		c.parseTime = layoutParser(opts.TimeFormat, !strings.Contains(opts.TimeFormat, "06"))
+		timePattern = cmp.Or(timePattern, layoutPattern(opts.TimeFormat))
+	}
+	if c.timeRegex, err = regexp.Compile(timePattern); err != nil {
+		return nil, fmt.Errorf("invalid -time-regex: %w", err)
+	}
+	return c, nil
+}
+
+// layoutParser parses timestamps in layout. For layouts without a year,
+// such as syslog's, it assumes the most recent such date not in the
+// future. RFC 3339 timestamps may use a space or a comma as logs often do.
+func layoutParser(layout string, noYear bool) func(string) (time.Time, error) {
+	return func(s string) (time.Time, error) {
+		if layout == time.RFC3339Nano {
+			s = strings.Replace(s, " ", "T", 1)
+			s = strings.Replace(s, ",", ".", 1)
+			if len(s) > 10 && !strings.ContainsAny(s[10:], "Z+-") {
+				return time.ParseInLocation("2006-01-02T15:04:05.999999999", s, time.Local)
+			}
+		}
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil || !noYear {
+			return t, err
+		}
+		now := time.Now()
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, nil
+	}
+}
+
+// layoutPattern derives a regular expression matching timestamps written
+// in a Go time layout.
+func layoutPattern(layout string) string {
+	tokens := []struct{ token, pattern string }{
+		{"January", `[A-Z][a-z]+`}, {"Monday", `[A-Z][a-z]+`},
+		{"2006", `\d{4}`}, {"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`}, {"-07:00", `[+-]\d{2}:\d{2}`},
+		{"-0700", `[+-]\d{4}`}, {"Jan", `[A-Z][a-z]{2}`}, {"Mon", `[A-Z][a-z]{2}`}, {"MST", `[A-Z]{3,4}`},
+		{".000000000", `\.\d{9}`}, {".000000", `\.\d{6}`}, {".000", `\.\d{3}`},
+		{".999999999", `(?:\.\d+)?`}, {".999999", `(?:\.\d+)?`}, {".999", `(?:\.\d+)?`},
+		{"01", `\d{2}`}, {"02", `\d{2}`}, {"_2", `[ \d]\d`}, {"15", `\d{2}`}, {"03", `\d{2}`},
+		{"04", `\d{2}`}, {"05", `\d{2}`}, {"06", `\d{2}`}, {"PM", `[AP]M`},
+		{"1", `\d{1,2}`}, {"2", `\d{1,2}`}, {"3", `\d{1,2}`}, {"4", `\d{1,2}`}, {"5", `\d{1,2}`},
+	}
+	var b strings.Builder
+	for rest := layout; rest != ""; {
+		matched := false
+		for _, t := range tokens {
+			if strings.HasPrefix(rest, t.token) {
+				b.WriteString(t.pattern)
+				rest = rest[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteString(regexp.QuoteMeta(rest[:1]))
+			rest = rest[1:]
+		}
+	}
+	return b.String()
+}
+
+// add counts one line if it matches the pattern and has a timestamp.
+func (c *logCounter) add(line string) bool {
+	groups := c.pattern.FindStringSubmatch(line)
+	if groups == nil {
+		return false
+	}
+	c.matched++
+	stamp := c.timeRegex.FindString(line)
+	t, err := c.parseTime(stamp)
+	if stamp == "" || err != nil {
+		c.undated++
+		return false
+	}
+
+	key := strings.Join(nonEmpty(groups[1:]), "/")
+	if c.counts[key] == nil {
+		c.counts[key] = map[time.Time]float64{}
+	}
+	c.counts[key][c.truncate(t)]++
+	return true
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// truncate returns the start of the bucket holding t.
+func (c *logCounter) truncate(t time.Time) time.Time {
+	switch c.bucket {
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "hour":
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// next returns the start of the bucket after t.
+func (c *logCounter) next(t time.Time) time.Time {
+	switch c.bucket {
+	case "minute":
+		return t.Add(time.Minute)
+	case "hour":
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// maxFilledBuckets bounds the empty buckets added between counts, so a
+// stray timestamp years away cannot produce millions of points.
+const maxFilledBuckets = 100000
+
+// points returns a group's counts in time order, with empty buckets
+// between the first and last count filled with zero.
+func (c *logCounter) points(key string, from, to time.Time) []timePoint {
+	counts := c.counts[key]
+	var points []timePoint
+	for t := from; !t.After(to) && len(points) < maxFilledBuckets; t = c.next(t) {
+		points = append(points, timePoint{Date: t, Value: counts[t]})
+	}
+	return points
+}
+
+// keys returns the groups, largest first.
+func (c *logCounter) keys() []string {
+	totals := map[string]float64{}
+	keys := make([]string, 0, len(c.counts))
+	for key, counts := range c.counts {
+		keys = append(keys, key)
+		for _, n := range counts {
+			totals[key] += n
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]] != totals[keys[j]] {
+			return totals[keys[i]] > totals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// span returns the first and last bucket with a count.
+func (c *logCounter) span() (from, to time.Time) {
+	for _, counts := range c.counts {
+		for t := range counts {
+			if from.IsZero() || t.Before(from) {
+				from = t
+			}
+			if t.After(to) {
+				to = t
+			}
+		}
+	}
+	return from, to
+}
+
+// chartData returns the counts as the JSON input of the chart type: one
+// line per group over time, the daily or hourly calendar of all matches,
+// the total per group as a table, drawn as bars, slices or rows, or the
+// total of all matches with its trend as a stat card.
+func (c *logCounter) chartData(vizType, label string) ([]byte, error) {
+	if len(c.counts) == 0 {
+		if c.undated > 0 {
+			return nil, fmt.Errorf("%d lines matched but none had a timestamp; check -time-format", c.undated)
+		}
+		return nil, fmt.Errorf("no lines matched")
+	}
+	keys := c.keys()
+	from, to := c.span()
+
+	switch vizType {
+	case "line-graph", "area", "sparkline":
+		if len(keys) == 1 {
+			return json.Marshal(map[string]any{"points": c.points(keys[0], from, to)})
+		}
+		series := make([]lineSeries, len(keys))
+		for i, key := range keys {
+			series[i] = lineSeries{Label: key, Points: c.points(key, from, to)}
+		}
+		return json.Marshal(map[string]any{"series": series})
+
+	case "heatmap":
+		var days []heatmapDay
+		for _, counts := range c.counts {
+			for t, n := range counts {
+				days = append(days, heatmapDay{Date: t, Count: n})
+			}
+		}
+		sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+		return json.Marshal(map[string]any{"days": days})
+
+	case "stat-card":
+		total := 0.0
+		var trend []timePoint
+		for _, key := range keys {
+			for i, p := range c.points(key, from, to) {
+				if i == len(trend) {
+					trend = append(trend, p)
+				} else {
+					trend[i].Value += p.Value
+				}
+				total += p.Value
+			}
+		}
+		return json.Marshal(map[string]any{"title": label, "value": total, "subtitle": "trend per " + c.bucket, "trendData": trend})
+
+	case "bar-chart", "pie", "donut", "table":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"match", "lines"})
+		for _, key := range keys {
+			total := 0.0
+			for _, n := range c.counts[key] {
+				total += n
+			}
+			w.Write([]string{cmp.Or(key, label), strconv.FormatFloat(total, 'f', -1, 64)})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	}
+	return nil, fmt.Errorf("logs draw heatmap, line-graph, area, sparkline, stat-card, bar-chart, pie, donut and table, not %s", vizType)
+}
+
+// logFile is an open log. Reads return its lines, decompressed when the
+// file is gzipped.
+type logFile struct {
+	io.Reader
+	file *os.File
+}
+
+func (l logFile) Close() error { return l.file.Close() }
+
+// offset returns how far into the file has been read, which once the log
+// has been scanned to the end is where following it starts.
+func (l logFile) offset() (int64, error) {
+	return l.file.Seek(0, io.SeekCurrent)
+}
+
+// openLog opens a log file, or stdin for "-", decompressing gzip files,
+// such as rotated logs, by their magic number.
+func openLog(path string) (logFile, error) {
+	var f *os.File
+	if path == "-" {
+		f = os.Stdin
+	} else {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return logFile{}, err
+		}
+	}
+	r := bufio.NewReader(f)
+	if magic, err := r.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return logFile{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return logFile{gz, f}, nil
+	}
+	return logFile{r, f}, nil
+}
+
+// scanLog feeds every line of r to the counter.
+func scanLog(r io.Reader, c *logCounter) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for scanner.Scan() {
+		c.add(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// logTail reads what has been appended to a file since the last read,
+// starting again from the top when the file shrinks, as it does when it
+// is truncated or replaced by log rotation.
+type logTail struct {
+	path    string
+	offset  int64
+	partial string
+}
+
+func (t *logTail) read(c *logCounter) (bool, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() < t.offset {
+		t.offset, t.partial = 0, ""
+	}
+	if info.Size() == t.offset {
+		return false, nil
+	}
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return false, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return false, err
+	}
+	t.offset += int64(len(data))
+
+	lines := strings.Split(t.partial+string(data), "\n")
+	t.partial = lines[len(lines)-1]
+	counted := false
+	for _, line := range lines[:len(lines)-1] {
+		if c.add(strings.TrimSuffix(line, "\r")) {
+			counted = true
+		}
+	}
+	return counted, nil
+}
+
+// logs runs the logs subcommand and returns the process exit code. It
+// takes the chart options of render as well as its own.
+func logs(args []string) int {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	var opts logOptions
+	fs.StringVar(&opts.Pattern, "pattern", "", "Regular expression selecting lines")
+	fs.StringVar(&opts.TimeFormat, "time-format", "RFC3339", "Timestamp format")
+	fs.StringVar(&opts.TimeRegex, "time-regex", "", "Regular expression finding the timestamp")
+	fs.StringVar(&opts.Bucket, "bucket", "hour", "Time bucket size")
+	fs.BoolVar(&opts.Follow, "follow", false, "Keep reading the last file")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	cfg, err := parseFlags(fs, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if !flagSet(fs, "type") {
+		cfg.vizType = "line-graph"
+	}
+	cfg = terminalGraphics(cfg)
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	counter, err := newLogCounter(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	label := cmp.Or(opts.Pattern, "lines")
+	var offset int64
+	for i, path := range files {
+		r, err := openLog(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading logs: %v\n", err)
+			return 1
+		}
+		err = scanLog(r, counter)
+		if err == nil && opts.Follow && i == len(files)-1 && path != "-" {
+			offset, err = r.offset()
+		}
+		r.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+			return 1
+		}
+	}
+
+	draw := func() (string, error) {
+		data, err := counter.chartData(cfg.vizType, label)
+		if err != nil {
+			return "", err
+		}
+		return render(cfg, data)
+	}
+
+	if !opts.Follow {
+		output, err := draw()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := writeOutput(cfg.output, output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	return followLog(cfg, files[len(files)-1], offset, counter, draw)
+}
+
+// followLog redraws the chart as lines are appended to path after offset,
+// where the scan of the file ended, checking at the -poll interval, or
+// every second.
+func followLog(cfg Config, path string, offset int64, counter *logCounter, draw func() (string, error)) int {
+	if path == "-" {
+		fmt.Fprintln(os.Stderr, "Error: -follow needs a log file")
+		return 1
+	}
+	live, err := newLiveOutput(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tail := &logTail{path: path, offset: offset}
+	status := "Following " + path
+
+	output, err := draw()
+	live.update(output, err, status)
+	interval := cfg.watch.Poll
+	if interval <= 0 {
+		interval = defaultPoll
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		counted, err := tail.read(counter)
+		if err != nil {
+			live.update("", err, status)
+			continue
+		}
+		if counted {
+			output, err := draw()
+			live.update(output, err, status)
+		}
+	}
+	return 0
+}
+
+// flagSet reports whether a flag was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
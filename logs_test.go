@@ -0,0 +1,188 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogCounterTimeFormats(t *testing.T) {
+	tests := []struct {
+		format, line string
+		want         time.Time
+	}{
+		{"RFC3339", "2024-03-01T12:34:56Z ERROR disk full", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"RFC3339", "2024-03-01 12:34:56,123+01:00 ERROR", time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{"common", `10.0.0.1 - - [01/Mar/2024:12:34:56 +0000] "GET / HTTP/1.1" 500`, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"unix", "1709296496 ERROR", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"unix-millis", "ts=1709296496000 ERROR", time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{"2006/01/02 15:04", "[2024/03/01 12:34] ERROR", time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		c, err := newLogCounter(logOptions{Pattern: "ERROR|500", TimeFormat: tt.format, Bucket: "hour"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.add(tt.line) {
+			t.Errorf("%s: %q not counted", tt.format, tt.line)
+			continue
+		}
+		for bucket := range c.counts[""] {
+			if !bucket.Equal(tt.want) {
+				t.Errorf("%s: bucket %v, want %v", tt.format, bucket, tt.want)
+			}
+		}
+	}
+}
+
+func TestNewLogCounterErrors(t *testing.T) {
+	for _, opts := range []logOptions{
+		{Pattern: "x", TimeFormat: "RFC3339", Bucket: "week"},
+		{Pattern: "(", TimeFormat: "RFC3339", Bucket: "day"},
+		{Pattern: "x", TimeFormat: "RFC3339", TimeRegex: "[", Bucket: "day"},
+	} {
+		if _, err := newLogCounter(opts); err == nil {
+			t.Errorf("newLogCounter(%+v) succeeded, want an error", opts)
+		}
+	}
+}
+
+func TestLayoutPattern(t *testing.T) {
+	tests := []struct{ layout, match string }{
+		{"2006-01-02 15:04:05.000", "at 2024-03-01 12:34:56.789 x"},
+		{"Jan _2 15:04:05", "Mar  1 12:34:56"},
+		{"02/Jan/2006:15:04:05 -0700", "01/Mar/2024:12:34:56 +0100"},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile(layoutPattern(tt.layout))
+		if !re.MatchString(tt.match) {
+			t.Errorf("layoutPattern(%q) = %s does not match %q", tt.layout, re, tt.match)
+		}
+	}
+}
+
+func TestLogCounterChartData(t *testing.T) {
+	c, err := newLogCounter(logOptions{Pattern: `" (\d)\d\d `, TimeFormat: "common", Bucket: "minute"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{
+		`[01/Mar/2024:12:00:10 +0000] "GET /" 200 `,
+		`[01/Mar/2024:12:00:50 +0000] "GET /a" 503 `,
+		`[01/Mar/2024:12:02:00 +0000] "GET /b" 200 `,
+		`[01/Mar/2024:12:02:00 +0000] no status`,
+		`"GET /c" 404 without a time`,
+	}
+	for _, line := range lines {
+		c.add(line)
+	}
+	if c.matched != 4 || c.undated != 1 {
+		t.Errorf("matched %d, undated %d, want 4 and 1", c.matched, c.undated)
+	}
+
+	out, err := c.chartData("line-graph", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in lineGraphInput
+	if err := json.Unmarshal(out, &in); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.Series) != 2 || in.Series[0].Label != "2" || len(in.Series[0].Points) != 3 || in.Series[0].Points[1].Value != 0 {
+		t.Errorf("series = %+v, want 2xx first with the empty minute filled", in.Series)
+	}
+
+	out, err = c.chartData("bar-chart", "")
+	if err != nil || string(out) != "match,lines\n2,2\n5,1\n" {
+		t.Errorf("bar-chart data = %q, %v", out, err)
+	}
+	if _, err := c.chartData("gauge", ""); err == nil {
+		t.Error("gauge accepted")
+	}
+
+	empty, _ := newLogCounter(logOptions{Pattern: "x", TimeFormat: "RFC3339", Bucket: "day"})
+	empty.add("x without a time")
+	if _, err := empty.chartData("line-graph", ""); err == nil || !strings.Contains(err.Error(), "-time-format") {
+		t.Errorf("undated matches: error %v, want a hint about -time-format", err)
+	}
+}
+
+func TestOpenLogGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	io.WriteString(gz, "2024-03-01T00:00:00Z ERROR a\n2024-03-02T00:00:00Z ERROR b\n")
+	gz.Close()
+	f.Close()
+
+	r, err := openLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	c, _ := newLogCounter(logOptions{Pattern: "ERROR", TimeFormat: "RFC3339", Bucket: "day"})
+	if err := scanLog(r, c); err != nil {
+		t.Fatal(err)
+	}
+	if c.matched != 2 {
+		t.Errorf("matched %d lines in the gzipped log, want 2", c.matched)
+	}
+}
+
+func TestFollowFromScanOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	write := func(text string, flag int) {
+		f, err := os.OpenFile(path, flag|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		f.WriteString(text)
+	}
+	write("2024-03-01T00:00:00Z ERROR a\n", os.O_CREATE|os.O_TRUNC)
+
+	c, _ := newLogCounter(logOptions{Pattern: "ERROR", TimeFormat: "RFC3339", Bucket: "day"})
+	r, err := openLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanLog(r, c)
+	offset, err := r.offset()
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lines written after the scan are read by the tail, not skipped.
+	write("2024-03-01T00:01:00Z ERROR b\n2024-03-01T00:02:00Z ERR", os.O_APPEND)
+
+	tail := &logTail{path: path, offset: offset}
+	if counted, err := tail.read(c); !counted || err != nil {
+		t.Fatalf("read = %v, %v", counted, err)
+	}
+	if c.matched != 2 {
+		t.Errorf("matched %d lines, want 2", c.matched)
+	}
+	write("OR c\n", os.O_APPEND)
+	tail.read(c)
+	if c.matched != 3 {
+		t.Errorf("matched %d lines after the partial line was finished, want 3", c.matched)
+	}
+
+	// A rotated, shorter file is read from the top.
+	write("2024-03-02T00:00:00Z ERROR d\n", os.O_TRUNC)
+	tail.read(c)
+	if c.matched != 4 {
+		t.Errorf("matched %d lines after rotation, want 4", c.matched)
+	}
+}
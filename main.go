@@ -1,11 +1,19 @@
+//go:build !dashboard && !simpledashboard
+
 package main
 
 import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"html"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/SCKelemen/dataviz"
@@ -16,14 +24,60 @@ const usage = `viz-cli - Data visualization tool with SVG and terminal output
 
 Usage:
   viz-cli [options]
+  viz-cli render [options]
+  viz-cli batch [-workers N] manifest.json
+  viz-cli serve [-addr :8080] [-max-bytes N] [-max-age D] [-max-size N] [-max-area N]
+                [-rasterizer CMD]
+  viz-cli logs [options] [FILE...]
+  viz-cli git [options] [PATH...]
 
 Options:
   -type string
-        Visualization type: heatmap, line-graph, bar-chart, stat-card (default "heatmap")
+        Visualization type: heatmap, line-graph, bar-chart, stat-card, scatter,
+        area, histogram, pie, donut, sparkline, table, gauge, progress,
+        bullet (default "heatmap")
   -format string
-        Output format: svg, terminal (default "terminal")
+        Output format: svg, terminal, or an image drawn inline in the terminal:
+        sixel, kitty, iterm (default "terminal"). Images need the -rasterizer
+        installed, fill -width by -height cells and fall back to terminal text
+        without the rasterizer or when the terminal does not answer that it
+        supports the protocol
+  -rasterizer string
+        SVG to PNG converter for image formats, reading stdin and writing
+        stdout (default "rsvg-convert")
   -data string
-        Path to JSON data file (or use stdin with -)
+        Path or http(s) URL of the JSON or CSV data (or use stdin with -)
+  -header string
+        HTTP header for a -data URL, e.g. 'Authorization: Bearer TOKEN' (repeatable)
+  -timeout duration
+        HTTP request timeout for a -data URL (default 10s)
+  -cache-dir string
+        Where the last good response for each URL is kept, and used when a
+        later fetch fails; empty disables caching (default: user cache dir)
+  -select string
+        Path to the data within a JSON document, e.g. $.data.items[0] or
+        results[*].value; FIELD=PATH wraps the result, e.g. points=$.data.values
+  -output string
+        Write the chart to this file instead of stdout
+  -sql string
+        Chart the result of this SQL query instead of -data
+  -db string
+        Database for -sql, e.g. sqlite:///path/to/app.db (opened read-only)
+  -x string
+        Table column holding the dates or labels (default: the first column)
+  -y string
+        Comma-separated table columns holding values (default: every numeric column)
+  -series string
+        Table column whose values split the rows into series or bar segments
+  -input string
+        Read -data as another format and convert it for the chart type:
+        prometheus (text exposition format, OpenMetrics, or a query API response)
+  -metric string
+        Prometheus series selector, e.g. http_requests_total{code=~"5.."}
+  -by string
+        Comma-separated Prometheus labels to group series by
+  -agg string
+        Combine the series of a group: sum, avg, min, max, count (default "sum")
   -theme string
         Theme: default, midnight, nord, paper, wrapped (default "default")
   -width int
@@ -32,12 +86,149 @@ Options:
         Height in pixels (SVG) or characters (terminal) (default 24)
   -color string
         Primary color for visualization (hex format) (default "#3B82F6")
+  -axes
+        Draw labelled axes on line, bar, scatter, area and histogram charts,
+        and month, hour and weekday labels on heatmaps
+  -grid
+        Draw dim gridlines at each axis tick
+  -y-min float
+        Lower bound of the value axis (default: from data)
+  -y-max float
+        Upper bound of the value axis (default: from data)
+  -y-log
+        Use a logarithmic value axis
+  -number-format string
+        Value labels: plain, grouped[:N], si[:N], bytes[:N], percent[:N],
+        currency[:CODE], fixed:N (default "plain")
+  -date-format string
+        Date labels: auto, iso, rfc3339, relative, or a Go time layout (default "auto")
+  -locale string
+        Locale for digit grouping and decimal marks, e.g. en, de, fr (default "en")
+  -spark-style string
+        Sparkline glyphs: blocks, or braille for twice the resolution (default "blocks")
+  -spark-labels string
+        Comma-separated sparkline annotations: min, max, last
+  -sort string
+        Sort table rows by a column; prefix with - for descending order
+  -bars string
+        Comma-separated table columns to show with inline bars
+  -heat string
+        Comma-separated table columns to shade by value
+  -border string
+        Table border: light, rounded, heavy, double, ascii, none (default "light")
+  -bar-mode string
+        Draw bar segments stacked, grouped, or percent (stacked to 100%)
+  -orientation string
+        Bar orientation: horizontal, vertical (default "horizontal")
+  -mark DATE[=LABEL]
+        Vertical marker on line graphs and heatmaps, e.g. 2024-01-15=deploy v2 (repeatable)
+  -hline VALUE[=LABEL]
+        Horizontal reference line on line graphs, e.g. 99.9=SLO (repeatable)
+  -shade FROM..TO[=LABEL]
+        Shaded date range, e.g. 2024-01-10..2024-01-12=outage (repeatable)
+  -callout DATE,VALUE[=LABEL]
+        Labelled point on line graphs or day on heatmaps (repeatable)
+  -layout string
+        Heatmap layout: weeks, linear, or day-hour for a 7x24 hour-of-week grid
+  -buckets string
+        Comma-separated lower bounds of the heatmap color levels, e.g. 1,5,10,20
+  -quantiles int
+        Split the non-zero heatmap counts into this many equally full levels
+  -scale string
+        Heatmap color scale: linear, log (default "linear")
+  -legend
+        Draw a heatmap legend mapping colors to counts
+  -watch
+        Redraw the chart whenever the -data file changes; SVG output also
+        needs -output, which is rewritten on each change
+  -debounce duration
+        Wait this long after the last change before redrawing (default 200ms)
+  -poll duration
+        Check the data file at this interval instead of using change
+        notifications, e.g. 1s on network filesystems
+
+Logs Options (with the options above; -type defaults to line-graph):
+  -pattern string
+        Regular expression selecting the lines to count; with capture groups,
+        lines are counted per captured value, e.g. ' (ERROR|WARN) '
+  -time-format string
+        Timestamp format: RFC3339, RFC1123, common (web server logs), syslog,
+        unix, unix-millis, or a Go time layout (default "RFC3339")
+  -time-regex string
+        Regular expression finding the timestamp (default: from -time-format)
+  -bucket string
+        Count per minute, hour or day (default "hour")
+  -follow
+        Keep reading lines appended to the last file and redraw; -poll sets
+        how often it is checked
+  Files ending in gzip data, such as rotated logs, are decompressed; with no
+  files, lines are read from stdin.
+
+Git Options (with the options above; -type defaults to heatmap):
+  -repo string
+        Repository to read the local history of (default ".")
+  -author string
+        Only commits by authors matching this pattern
+  -since string, -until string
+        Only commits after or before a date, e.g. 2024-01-01 or "3 months ago"
+  -no-merges
+        Leave out merge commits
+  -per string
+        Bars, slices and table rows per author or per top-level dir
+        (default "author")
+  -top int
+        Bars shown before the rest are combined, 0 for all (default 10)
+  -bucket string
+        Line graph, area and stat card trend per day, week or month
+        (default "day")
+  The heatmap shows commits per day and line graphs the lines added and
+  deleted; PATH arguments limit the history to those files or directories.
 
 Data Formats:
+  Values may be fractional or negative. Bar charts with negative values are
+  drawn from a zero baseline; heatmap counts are shaded relative to the range.
   Heatmap:      {"days": [{"date": "2024-01-01T00:00:00Z", "count": 10}, ...], "type": "linear"}
+                optional "buckets": [1, 5, 10], "quantiles": 4, "scale": "log",
+                "labels": true, "legend": true; "type": "day-hour" sums counts
+                by weekday and hour of "date"
   Line Graph:   {"points": [{"date": "2024-01-01T00:00:00Z", "value": 100}, ...], "color": "#3B82F6"}
+                or {"series": [{"label": "v1", "color": "#3B82F6", "points": [...]}, ...]}
+                optional "annotations": [{"date": "...", "label": "deploy"}, {"value": 99.9,
+                "label": "SLO"}, {"from": "...", "to": "...", "label": "outage"},
+                {"date": "...", "value": 42, "label": "peak"}]; "color" may be a hex
+                color or good, warn, critical
   Bar Chart:    {"bars": [{"value": 100, "secondary": 50, "label": "Item 1"}, ...], "color": "#3B82F6"}
+                or {"segments": [{"label": "Open", "color": "#10B981"}, ...],
+                    "bars": [{"label": "v1.0", "values": [12, 30]}, ...]}
+                optional "mode": "stacked" | "grouped" | "percent", "orientation": "vertical"
   Stat Card:    {"title": "Total", "value": "1,234", "subtitle": "past month", "color": "#3B82F6"}
+                (a numeric "value" is formatted with -number-format)
+  Scatter:      {"points": [{"x": 1.5, "y": 20, "label": "a"}, ...]} or {"series": [...]}
+                (use "date" instead of "x" for a time axis)
+  Area:         same as Line Graph; several series are stacked
+  Histogram:    {"values": [1.2, 3.4, ...], "bins": 10} (bins default to Sturges' rule)
+  Sparkline:    same as a single-series Line Graph; printed as exactly -width
+                columns of plain text with no trailing newline
+  Table:        {"columns": ["Repo", {"name": "Stars", "style": "bar"}], "rows": [["viz-cli", 120], ...]}
+                or CSV with a header row; numeric cells are formatted with -number-format
+  Pie / Donut:  {"slices": [{"label": "Go", "value": 42, "color": "#00ADD8"}, ...], "innerRadius": 0.6}
+  Gauge:        {"label": "Error budget", "value": 72, "min": 0, "max": 100, "target": 90,
+                 "thresholds": {"warn": 50, "critical": 25}}
+                (also for progress and bullet; max defaults to 100, and a critical
+                threshold below warn means low values are bad)
+  Tabular:      CSV with a header of two or more columns, or the rows of a -sql
+                query, for any chart type: -x is the date or label of each row,
+                -y the values and -series splits rows into series; dates may be
+                RFC 3339 or YYYY-MM-DD [HH:MM[:SS]]
+  Prometheus:   with -input prometheus, the newest value of each group becomes a bar
+                or table row; series over time become line graph, area and sparkline
+                series; a stat card shows every selected series combined
+  Batch:        {"workers": 4, "defaults": {"format": "svg", "theme": "nord"},
+                 "charts": [{"name": "latency", "type": "line-graph", "data": "latency.json",
+                             "width": 800, "height": 300, "output": "out/latency.svg",
+                             "args": ["-axes"]}, ...]}
+                (or just the array of charts; paths are relative to the manifest,
+                and workers defaults to the number of CPUs)
 
 Examples:
   # Terminal heatmap from file
@@ -46,34 +237,181 @@ Examples:
   # SVG line graph from stdin
   cat metrics.json | viz-cli -type line-graph -format svg > output.svg
 
+  # Compare several series; colors come from the theme when omitted
+  viz-cli -type line-graph -data examples/multiseries.json -theme nord
+
   # Terminal bar chart with custom theme
   viz-cli -type bar-chart -data repos.json -theme midnight
+
+  # Line graph with axes and gridlines, value axis starting at zero
+  viz-cli -type line-graph -data examples/linegraph.json -axes -grid -y-min 0
+
+  # Mark a deploy, an outage and the SLO target on a latency graph
+  viz-cli -type line-graph -data examples/linegraph.json -axes \
+    -mark 2024-01-05=deploy -shade 2024-01-07..2024-01-08=outage -hline 140=SLO
+
+  # Byte sizes on the value axis, ISO dates on the time axis
+  viz-cli -type line-graph -data usage.json -axes -number-format bytes -date-format iso
+
+  # On-call pages by hour of the week, in quartiles, with labels and a legend
+  viz-cli -type heatmap -data examples/pages.json -quantiles 4 -axes -legend
+
+  # Issues per release as stacked columns, then as shares of each release
+  viz-cli -type bar-chart -data examples/segments.json -orientation vertical -axes
+  viz-cli -type bar-chart -data examples/segments.json -bar-mode percent
+
+  # Stacked areas, a scatter plot and a donut
+  viz-cli -type area -data examples/multiseries.json -axes
+  viz-cli -type scatter -data examples/scatter.json -axes -grid
+  viz-cli -type donut -data examples/pie.json
+
+  # CSV report sorted by stars, with inline bars and shaded cells
+  viz-cli -type table -data examples/repos.csv -sort -stars -bars stars -heat issues
+
+  # Error budget as a dial, and the same value as a bullet chart
+  viz-cli -type gauge -data examples/gauge.json -height 10
+  viz-cli -type bullet -data examples/gauge.json
+
+  # Redraw in place whenever cron regenerates the data, or keep an SVG current
+  viz-cli render -watch -type line-graph -data metrics.json -axes
+  viz-cli render -watch -type heatmap -data pages.json -format svg -output pages.svg
+
+  # Serve charts over HTTP: POST data (or GET it in ?data=) to /render/{type};
+  # options are query parameters, and ?format= or the Accept header picks
+  # svg, png (via rsvg-convert) or terminal text; /healthz reports liveness
+  viz-cli serve -addr :8080
+  curl --data-binary @examples/linegraph.json 'localhost:8080/render/line-graph?axes=true'
+
+  # Chart an API response, falling back to the cached copy when it is down
+  viz-cli -type line-graph -data https://metrics.example.com/api/latency \
+    -header 'Authorization: Bearer TOKEN' -select 'points=$.data.series[0].values'
+
+  # Requests per status code from a /metrics endpoint, and a range query
+  viz-cli -type bar-chart -input prometheus -data http://localhost:9090/metrics \
+    -metric http_requests_total -by code -axes
+  viz-cli -type line-graph -input prometheus -axes -by instance \
+    -data 'http://prometheus:9090/api/v1/query_range?query=rate(errors_total[5m])&start=...'
+
+  # Events per day from a SQLite database, and per month split by kind
+  viz-cli render -type line-graph -axes -db sqlite:///var/lib/app.db \
+    -sql 'SELECT day, count(*) AS events FROM events GROUP BY day'
+  viz-cli render -type bar-chart -series kind -db sqlite:///var/lib/app.db \
+    -sql "SELECT strftime('%Y-%m', day) AS month, kind, count(*) FROM events GROUP BY 1, 2"
+
+  # Errors per hour across rotated logs, and HTTP status classes per minute, live
+  viz-cli logs -pattern ERROR -axes app.log.2.gz app.log.1 app.log
+  viz-cli logs -pattern '" (\d)\d\d ' -time-format common -bucket minute -follow access.log
+
+  # A year of commits, the busiest directories, and weekly churn under src/
+  viz-cli git -since "1 year ago"
+  viz-cli git -type bar-chart -per dir -no-merges
+  viz-cli git -type line-graph -bucket week -axes src/
+
+  # Render every chart of a nightly report in one run
+  viz-cli batch examples/batch.json
+
+  # Full-resolution line graph inline in a kitty, sixel or iTerm2 terminal
+  viz-cli -type line-graph -data examples/linegraph.json -axes -format kitty
+
+  # One-line trend for a tmux status line or shell prompt
+  viz-cli -type sparkline -data examples/linegraph.json -width 20 -spark-labels last
 `
 
+// renderOptions are the chart settings from the command line: the axis and
+// label settings that every chart shares, and the settings of single chart
+// types, which are merged into a chart's input before it is drawn.
+type renderOptions struct {
+	chartOptions
+	Spark    sparkOptions
+	Table    tableOptions
+	Heatmap  heatmapOptions
+	BarChart barChartOptions
+
+	// Annotations are drawn on line graphs and heatmaps in addition to any
+	// in the data.
+	Annotations []annotation
+}
+
 type Config struct {
-	vizType  string
-	format   string
-	dataFile string
-	theme    string
-	width    int
-	height   int
-	color    string
+	vizType    string
+	format     string
+	rasterizer string
+	dataFile   string
+	output     string
+	theme      string
+	width      int
+	height     int
+	color      string
+	chart      renderOptions
+	watch      watchOptions
+	fetch      fetchOptions
+	input      string
+	prom       promOptions
+	sql        sqlOptions
+	columns    columnMapping
 }
 
 func main() {
-	cfg := parseFlags()
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "render":
+			args = args[1:]
+		case "batch":
+			os.Exit(batch(args[1:]))
+		case "serve":
+			os.Exit(serve(args[1:]))
+		case "logs":
+			os.Exit(logs(args[1:]))
+		case "git":
+			os.Exit(gitActivity(args[1:]))
+		}
+	}
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	cfg, err := parseFlags(flag.CommandLine, args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg = terminalGraphics(cfg)
 
-	if cfg.dataFile == "" || cfg.dataFile == "-" {
+	if cfg.watch.Enabled {
+		if err := watch(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.sql.Query == "" && (cfg.dataFile == "" || cfg.dataFile == "-") {
 		fmt.Fprintln(os.Stderr, "Reading from stdin...")
 	}
 
 	// Read data
-	data, err := readData(cfg.dataFile)
+	data, err := loadData(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading data: %v\n", err)
 		os.Exit(1)
 	}
 
+	output, err := render(cfg, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Output result
+	if err := writeOutput(cfg.output, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// render draws data as configured and returns the SVG document or the
+// terminal text.
+func render(cfg Config, data []byte) (string, error) {
 	// Get design tokens
 	tokens := getTheme(cfg.theme)
 
@@ -85,50 +423,224 @@ func main() {
 		Theme:        cfg.theme,
 	}
 
+	// Convert other input formats to the chart's JSON
+	var err error
+	switch {
+	case cfg.input == "prometheus":
+		data, err = prometheusData(data, cfg.vizType, cfg.prom)
+	case cfg.vizType != "table" && (cfg.sql.Query != "" || isTabular(data)):
+		data, err = tabularData(data, cfg.vizType, cfg.columns)
+	}
+	if err != nil {
+		return "", err
+	}
+
 	// Choose renderer
 	var output dataviz.Output
 	switch cfg.format {
 	case "svg":
-		output = renderSVG(cfg.vizType, data, bounds, renderConfig)
+		output, err = renderSVG(cfg.vizType, data, bounds, renderConfig, cfg.chart)
 	case "terminal":
-		output = renderTerminal(cfg.vizType, data, bounds, renderConfig)
+		output, err = renderTerminal(cfg.vizType, data, bounds, renderConfig, cfg.chart)
+	case "sixel", "kitty", "iterm":
+		return renderImage(cfg, data, renderConfig)
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown format: %s\n", cfg.format)
-		os.Exit(1)
+		return "", fmt.Errorf("unknown format: %s", cfg.format)
 	}
+	if err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
 
-	// Output result
-	fmt.Print(output.String())
+// writeOutput prints the output, or writes it to path when one is given.
+// The file is replaced in one step so that readers never see it half
+// written.
+func writeOutput(path, output string) error {
+	if path == "" || path == "-" {
+		_, err := fmt.Print(output)
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(output); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
 }
 
-func parseFlags() Config {
-	cfg := Config{}
+// parseFlags reads command line options into a Config using the flag set
+// fs, so that batch entries can be parsed the same way as the command line.
+func parseFlags(fs *flag.FlagSet, args []string) (Config, error) {
+	cfg := Config{chart: renderOptions{chartOptions: defaultChartOptions()}}
+
+	fs.StringVar(&cfg.vizType, "type", "heatmap", "Visualization type")
+	fs.StringVar(&cfg.format, "format", "terminal", "Output format")
+	fs.StringVar(&cfg.rasterizer, "rasterizer", "rsvg-convert", "SVG to PNG converter for image formats")
+	fs.StringVar(&cfg.dataFile, "data", "-", "Data file path")
+	fs.StringVar(&cfg.output, "output", "", "Output file path")
+	fs.Var(headerFlag{&cfg.fetch.Headers}, "header", "HTTP request header")
+	fs.DurationVar(&cfg.fetch.Timeout, "timeout", 10*time.Second, "HTTP request timeout")
+	fs.StringVar(&cfg.fetch.CacheDir, "cache-dir", defaultCacheDir(), "HTTP response cache directory")
+	fs.StringVar(&cfg.fetch.Select, "select", "", "Path to the data within the JSON document")
+	fs.StringVar(&cfg.sql.Query, "sql", "", "SQL query")
+	fs.StringVar(&cfg.sql.DB, "db", "", "Database URL")
+	fs.StringVar(&cfg.columns.X, "x", "", "Date or label column")
+	yColumns := fs.String("y", "", "Value columns")
+	fs.StringVar(&cfg.columns.Series, "series", "", "Series column")
+	fs.StringVar(&cfg.input, "input", "", "Input format")
+	fs.StringVar(&cfg.prom.Metric, "metric", "", "Prometheus series selector")
+	promBy := fs.String("by", "", "Prometheus labels to group by")
+	fs.StringVar(&cfg.prom.Agg, "agg", "sum", "Prometheus aggregation")
+	fs.StringVar(&cfg.theme, "theme", "default", "Theme name")
+	fs.IntVar(&cfg.width, "width", 80, "Width")
+	fs.IntVar(&cfg.height, "height", 24, "Height")
+	fs.StringVar(&cfg.color, "color", "#3B82F6", "Primary color")
+	fs.BoolVar(&cfg.chart.Axes, "axes", false, "Draw labelled axes")
+	fs.BoolVar(&cfg.chart.Grid, "grid", false, "Draw gridlines")
+	fs.Float64Var(&cfg.chart.YMin, "y-min", math.NaN(), "Value axis lower bound")
+	fs.Float64Var(&cfg.chart.YMax, "y-max", math.NaN(), "Value axis upper bound")
+	fs.BoolVar(&cfg.chart.YLog, "y-log", false, "Logarithmic value axis")
+	numberSpec := fs.String("number-format", "plain", "Number format")
+	dateSpec := fs.String("date-format", "auto", "Date format")
+	localeTag := fs.String("locale", "en", "Locale")
+	fs.StringVar(&cfg.chart.Spark.Style, "spark-style", "blocks", "Sparkline style")
+	sparkLabels := fs.String("spark-labels", "", "Sparkline annotations")
+	fs.StringVar(&cfg.chart.Table.Sort, "sort", "", "Table sort column")
+	barColumns := fs.String("bars", "", "Table columns with inline bars")
+	heatColumns := fs.String("heat", "", "Table columns shaded by value")
+	fs.StringVar(&cfg.chart.Table.Border, "border", "light", "Table border style")
+	fs.StringVar(&cfg.chart.BarChart.Mode, "bar-mode", "", "Bar segment mode")
+	fs.StringVar(&cfg.chart.BarChart.Orientation, "orientation", "", "Bar orientation")
+	fs.Var(annotationFlag{"marker", &cfg.chart.Annotations}, "mark", "Vertical date marker")
+	fs.Var(annotationFlag{"line", &cfg.chart.Annotations}, "hline", "Horizontal reference line")
+	fs.Var(annotationFlag{"range", &cfg.chart.Annotations}, "shade", "Shaded date range")
+	fs.Var(annotationFlag{"callout", &cfg.chart.Annotations}, "callout", "Point callout")
+	fs.StringVar(&cfg.chart.Heatmap.Layout, "layout", "", "Heatmap layout")
+	buckets := fs.String("buckets", "", "Heatmap bucket lower bounds")
+	fs.IntVar(&cfg.chart.Heatmap.Quantiles, "quantiles", 0, "Heatmap quantile buckets")
+	fs.StringVar(&cfg.chart.Heatmap.Scale, "scale", "", "Heatmap color scale")
+	fs.BoolVar(&cfg.chart.Heatmap.Legend, "legend", false, "Heatmap legend")
+	fs.BoolVar(&cfg.watch.Enabled, "watch", false, "Re-render when the data file changes")
+	fs.DurationVar(&cfg.watch.Debounce, "debounce", 200*time.Millisecond, "Watch debounce delay")
+	fs.DurationVar(&cfg.watch.Poll, "poll", 0, "Watch polling interval")
+
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
 
-	flag.StringVar(&cfg.vizType, "type", "heatmap", "Visualization type")
-	flag.StringVar(&cfg.format, "format", "terminal", "Output format")
-	flag.StringVar(&cfg.dataFile, "data", "-", "Data file path")
-	flag.StringVar(&cfg.theme, "theme", "default", "Theme name")
-	flag.IntVar(&cfg.width, "width", 80, "Width")
-	flag.IntVar(&cfg.height, "height", 24, "Height")
-	flag.StringVar(&cfg.color, "color", "#3B82F6", "Primary color")
+	numbers, err := parseNumberFormat(*numberSpec, *localeTag)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.chart.Numbers = numbers
+	cfg.chart.Dates = parseDateFormat(*dateSpec)
 
-	flag.Usage = func() {
-		fmt.Fprint(os.Stderr, usage)
+	if cfg.chart.Spark.Style != "blocks" && cfg.chart.Spark.Style != "braille" {
+		return cfg, fmt.Errorf("unknown sparkline style %q", cfg.chart.Spark.Style)
 	}
+	cfg.chart.Spark.Labels, err = parseSparkLabels(*sparkLabels)
+	if err != nil {
+		return cfg, err
+	}
+
+	if _, ok := borderStyles[cfg.chart.Table.Border]; !ok {
+		return cfg, fmt.Errorf("unknown border style %q", cfg.chart.Table.Border)
+	}
+	if cfg.input != "" && cfg.input != "prometheus" {
+		return cfg, fmt.Errorf("unknown input format %q", cfg.input)
+	}
+	if _, ok := promAggregates[cfg.prom.Agg]; !ok {
+		return cfg, fmt.Errorf("unknown aggregation %q", cfg.prom.Agg)
+	}
+	cfg.prom.By = splitList(*promBy)
+	cfg.columns.Y = splitList(*yColumns)
+	if cfg.sql.Query != "" && cfg.sql.DB == "" {
+		return cfg, fmt.Errorf("-sql needs a -db")
+	}
+
+	cfg.chart.Table.Bars = splitList(*barColumns)
+	cfg.chart.Table.Heat = splitList(*heatColumns)
 
-	flag.Parse()
+	for _, item := range splitList(*buckets) {
+		bound, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid heatmap bucket %q", item)
+		}
+		cfg.chart.Heatmap.Buckets = append(cfg.chart.Heatmap.Buckets, bound)
+	}
 
-	return cfg
+	return cfg, nil
 }
 
-func readData(path string) ([]byte, error) {
-	if path == "" || path == "-" {
-		return io.ReadAll(os.Stdin)
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return os.ReadFile(path)
+	return items
+}
+
+// loadData reads the chart data: the result of the -sql query, or else
+// the -data file.
+func loadData(cfg Config) ([]byte, error) {
+	if cfg.sql.Query != "" {
+		return querySQL(cfg.sql, cfg.fetch.Timeout)
+	}
+	return readData(cfg.dataFile, cfg.fetch)
+}
+
+// readData reads the data from stdin, a file or a URL, then applies any
+// -select expression.
+func readData(path string, fetch fetchOptions) ([]byte, error) {
+	var data []byte
+	var err error
+	switch {
+	case path == "" || path == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case isURL(path):
+		data, err = fetchData(path, fetch)
+	default:
+		data, err = os.ReadFile(path)
+	}
+	if err != nil || fetch.Select == "" {
+		return data, err
+	}
+	return selectData(data, fetch.Select)
+}
+
+// themes caches design tokens by theme name. Renderers only read the
+// tokens, so one set serves every chart drawn in a batch or by the server.
+var themes struct {
+	sync.Mutex
+	tokens map[string]*design.DesignTokens
 }
 
 func getTheme(name string) *design.DesignTokens {
+	themes.Lock()
+	defer themes.Unlock()
+	if tokens, ok := themes.tokens[name]; ok {
+		return tokens
+	}
+	if themes.tokens == nil {
+		themes.tokens = make(map[string]*design.DesignTokens)
+	}
+	tokens := newTheme(name)
+	themes.tokens[name] = tokens
+	return tokens
+}
+
+func newTheme(name string) *design.DesignTokens {
 	switch name {
 	case "midnight":
 		return design.MidnightTheme()
@@ -143,55 +655,157 @@ func getTheme(name string) *design.DesignTokens {
 	}
 }
 
-func renderSVG(vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+func renderSVG(vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig, opts renderOptions) (dataviz.Output, error) {
 	renderer := dataviz.NewSVGRenderer()
-	return renderVisualization(renderer, vizType, data, bounds, config)
+	return renderVisualization(renderer, vizType, data, bounds, config, opts)
 }
 
-func renderTerminal(vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+func renderTerminal(vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig, opts renderOptions) (dataviz.Output, error) {
 	renderer := dataviz.NewTerminalRenderer()
-	return renderVisualization(renderer, vizType, data, bounds, config)
+	return renderVisualization(renderer, vizType, data, bounds, config, opts)
 }
 
-func renderVisualization(r dataviz.Renderer, vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig) dataviz.Output {
+func renderVisualization(r dataviz.Renderer, vizType string, data []byte, bounds dataviz.Bounds, config dataviz.RenderConfig, opts renderOptions) (dataviz.Output, error) {
 	switch vizType {
 	case "heatmap":
-		var heatmapData dataviz.HeatmapData
+		var heatmapData heatmapInput
 		if err := json.Unmarshal(data, &heatmapData); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing heatmap data: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("parsing heatmap data: %w", err)
+		}
+		if err := heatmapData.apply(opts.Heatmap, opts.Axes, opts.Annotations); err != nil {
+			return nil, err
 		}
-		return r.RenderHeatmap(heatmapData, bounds, config)
+		if !heatmapData.fitsDataviz() || opts.enabled() {
+			return renderChart(r, heatmapData, bounds, config, opts.chartOptions), nil
+		}
+		return r.RenderHeatmap(heatmapData.toDataviz(), bounds, config), nil
 
 	case "line-graph":
-		var lineData dataviz.LineGraphData
+		var lineData lineGraphInput
 		if err := json.Unmarshal(data, &lineData); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing line graph data: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("parsing line graph data: %w", err)
+		}
+		lineData.Annotations = append(lineData.Annotations, opts.Annotations...)
+		if !lineData.fitsDataviz() || opts.enabled() {
+			return renderChart(r, lineData, bounds, config, opts.chartOptions), nil
 		}
-		return r.RenderLineGraph(lineData, bounds, config)
+		graph := lineData.toDataviz()
+		if err := svgSafe(r, []*string{&graph.GradientID}, graph.Color, graph.FillColor); err != nil {
+			return nil, err
+		}
+		return r.RenderLineGraph(graph, bounds, config), nil
 
 	case "bar-chart":
-		var barData dataviz.BarChartData
+		var barData barChartInput
 		if err := json.Unmarshal(data, &barData); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing bar chart data: %v\n", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("parsing bar chart data: %w", err)
+		}
+		if err := barData.apply(opts.BarChart); err != nil {
+			return nil, err
+		}
+		if !barData.fitsDataviz() || opts.enabled() {
+			return renderChart(r, barData, bounds, config, opts.chartOptions), nil
 		}
-		return r.RenderBarChart(barData, bounds, config)
+		bars := barData.toDataviz()
+		if err := svgSafe(r, nil, bars.Color); err != nil {
+			return nil, err
+		}
+		return r.RenderBarChart(bars, bounds, config), nil
 
 	case "stat-card":
-		var statData dataviz.StatCardData
-		if err := json.Unmarshal(data, &statData); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing stat card data: %v\n", err)
-			os.Exit(1)
+		var statInput statCardInput
+		if err := json.Unmarshal(data, &statInput); err != nil {
+			return nil, fmt.Errorf("parsing stat card data: %w", err)
+		}
+		statData, err := statInput.resolve(opts.Numbers)
+		if err != nil {
+			return nil, fmt.Errorf("parsing stat card data: %w", err)
+		}
+		texts := []*string{&statData.Title, &statData.Value, &statData.Subtitle, &statData.Legend1, &statData.Legend2}
+		if err := svgSafe(r, texts, statData.Color, statData.TrendColor, statData.TrendColor2); err != nil {
+			return nil, err
+		}
+		return r.RenderStatCard(statData, bounds, config), nil
+
+	case "scatter":
+		var scatterData scatterInput
+		if err := json.Unmarshal(data, &scatterData); err != nil {
+			return nil, fmt.Errorf("parsing scatter data: %w", err)
+		}
+		return renderChart(r, scatterData, bounds, config, opts.chartOptions), nil
+
+	case "area":
+		var areaData areaChartInput
+		if err := json.Unmarshal(data, &areaData); err != nil {
+			return nil, fmt.Errorf("parsing area chart data: %w", err)
+		}
+		return renderChart(r, areaData, bounds, config, opts.chartOptions), nil
+
+	case "histogram":
+		var histogramData histogramInput
+		if err := json.Unmarshal(data, &histogramData); err != nil {
+			return nil, fmt.Errorf("parsing histogram data: %w", err)
+		}
+		return renderChart(r, histogramData, bounds, config, opts.chartOptions), nil
+
+	case "sparkline":
+		var sparkData sparklineInput
+		if err := json.Unmarshal(data, &sparkData); err != nil {
+			return nil, fmt.Errorf("parsing sparkline data: %w", err)
 		}
-		return r.RenderStatCard(statData, bounds, config)
+		sparkData.apply(opts.Spark)
+		return renderChart(r, sparkData, bounds, config, opts.chartOptions), nil
+
+	case "table":
+		tableData, err := parseTable(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing table data: %w", err)
+		}
+		if err := tableData.apply(opts.Table); err != nil {
+			return nil, err
+		}
+		return renderChart(r, tableData, bounds, config, opts.chartOptions), nil
+
+	case "pie", "donut":
+		var pieData pieInput
+		if err := json.Unmarshal(data, &pieData); err != nil {
+			return nil, fmt.Errorf("parsing pie chart data: %w", err)
+		}
+		if vizType == "donut" {
+			pieData.Donut = true
+		}
+		return renderChart(r, pieData, bounds, config, opts.chartOptions), nil
+
+	case "gauge", "progress", "bullet":
+		var gaugeData gaugeInput
+		if err := json.Unmarshal(data, &gaugeData); err != nil {
+			return nil, fmt.Errorf("parsing gauge data: %w", err)
+		}
+		gaugeData.Style = vizType
+		return renderChart(r, gaugeData, bounds, config, opts.chartOptions), nil
 
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown visualization type: %s\n", vizType)
-		os.Exit(1)
+		return nil, fmt.Errorf("unknown visualization type: %s", vizType)
+	}
+}
+
+// svgSafe prepares data for the dataviz SVG renderer, which writes text and
+// colors into the document as given. The texts are escaped in place, and
+// colors other than plain color values are refused. Other renderers take
+// the data as it is.
+func svgSafe(r dataviz.Renderer, texts []*string, colors ...string) error {
+	if _, ok := r.(*dataviz.SVGRenderer); !ok {
 		return nil
 	}
+	for _, c := range colors {
+		if c != "" && !isColorValue(c) {
+			return fmt.Errorf("invalid color %q (want e.g. #3B82F6 or steelblue)", c)
+		}
+	}
+	for _, t := range texts {
+		*t = html.EscapeString(*t)
+	}
+	return nil
 }
 
 // Helper to create sample data for testing
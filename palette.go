@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// themePalettes holds the categorical colors used when a chart has several
+// series and the data does not specify a color for each one.
+var themePalettes = map[string][]string{
+	"default":  {"#3B82F6", "#F59E0B", "#10B981", "#EF4444", "#8B5CF6", "#EC4899", "#14B8A6", "#F97316"},
+	"midnight": {"#7D56F4", "#22D3EE", "#F472B6", "#A3E635", "#FBBF24", "#60A5FA", "#F87171", "#34D399"},
+	"nord":     {"#88C0D0", "#BF616A", "#A3BE8C", "#EBCB8B", "#B48EAD", "#D08770", "#81A1C1", "#5E81AC"},
+	"paper":    {"#1E40AF", "#B45309", "#047857", "#B91C1C", "#6D28D9", "#BE185D", "#0F766E", "#C2410C"},
+	"wrapped":  {"#1DB954", "#FF6437", "#F037A5", "#509BF5", "#FFC864", "#AF2896", "#19E68C", "#CDF564"},
+}
+
+// statusPalettes holds the good, warn and critical colors of each theme for
+// charts that show a value against thresholds.
+var statusPalettes = map[string]map[string]string{
+	"default":  {"good": "#10B981", "warn": "#F59E0B", "critical": "#EF4444"},
+	"midnight": {"good": "#34D399", "warn": "#FBBF24", "critical": "#F87171"},
+	"nord":     {"good": "#A3BE8C", "warn": "#EBCB8B", "critical": "#BF616A"},
+	"paper":    {"good": "#047857", "warn": "#B45309", "critical": "#B91C1C"},
+	"wrapped":  {"good": "#1DB954", "warn": "#FFC864", "critical": "#F037A5"},
+}
+
+// statusColor returns the theme color for a status: "good", "warn" or
+// "critical".
+func statusColor(theme, status string) string {
+	palette, ok := statusPalettes[theme]
+	if !ok {
+		palette = statusPalettes["default"]
+	}
+	return palette[status]
+}
+
+// paletteColor returns the i-th series color for a theme, cycling through
+// the palette when there are more series than colors.
+func paletteColor(theme string, i int) string {
+	palette, ok := themePalettes[theme]
+	if !ok {
+		palette = themePalettes["default"]
+	}
+	return palette[i%len(palette)]
+}
+
+// themeColors returns the background and text colors of the configured
+// theme for charts that draw their own SVG.
+func themeColors(config dataviz.RenderConfig) (background, text string) {
+	if config.DesignTokens == nil {
+		return "", "#374151"
+	}
+	return config.DesignTokens.Background, config.DesignTokens.Color
+}
+
+// mixColors blends hex color a toward b by t, from 0 (a) to 1 (b). An
+// unparseable color is treated as the other one.
+func mixColors(a, b string, t float64) string {
+	ar, ag, ab, okA := parseHex(a)
+	br, bg, bb, okB := parseHex(b)
+	switch {
+	case !okA && !okB:
+		return ""
+	case !okA:
+		return b
+	case !okB:
+		return a
+	}
+	mix := func(x, y int) int { return int(math.Round(float64(x) + (float64(y)-float64(x))*t)) }
+	return fmt.Sprintf("#%02X%02X%02X", mix(ar, br), mix(ag, bg), mix(ab, bb))
+}
+
+// contrastText returns black or white, whichever is easier to read on the
+// given background.
+func contrastText(background string) string {
+	r, g, b, ok := parseHex(background)
+	if !ok {
+		return ""
+	}
+	// Relative luminance with the Rec. 709 weights, without gamma.
+	if 0.2126*float64(r)+0.7152*float64(g)+0.0722*float64(b) > 140 {
+		return "#000000"
+	}
+	return "#FFFFFF"
+}
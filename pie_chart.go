@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// quadrantGlyphs are the block glyphs for each combination of filled
+// quarters of a cell, indexed by a mask of upper left (1), upper right (2),
+// lower left (4) and lower right (8).
+var quadrantGlyphs = []rune(" ▘▝▀▖▌▞▛▗▚▐▜▄▙▟█")
+
+// pieSlice is one slice of a pie or donut chart.
+type pieSlice struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Color string  `json:"color"`
+}
+
+// pieInput is the JSON accepted by -type pie and -type donut. InnerRadius
+// is the size of a donut's hole as a fraction of its radius.
+type pieInput struct {
+	Slices      []pieSlice `json:"slices"`
+	Donut       bool       `json:"donut"`
+	InnerRadius float64    `json:"innerRadius"`
+}
+
+// pieWedge is a slice resolved to its angles, measured clockwise from the
+// top in radians.
+type pieWedge struct {
+	pieSlice
+	From, To float64
+	Share    float64
+}
+
+// wedges lays out the slices around the circle. Negative values are
+// treated as zero. It returns nil when there is nothing to draw.
+func (in pieInput) wedges(config dataviz.RenderConfig) []pieWedge {
+	total := in.total()
+	if total <= 0 {
+		return nil
+	}
+	wedges := make([]pieWedge, len(in.Slices))
+	angle := 0.0
+	for i, s := range in.Slices {
+		if s.Color == "" {
+			s.Color = paletteColor(config.Theme, i)
+		}
+		share := math.Max(0, s.Value) / total
+		wedges[i] = pieWedge{pieSlice: s, From: angle, To: angle + share*2*math.Pi, Share: share}
+		angle = wedges[i].To
+	}
+	return wedges
+}
+
+// hole returns the inner radius as a fraction of the outer radius.
+func (in pieInput) hole() float64 {
+	if !in.Donut {
+		return 0
+	}
+	if in.InnerRadius <= 0 || in.InnerRadius >= 1 {
+		return 0.55
+	}
+	return in.InnerRadius
+}
+
+// total returns the sum of the drawn slice values.
+func (in pieInput) total() float64 {
+	total := 0.0
+	for _, s := range in.Slices {
+		total += math.Max(0, s.Value)
+	}
+	return total
+}
+
+// wedgeAt returns the color of the wedge under a point at distance r (as a
+// fraction of the radius) and the given angle, or "" outside the ring.
+func wedgeAt(wedges []pieWedge, hole, r, angle float64) string {
+	if r > 1 || r < hole {
+		return ""
+	}
+	for _, w := range wedges {
+		if angle >= w.From && angle < w.To {
+			return w.Color
+		}
+	}
+	return wedges[len(wedges)-1].Color
+}
+
+// pieLegend describes each slice with its value and share.
+func pieLegend(wedges []pieWedge, opts chartOptions) []legendEntry {
+	percent := numberFormat{Kind: "percent", Decimals: 1, Locale: opts.Numbers.Locale}
+	entries := make([]legendEntry, len(wedges))
+	for i, w := range wedges {
+		label := w.Label
+		if label == "" {
+			label = fmt.Sprintf("Slice %d", i+1)
+		}
+		entries[i] = legendEntry{
+			Label: fmt.Sprintf("%s %s (%s)", label, opts.Numbers.Format(w.Value), percent.Format(w.Share)),
+			Color: w.Color,
+		}
+	}
+	return entries
+}
+
+// Terminal implements chart. The circle is drawn with quadrant glyphs, each
+// cell showing up to two slice colors as foreground and background. Cells
+// are about twice as tall as they are wide, so a quarter cell is treated as
+// half a unit wide and one unit tall. The legend goes to the right of the
+// chart when it fits, otherwise below it.
+func (in pieInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	wedges := in.wedges(config)
+	if wedges == nil {
+		return ""
+	}
+	legend := pieLegend(wedges, opts)
+	legendWidth := 0
+	for _, e := range legend {
+		legendWidth = max(legendWidth, 2+utf8.RuneCountInString(e.Label))
+	}
+
+	// The chart is rows tall and 2*rows wide; with the legend beside it,
+	// both have to fit in the width.
+	rows := min(bounds.Height, (bounds.Width-legendWidth-2)/2)
+	beside := rows >= 4
+	if !beside {
+		rows = min(bounds.Height-len(legend), bounds.Width/2)
+	}
+	if rows < 1 {
+		return ""
+	}
+	cols := rows * 2
+	radius := float64(rows)
+	hole := in.hole()
+
+	lines := make([]string, rows)
+	for row := range lines {
+		var b strings.Builder
+		for col := 0; col < cols; col++ {
+			var colors [4]string
+			for q := range colors {
+				// Centre of the quarter cell relative to the chart centre.
+				x := float64(col) + 0.25 + 0.5*float64(q%2) - float64(cols)/2
+				y := float64(row*2) + 0.5 + float64(q/2) - radius
+				r := math.Hypot(x, y) / radius
+				angle := math.Atan2(x, -y)
+				if angle < 0 {
+					angle += 2 * math.Pi
+				}
+				colors[q] = wedgeAt(wedges, hole, r, angle)
+			}
+			b.WriteString(quadrantCell(colors))
+		}
+		lines[row] = b.String()
+	}
+
+	if in.Donut {
+		in.labelHole(lines, cols, radius*hole, opts)
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(line)
+		if beside && i < len(legend) {
+			b.WriteString("  ")
+			b.WriteString(colorize("●", legend[i].Color))
+			b.WriteString(" ")
+			b.WriteString(legend[i].Label)
+		}
+		b.WriteString("\n")
+	}
+	if !beside {
+		for _, e := range legend {
+			b.WriteString(colorize("●", e.Color) + " " + e.Label + "\n")
+		}
+	}
+	return b.String()
+}
+
+// labelHole writes the total into the middle row of a donut when it fits
+// inside the hole. holeRadius is measured in rows.
+func (in pieInput) labelHole(lines []string, cols int, holeRadius float64, opts chartOptions) {
+	text := opts.Numbers.Format(in.total())
+	width := utf8.RuneCountInString(text)
+	// The hole spans 2*holeRadius columns; keep a column clear on each side.
+	if float64(width) > 2*holeRadius-2 || len(lines) < 3 {
+		return
+	}
+	row := len(lines) / 2
+	pad := (cols - width) / 2
+	lines[row] = cutCells(lines[row], 0, pad) + text + cutCells(lines[row], pad+width, cols)
+}
+
+// cutCells returns the cells from..to of a rendered line in which every
+// cell is a single glyph, optionally wrapped in escape sequences that end
+// with a reset.
+func cutCells(line string, from, to int) string {
+	var b strings.Builder
+	cell := 0
+	for len(line) > 0 && cell < to {
+		end := strings.Index(line, ansiReset)
+		var glyph string
+		if strings.HasPrefix(line, "\x1b") && end >= 0 {
+			glyph, line = line[:end+len(ansiReset)], line[end+len(ansiReset):]
+		} else {
+			_, size := utf8.DecodeRuneInString(line)
+			glyph, line = line[:size], line[size:]
+		}
+		if cell >= from {
+			b.WriteString(glyph)
+		}
+		cell++
+	}
+	return b.String()
+}
+
+// quadrantCell renders one cell from the colors of its four quarters. The
+// most common color becomes the glyph's foreground and the next most common
+// its background; any further colors take the background color.
+func quadrantCell(colors [4]string) string {
+	counts := make(map[string]int, 4)
+	for _, c := range colors {
+		counts[c]++
+	}
+	fg, bg := "", ""
+	for _, c := range colors {
+		if c != "" && (fg == "" || counts[c] > counts[fg]) {
+			fg = c
+		}
+	}
+	if fg == "" {
+		return " "
+	}
+	found := false
+	for _, c := range colors {
+		if c != fg && (!found || counts[c] > counts[bg]) {
+			bg, found = c, true
+		}
+	}
+
+	mask := 0
+	for q, c := range colors {
+		if c == fg {
+			mask |= 1 << q
+		}
+	}
+	glyph := string(quadrantGlyphs[mask])
+	if bg == "" || mask == 15 {
+		return colorize(glyph, fg)
+	}
+	return ansiColor(fg) + ansiBackground(bg) + glyph + ansiReset
+}
+
+// SVG implements chart. Slices are polygons that follow the arc in small
+// steps, with the legend listed to the right of the circle.
+func (in pieInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	wedges := in.wedges(config)
+	if wedges == nil {
+		return doc.String()
+	}
+	legend := pieLegend(wedges, opts)
+	const fontSize = 11.0
+	legendWidth := 0.0
+	for _, e := range legend {
+		legendWidth = math.Max(legendWidth, 14+float64(utf8.RuneCountInString(e.Label))*fontSize*0.6)
+	}
+
+	radius := math.Min(float64(bounds.Height)-2*svgPadding, float64(bounds.Width)-3*svgPadding-legendWidth) / 2
+	radius = math.Max(radius, 10)
+	cx, cy := svgPadding+radius, float64(bounds.Height)/2
+	hole := in.hole()
+
+	arc := func(from, to, r float64) []svgPoint {
+		steps := max(1, int(math.Ceil((to-from)/(2*math.Pi/180))))
+		points := make([]svgPoint, 0, steps+1)
+		for i := 0; i <= steps; i++ {
+			a := from + (to-from)*float64(i)/float64(steps)
+			points = append(points, svgPoint{X: cx + r*math.Sin(a), Y: cy - r*math.Cos(a)})
+		}
+		return points
+	}
+	for i, w := range wedges {
+		if w.To <= w.From {
+			continue
+		}
+		points := arc(w.From, w.To, radius)
+		if hole > 0 {
+			inner := arc(w.From, w.To, radius*hole)
+			for j := len(inner) - 1; j >= 0; j-- {
+				points = append(points, inner[j])
+			}
+		} else {
+			points = append(points, svgPoint{X: cx, Y: cy})
+		}
+		doc.Add(svgPolygon{Points: points, Fill: w.Color, Title: legend[i].Label})
+	}
+	if hole > 0 {
+		doc.Add(svgText{X: cx, Y: cy + 6, Text: opts.Numbers.Format(in.total()), Fill: textColor, Size: 16, Anchor: "middle"})
+	}
+
+	x := cx + radius + svgPadding
+	y := cy - float64(len(legend)-1)*9
+	for _, e := range legend {
+		doc.Add(
+			svgRect{X: x, Y: y - 9, W: 10, H: 10, Fill: e.Color, Radius: 2},
+			svgText{X: x + 14, Y: y, Text: e.Label, Fill: textColor, Size: fontSize},
+		)
+		y += 18
+	}
+	return doc.String()
+}
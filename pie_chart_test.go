@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func TestPieWedges(t *testing.T) {
+	in := pieInput{Slices: []pieSlice{{Label: "a", Value: 3}, {Label: "b", Value: -2}, {Label: "c", Value: 1}}}
+	wedges := in.wedges(dataviz.RenderConfig{Theme: "default"})
+	wantShares := []float64{0.75, 0, 0.25}
+	for i, w := range wedges {
+		if w.Share != wantShares[i] {
+			t.Errorf("wedge %s share = %v, want %v", w.Label, w.Share, wantShares[i])
+		}
+		if w.Color != paletteColor("default", i) {
+			t.Errorf("wedge %s color = %q, want palette color %d", w.Label, w.Color, i)
+		}
+	}
+	if end := wedges[len(wedges)-1].To; math.Abs(end-2*math.Pi) > 1e-9 {
+		t.Errorf("wedges end at %v, want 2π", end)
+	}
+	if got := wedgeAt(wedges, 0.5, 0.25, 0); got != "" {
+		t.Errorf("wedgeAt inside the hole = %q, want none", got)
+	}
+	if got := wedgeAt(wedges, 0.5, 0.75, math.Pi); got != wedges[0].Color {
+		t.Errorf("wedgeAt(π) = %q, want the first wedge's %q", got, wedges[0].Color)
+	}
+	if (pieInput{Slices: []pieSlice{{Value: -1}}}).wedges(dataviz.RenderConfig{}) != nil {
+		t.Error("wedges drawn for a pie with nothing to show")
+	}
+}
@@ -0,0 +1,589 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promOptions selects and aggregates Prometheus series. Metric is a
+// selector such as `http_requests_total{method="GET",code=~"5.."}`; By
+// lists the labels to group series by, and Agg combines the series in a
+// group: sum, avg, min, max or count.
+type promOptions struct {
+	Metric string
+	By     []string
+	Agg    string
+}
+
+// promSample is one value of a series.
+type promSample struct {
+	Time  time.Time
+	Value float64
+}
+
+// promSeries is one labelled time series. Name is empty when the source
+// does not say, as in query results for expressions.
+type promSeries struct {
+	Name    string
+	Labels  map[string]string
+	Samples []promSample
+}
+
+// last returns the newest sample.
+func (s promSeries) last() promSample {
+	newest := s.Samples[0]
+	for _, sample := range s.Samples[1:] {
+		if sample.Time.After(newest.Time) {
+			newest = sample
+		}
+	}
+	return newest
+}
+
+// promAggregates are the functions -agg accepts.
+var promAggregates = map[string]func([]float64) float64{
+	"sum": func(v []float64) float64 {
+		total := 0.0
+		for _, x := range v {
+			total += x
+		}
+		return total
+	},
+	"avg": func(v []float64) float64 {
+		total := 0.0
+		for _, x := range v {
+			total += x
+		}
+		return total / float64(len(v))
+	},
+	"min": func(v []float64) float64 {
+		lo := math.Inf(1)
+		for _, x := range v {
+			lo = math.Min(lo, x)
+		}
+		return lo
+	},
+	"max": func(v []float64) float64 {
+		hi := math.Inf(-1)
+		for _, x := range v {
+			hi = math.Max(hi, x)
+		}
+		return hi
+	},
+	"count": func(v []float64) float64 { return float64(len(v)) },
+}
+
+// prometheusData converts Prometheus data, either the text exposition
+// format (including OpenMetrics) or a query API response, into the JSON
+// input of the chart type.
+func prometheusData(data []byte, vizType string, opts promOptions) ([]byte, error) {
+	var series []promSeries
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		series, err = parsePromQueryResult(trimmed)
+	} else {
+		series, err = parsePromText(data, time.Now())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := parsePromSelector(opts.Metric)
+	if err != nil {
+		return nil, err
+	}
+	var selected []promSeries
+	for _, s := range series {
+		if len(s.Samples) > 0 && selector.matches(s) {
+			selected = append(selected, s)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no Prometheus series match %q", opts.Metric)
+	}
+
+	combine, ok := promAggregates[opts.Agg]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregation %q (want sum, avg, min, max or count)", opts.Agg)
+	}
+	groups := groupPromSeries(selected, opts.By)
+
+	switch vizType {
+	case "bar-chart":
+		return promBars(groups, combine)
+	case "line-graph", "area", "sparkline":
+		return promLines(groups, combine)
+	case "stat-card":
+		return promStat(selected, opts, combine)
+	case "table":
+		return promTable(groups, opts.By, combine)
+	}
+	return nil, fmt.Errorf("prometheus input draws bar-chart, line-graph, area, sparkline, stat-card and table, not %s", vizType)
+}
+
+// promGroup is the series sharing the values of the -by labels.
+type promGroup struct {
+	Label  string
+	Values []string
+	Series []promSeries
+}
+
+// groupPromSeries groups series by the given labels, or keeps every series
+// apart when there are none. Groups are ordered by label.
+func groupPromSeries(series []promSeries, by []string) []promGroup {
+	index := map[string]int{}
+	var groups []promGroup
+	for _, s := range series {
+		var label string
+		var values []string
+		if len(by) > 0 {
+			for _, name := range by {
+				values = append(values, s.Labels[name])
+			}
+			label = strings.Join(values, "/")
+		} else {
+			label = promSeriesLabel(s)
+		}
+		i, ok := index[label]
+		if !ok {
+			i = len(groups)
+			index[label] = i
+			groups = append(groups, promGroup{Label: label, Values: values})
+		}
+		groups[i].Series = append(groups[i].Series, s)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Label < groups[j].Label })
+	return groups
+}
+
+// promSeriesLabel names a series as Prometheus writes it: the metric name
+// followed by the labels, e.g. up{job="api"}.
+func promSeriesLabel(s promSeries) string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, s.Labels[name])
+	}
+	if len(parts) == 0 {
+		return s.Name
+	}
+	return s.Name + "{" + strings.Join(parts, ",") + "}"
+}
+
+// latest combines the newest sample of each series in the group.
+func (g promGroup) latest(combine func([]float64) float64) float64 {
+	values := make([]float64, len(g.Series))
+	for i, s := range g.Series {
+		values[i] = s.last().Value
+	}
+	return combine(values)
+}
+
+// points combines the series of the group at each timestamp.
+func (g promGroup) points(combine func([]float64) float64) []timePoint {
+	byTime := map[time.Time][]float64{}
+	for _, s := range g.Series {
+		for _, sample := range s.Samples {
+			byTime[sample.Time] = append(byTime[sample.Time], sample.Value)
+		}
+	}
+	points := make([]timePoint, 0, len(byTime))
+	for t, values := range byTime {
+		points = append(points, timePoint{Date: t, Value: combine(values)})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	return points
+}
+
+// promBars draws the current value of each group, largest first.
+func promBars(groups []promGroup, combine func([]float64) float64) ([]byte, error) {
+	bars := make([]barValue, len(groups))
+	for i, g := range groups {
+		bars[i] = barValue{Label: g.Label, Value: g.latest(combine)}
+	}
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Value > bars[j].Value })
+	return json.Marshal(map[string]any{"bars": bars})
+}
+
+// promLines draws each group over time.
+func promLines(groups []promGroup, combine func([]float64) float64) ([]byte, error) {
+	series := make([]lineSeries, len(groups))
+	for i, g := range groups {
+		series[i] = lineSeries{Label: g.Label, Points: g.points(combine)}
+	}
+	if len(series) == 1 {
+		return json.Marshal(map[string]any{"points": series[0].Points})
+	}
+	return json.Marshal(map[string]any{"series": series})
+}
+
+// promStat combines every selected series into one number, with their
+// combined history as the card's trend.
+func promStat(series []promSeries, opts promOptions, combine func([]float64) float64) ([]byte, error) {
+	all := promGroup{Series: series}
+	title := opts.Metric
+	if title == "" {
+		title = series[0].Name
+	}
+	card := map[string]any{
+		"title":    title,
+		"value":    all.latest(combine),
+		"subtitle": fmt.Sprintf("%s of %d series", opts.Agg, len(series)),
+	}
+	if points := all.points(combine); len(points) > 1 {
+		card["trendData"] = points
+	}
+	return json.Marshal(card)
+}
+
+// promTable lists each group's labels and current value.
+func promTable(groups []promGroup, by []string, combine func([]float64) float64) ([]byte, error) {
+	columns := append([]string{}, by...)
+	if len(by) == 0 {
+		columns = []string{"series"}
+	}
+	columns = append(columns, "value")
+	rows := make([][]any, len(groups))
+	for i, g := range groups {
+		var row []any
+		if len(by) == 0 {
+			row = append(row, g.Label)
+		}
+		for _, v := range g.Values {
+			row = append(row, v)
+		}
+		rows[i] = append(row, g.latest(combine))
+	}
+	return json.Marshal(map[string]any{"columns": columns, "rows": rows})
+}
+
+// parsePromText reads the Prometheus text exposition format or
+// OpenMetrics. Samples without a timestamp are taken at now. NaN and
+// infinite values are dropped, since no chart can draw them.
+func parsePromText(data []byte, now time.Time) ([]promSeries, error) {
+	var series []promSeries
+	index := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, sample, err := parsePromSample(line, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			continue
+		}
+		key := promSeriesLabel(s)
+		i, ok := index[key]
+		if !ok {
+			i = len(series)
+			index[key] = i
+			series = append(series, s)
+		}
+		series[i].Samples = append(series[i].Samples, sample)
+	}
+	return series, scanner.Err()
+}
+
+// parsePromSample reads one sample line: name{labels} value [timestamp],
+// optionally followed by an OpenMetrics exemplar.
+func parsePromSample(line string, now time.Time) (promSeries, promSample, error) {
+	s := promSeries{Labels: map[string]string{}}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return s, promSample{}, fmt.Errorf("invalid sample %q", line)
+	}
+	s.Name = line[:end]
+	rest := line[end:]
+	if strings.HasPrefix(rest, "{") {
+		labels, after, err := parsePromLabels(rest[1:])
+		if err != nil {
+			return s, promSample{}, err
+		}
+		s.Labels, rest = labels, after
+	}
+	if exemplar := strings.Index(rest, " # "); exemplar >= 0 {
+		rest = rest[:exemplar]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 || len(fields) > 2 {
+		return s, promSample{}, fmt.Errorf("invalid sample %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return s, promSample{}, fmt.Errorf("invalid value %q", fields[0])
+	}
+	sample := promSample{Time: now, Value: value}
+	if len(fields) == 2 {
+		ts, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return s, promSample{}, fmt.Errorf("invalid timestamp %q", fields[1])
+		}
+		sample.Time = promTime(ts, strings.Contains(fields[1], "."))
+	}
+	return s, sample, nil
+}
+
+// promTime converts an exposition timestamp. Prometheus writes whole
+// milliseconds; OpenMetrics writes seconds, often with a fraction.
+func promTime(ts float64, fractional bool) time.Time {
+	if fractional || ts < 1e11 {
+		return time.Unix(0, int64(ts*1e9))
+	}
+	return time.UnixMilli(int64(ts))
+}
+
+// parsePromLabels reads label pairs up to the closing brace and returns
+// the text after it.
+func parsePromLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			return labels, s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, "", fmt.Errorf("invalid labels near %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		value, rest, err := unquotePromValue(s[eq+1:])
+		if err != nil {
+			return nil, "", err
+		}
+		labels[name] = value
+		s = rest
+	}
+}
+
+// unquotePromValue reads a double-quoted label value with \\, \" and \n
+// escapes from the start of s.
+func unquotePromValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("unterminated label value %s", s)
+}
+
+// promQueryResult is a Prometheus HTTP API query response.
+type promQueryResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// promRawSeries is one series of a query response: Value holds the sample
+// of an instant query and Values those of a range query.
+type promRawSeries struct {
+	Metric map[string]string `json:"metric"`
+	Value  []any             `json:"value"`
+	Values [][]any           `json:"values"`
+}
+
+// parsePromQueryResult reads an instant (vector) or range (matrix) query
+// response from /api/v1/query or /api/v1/query_range.
+func parsePromQueryResult(data []byte) ([]promSeries, error) {
+	var resp promQueryResult
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parsing Prometheus response: %w", err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("Prometheus query failed: %s", resp.Error)
+	}
+
+	var raw []promRawSeries
+	switch resp.Data.ResultType {
+	case "vector", "matrix":
+		if err := json.Unmarshal(resp.Data.Result, &raw); err != nil {
+			return nil, fmt.Errorf("parsing Prometheus %s: %w", resp.Data.ResultType, err)
+		}
+	case "scalar":
+		var value []any
+		if err := json.Unmarshal(resp.Data.Result, &value); err != nil {
+			return nil, fmt.Errorf("parsing Prometheus scalar: %w", err)
+		}
+		raw = append(raw, promRawSeries{Value: value})
+	default:
+		return nil, fmt.Errorf("unsupported Prometheus result type %q", resp.Data.ResultType)
+	}
+
+	series := make([]promSeries, 0, len(raw))
+	for _, r := range raw {
+		s := promSeries{Name: r.Metric["__name__"], Labels: map[string]string{}}
+		for name, value := range r.Metric {
+			if name != "__name__" {
+				s.Labels[name] = value
+			}
+		}
+		values := r.Values
+		if r.Value != nil {
+			values = append(values, r.Value)
+		}
+		for _, v := range values {
+			sample, err := parsePromPair(v)
+			if err != nil {
+				return nil, err
+			}
+			if !math.IsNaN(sample.Value) && !math.IsInf(sample.Value, 0) {
+				s.Samples = append(s.Samples, sample)
+			}
+		}
+		series = append(series, s)
+	}
+	return series, nil
+}
+
+// parsePromPair reads a [unix seconds, "value"] pair.
+func parsePromPair(pair []any) (promSample, error) {
+	if len(pair) != 2 {
+		return promSample{}, fmt.Errorf("invalid Prometheus sample %v", pair)
+	}
+	ts, ok := pair[0].(float64)
+	text, ok2 := pair[1].(string)
+	if !ok || !ok2 {
+		return promSample{}, fmt.Errorf("invalid Prometheus sample %v", pair)
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return promSample{}, fmt.Errorf("invalid Prometheus value %q", text)
+	}
+	return promSample{Time: time.Unix(0, int64(ts*1e9)), Value: value}, nil
+}
+
+// promSelector is a parsed -metric selector.
+type promSelector struct {
+	name     string
+	matchers []promMatcher
+}
+
+// promMatcher is one label matcher: =, !=, =~ or !~.
+type promMatcher struct {
+	label string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+var promMatcherPattern = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parsePromSelector reads a selector such as `up{job="api"}`. The metric
+// name and the braces are both optional; an empty selector matches all.
+func parsePromSelector(s string) (promSelector, error) {
+	var sel promSelector
+	s = strings.TrimSpace(s)
+	name, rest, hasLabels := strings.Cut(s, "{")
+	sel.name = strings.TrimSpace(name)
+	if !hasLabels {
+		return sel, nil
+	}
+	body, ok := strings.CutSuffix(strings.TrimSpace(rest), "}")
+	if !ok {
+		return sel, fmt.Errorf("invalid selector %q: missing }", s)
+	}
+	for _, part := range splitPromMatchers(body) {
+		m := promMatcherPattern.FindStringSubmatch(part)
+		if m == nil {
+			return sel, fmt.Errorf("invalid label matcher %q", strings.TrimSpace(part))
+		}
+		value, err := strconv.Unquote(`"` + m[3] + `"`)
+		if err != nil {
+			return sel, fmt.Errorf("invalid label matcher %q", strings.TrimSpace(part))
+		}
+		matcher := promMatcher{label: m[1], op: m[2], value: value}
+		if matcher.op == "=~" || matcher.op == "!~" {
+			if matcher.re, err = regexp.Compile("^(?:" + value + ")$"); err != nil {
+				return sel, fmt.Errorf("invalid label matcher %q: %w", strings.TrimSpace(part), err)
+			}
+		}
+		sel.matchers = append(sel.matchers, matcher)
+	}
+	return sel, nil
+}
+
+// splitPromMatchers splits the body of a selector at commas outside
+// quotes.
+func splitPromMatchers(body string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, body[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if last := body[start:]; strings.TrimSpace(last) != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// matches reports whether a series satisfies the selector. Series without
+// a name, as in query results for expressions, match any metric name.
+func (sel promSelector) matches(s promSeries) bool {
+	if sel.name != "" && s.Name != "" && s.Name != sel.name {
+		return false
+	}
+	for _, m := range sel.matchers {
+		value := s.Labels[m.label]
+		var ok bool
+		switch m.op {
+		case "=":
+			ok = value == m.value
+		case "!=":
+			ok = value != m.value
+		case "=~":
+			ok = m.re.MatchString(value)
+		case "!~":
+			ok = !m.re.MatchString(value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// promExposition mixes the Prometheus text format with OpenMetrics
+// timestamps and exemplars.
+const promExposition = `# HELP jobs_total Jobs run.
+# TYPE jobs_total counter
+jobs_total{queue="mail",state="ok"} 12 1700000000000
+jobs_total{queue="mail",state="ok"} 15 1700000060000
+jobs_total{state="failed", queue="mail"} 2
+jobs_total{queue="a \"quoted\"\\path"} 1 1700000000.5
+jobs_total{queue="video"} NaN
+queue_depth 7 # {trace_id="abc"} 1.0
+`
+
+func TestParsePromText(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	series, err := parsePromText([]byte(promExposition), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		label   string
+		samples int
+		last    float64
+		at      time.Time
+	}{
+		{`jobs_total{queue="mail",state="ok"}`, 2, 15, time.UnixMilli(1700000060000)},
+		{`jobs_total{queue="mail",state="failed"}`, 1, 2, now},
+		{`jobs_total{queue="a \"quoted\"\\path"}`, 1, 1, time.Unix(1700000000, 5e8)},
+		{`jobs_total{queue="video"}`, 0, 0, time.Time{}},
+		{`queue_depth`, 1, 7, now},
+	}
+	got := map[string]promSeries{}
+	for _, s := range series {
+		got[promSeriesLabel(s)] = s
+	}
+	for _, tt := range tests {
+		s, ok := got[tt.label]
+		if tt.samples == 0 {
+			if ok {
+				t.Errorf("%s: NaN sample kept", tt.label)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: series missing from %v", tt.label, got)
+			continue
+		}
+		if len(s.Samples) != tt.samples || s.last().Value != tt.last || !s.last().Time.Equal(tt.at) {
+			t.Errorf("%s: %d samples, last %+v, want %d, %v at %v", tt.label, len(s.Samples), s.last(), tt.samples, tt.last, tt.at)
+		}
+	}
+}
+
+func TestParsePromTextErrors(t *testing.T) {
+	for _, text := range []string{
+		"ok 1\n{code=\"200\"} 1\n",
+		"up{job=\"api} 1\n",
+		"up{job=api} 1\n",
+		"up one\n",
+		"up 1 yesterday\n",
+		"up 1 2 3\n",
+	} {
+		if _, err := parsePromText([]byte(text), time.Now()); err == nil {
+			t.Errorf("parsePromText(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestPromSeriesLabel(t *testing.T) {
+	tests := []struct {
+		s    promSeries
+		want string
+	}{
+		{promSeries{Name: "up"}, "up"},
+		{promSeries{Name: "up", Labels: map[string]string{"job": "api", "env": "prod"}}, `up{env="prod",job="api"}`},
+		{promSeries{Labels: map[string]string{"job": "api"}}, `{job="api"}`},
+	}
+	for _, tt := range tests {
+		if got := promSeriesLabel(tt.s); got != tt.want {
+			t.Errorf("promSeriesLabel(%+v) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPromSelector(t *testing.T) {
+	series := promSeries{Name: "http_requests_total", Labels: map[string]string{"code": "503", "path": "/a,b"}}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"http_requests_total", true},
+		{"up", false},
+		{`{code=~"5.."}`, true},
+		{`http_requests_total{code!~"5..|4.."}`, false},
+		{`{code="503", path="/a,b"}`, true},
+		{`{code!="503"}`, false},
+	}
+	for _, tt := range tests {
+		sel, err := parsePromSelector(tt.selector)
+		if err != nil {
+			t.Errorf("parsePromSelector(%q): %v", tt.selector, err)
+			continue
+		}
+		if got := sel.matches(series); got != tt.want {
+			t.Errorf("%s matches = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+	for _, bad := range []string{`up{job="api"`, `{job=api}`, `{job=~"("}`} {
+		if _, err := parsePromSelector(bad); err == nil {
+			t.Errorf("parsePromSelector(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestPrometheusBarsFromExposition(t *testing.T) {
+	data, err := os.ReadFile("examples/metrics.prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := prometheusData(data, "bar-chart", promOptions{Metric: "http_requests_total", By: []string{"code"}, Agg: "sum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct{ Bars []barValue }
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []barValue{{Label: "200", Value: 21761}, {Label: "404", Value: 212}, {Label: "500", Value: 57}, {Label: "503", Value: 19}}
+	if len(got.Bars) != len(want) {
+		t.Fatalf("bars = %+v, want %+v", got.Bars, want)
+	}
+	for i := range want {
+		if got.Bars[i].Label != want[i].Label || got.Bars[i].Value != want[i].Value {
+			t.Errorf("bar %d = %+v, want %+v", i, got.Bars[i], want[i])
+		}
+	}
+}
+
+func TestPrometheusQueryAPI(t *testing.T) {
+	responses := map[string]string{
+		"/api/v1/query_range": `{"status": "success", "data": {"resultType": "matrix", "result": [
+			{"metric": {"instance": "b"}, "values": [[1700000000, "1"], [1700000060, "3"]]},
+			{"metric": {"instance": "a"}, "values": [[1700000000, "2"], [1700000060, "NaN"]]}]}}`,
+		"/api/v1/query": `{"status": "success", "data": {"resultType": "vector", "result": [
+			{"metric": {"__name__": "up", "job": "api"}, "value": [1700000000.5, "1"]}]}}`,
+		"/api/v1/bad": `{"status": "error", "error": "parse error"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(responses[r.URL.Path]))
+	}))
+	defer srv.Close()
+
+	data, err := readData(srv.URL+"/api/v1/query_range?query=rate(errors_total[5m])", fetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := prometheusData(data, "line-graph", promOptions{By: []string{"instance"}, Agg: "sum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines lineGraphInput
+	if err := json.Unmarshal(out, &lines); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines.Series) != 2 || lines.Series[0].Label != "a" || len(lines.Series[0].Points) != 1 || len(lines.Series[1].Points) != 2 {
+		t.Errorf("series = %+v, want a with one point and b with two", lines.Series)
+	}
+
+	data, err = readData(srv.URL+"/api/v1/query?query=up", fetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := parsePromQueryResult(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || promSeriesLabel(series[0]) != `up{job="api"}` || !series[0].last().Time.Equal(time.Unix(1700000000, 5e8)) {
+		t.Errorf("vector = %+v", series)
+	}
+
+	data, _ = readData(srv.URL+"/api/v1/bad", fetchOptions{})
+	if _, err := prometheusData(data, "line-graph", promOptions{Agg: "sum"}); err == nil {
+		t.Error("failed query charted, want an error")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// scatterPoint is one point of a scatter plot. The x position is either a
+// number or, when every point has one, a date.
+type scatterPoint struct {
+	X     float64   `json:"x"`
+	Y     float64   `json:"y"`
+	Date  time.Time `json:"date"`
+	Label string    `json:"label"`
+}
+
+// scatterSeries is one named group of points drawn in its own color.
+type scatterSeries struct {
+	Label  string         `json:"label"`
+	Color  string         `json:"color"`
+	Points []scatterPoint `json:"points"`
+}
+
+// scatterInput is the JSON accepted by -type scatter. Like the line graph,
+// a single group can be given with the top-level points field.
+type scatterInput struct {
+	Label  string          `json:"label"`
+	Color  string          `json:"color"`
+	Points []scatterPoint  `json:"points"`
+	Series []scatterSeries `json:"series"`
+}
+
+// series returns the groups to draw with colors filled in from the theme.
+func (in scatterInput) series(config dataviz.RenderConfig) []scatterSeries {
+	if len(in.Series) == 0 {
+		color := in.Color
+		if color == "" {
+			color = config.Color
+		}
+		return []scatterSeries{{Label: in.Label, Color: color, Points: in.Points}}
+	}
+	series := make([]scatterSeries, len(in.Series))
+	for i, s := range in.Series {
+		if s.Color == "" {
+			s.Color = paletteColor(config.Theme, i)
+		}
+		series[i] = s
+	}
+	return series
+}
+
+// scatterLayout holds the scales shared by both renderings.
+type scatterLayout struct {
+	dated      bool
+	start, end time.Time
+	x, y       valueScale
+}
+
+// newScatterLayout measures the points. ok is false when there are none.
+func newScatterLayout(series []scatterSeries, opts chartOptions, maxX, maxY int) (scatterLayout, bool) {
+	var l scatterLayout
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	l.dated = true
+	count := 0
+	for _, s := range series {
+		for _, p := range s.Points {
+			if p.Date.IsZero() {
+				l.dated = false
+			} else {
+				if l.start.IsZero() || p.Date.Before(l.start) {
+					l.start = p.Date
+				}
+				if p.Date.After(l.end) {
+					l.end = p.Date
+				}
+			}
+			xMin, xMax = math.Min(xMin, p.X), math.Max(xMax, p.X)
+			yMin, yMax = math.Min(yMin, p.Y), math.Max(yMax, p.Y)
+			count++
+		}
+	}
+	if count == 0 {
+		return l, false
+	}
+
+	// The y settings in opts apply to the value axis only.
+	xOpts := opts
+	xOpts.YMin, xOpts.YMax, xOpts.YLog = math.NaN(), math.NaN(), false
+	l.x = newValueScale(xMin, xMax, xOpts, maxX)
+	l.y = newValueScale(yMin, yMax, opts, maxY)
+	return l, true
+}
+
+// xRatio positions a point along the x-axis.
+func (l scatterLayout) xRatio(p scatterPoint) float64 {
+	if l.dated {
+		return seriesExtent{start: l.start, end: l.end}.xRatio(p.Date)
+	}
+	return l.x.Ratio(p.X)
+}
+
+// xTicks returns the ticks for the x-axis.
+func (l scatterLayout) xTicks(maxTicks int, opts chartOptions) []axisTick {
+	if l.dated {
+		return dateTicks(l.start, l.end, maxTicks, opts.Dates)
+	}
+	return l.x.Ticks(maxTicks, opts.Numbers)
+}
+
+// tooltip describes a point for SVG hover text.
+func (l scatterLayout) tooltip(p scatterPoint, opts chartOptions) string {
+	x := opts.Numbers.Format(p.X)
+	if l.dated {
+		x = opts.Dates.Format(p.Date, "Jan 2 2006 15:04")
+	}
+	text := fmt.Sprintf("(%s, %s)", x, opts.Numbers.Format(p.Y))
+	if p.Label != "" {
+		text = p.Label + " " + text
+	}
+	return text
+}
+
+// Terminal implements chart. Each point is a 2x2 block of braille dots so
+// that it stays visible at terminal resolution.
+func (in scatterInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	series := in.series(config)
+	legend := terminalLegend(seriesLegend(scatterLegendSeries(series)), bounds.Width)
+	frame := newPlotFrame(bounds.Width, bounds.Height-len(legend), opts.Axes)
+	l, ok := newScatterLayout(series, opts, maxXTicks(frame.cols), maxYTicks(frame.rows))
+	if !ok {
+		return ""
+	}
+	frame.setYTicks(l.y.Ticks(maxYTicks(frame.rows), opts.Numbers))
+	frame.xTicks = l.xTicks(maxXTicks(frame.cols), opts)
+
+	canvas := newColorCanvas(frame.cols, frame.rows)
+	if opts.Grid {
+		frame.drawGrid(canvas)
+	}
+	maxX := float64(canvas.PixelWidth() - 2)
+	maxY := float64(canvas.PixelHeight() - 2)
+	for _, s := range series {
+		for _, p := range s.Points {
+			x := int(math.Round(l.xRatio(p) * maxX))
+			y := int(math.Round((1 - l.y.Ratio(p.Y)) * maxY))
+			canvas.Set(x, y, s.Color)
+			canvas.Set(x+1, y, s.Color)
+			canvas.Set(x, y+1, s.Color)
+			canvas.Set(x+1, y+1, s.Color)
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range frame.decorate(canvas.Lines()) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	for _, line := range legend {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SVG implements chart.
+func (in scatterInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+
+	series := in.series(config)
+	legend := seriesLegend(scatterLegendSeries(series))
+	area := newSVGPlotArea(bounds, len(legend) > 0, opts.Axes)
+	l, ok := newScatterLayout(series, opts, maxSVGTicks(area.W), maxSVGTicks(area.H))
+	if !ok {
+		return doc.String()
+	}
+	area.drawAxes(doc, l.y.Ticks(maxSVGTicks(area.H), opts.Numbers), l.xTicks(maxSVGTicks(area.W), opts), opts, textColor)
+	if len(legend) > 0 {
+		svgLegend(doc, legend, float64(bounds.Width)-svgPadding, svgPadding+10, textColor)
+	}
+
+	for i, s := range series {
+		for _, p := range s.Points {
+			tooltip := l.tooltip(p, opts)
+			if len(legend) > 0 {
+				tooltip = legend[i].Label + " · " + tooltip
+			}
+			doc.Add(svgCircle{
+				CX:      area.X + l.xRatio(p)*area.W,
+				CY:      area.Y + (1-l.y.Ratio(p.Y))*area.H,
+				R:       3.5,
+				Fill:    s.Color,
+				Opacity: 0.8,
+				Title:   tooltip,
+			})
+		}
+	}
+	return doc.String()
+}
+
+// scatterLegendSeries adapts scatter groups for seriesLegend.
+func scatterLegendSeries(series []scatterSeries) []lineSeries {
+	lines := make([]lineSeries, len(series))
+	for i, s := range series {
+		lines[i] = lineSeries{Label: s.Label, Color: s.Color}
+	}
+	return lines
+}
@@ -0,0 +1,162 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// selectData applies a -select expression to JSON data. The expression is
+// a path such as "$.data.items[0]" or "results[*].value": "$" is the
+// document, ".name" and ["name"] pick an object field, [N] an array
+// element (negative from the end) and [*] every element. Prefixed with
+// "FIELD=", the selection is wrapped in an object under FIELD, e.g.
+// "points=$.data.series[0].values" to feed a line graph.
+func selectData(data []byte, expr string) ([]byte, error) {
+	field, path := "", expr
+	if name, rest, ok := strings.Cut(expr, "="); ok && isFieldName(name) {
+		field, path = name, rest
+	}
+	steps, err := parseSelectPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("-select needs JSON data: %w", err)
+	}
+	selected, err := selectPath(doc, steps, "$")
+	if err != nil {
+		return nil, err
+	}
+	if field != "" {
+		selected = map[string]any{field: selected}
+	}
+	return json.Marshal(selected)
+}
+
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r != '_' && r != '-' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// selectStep is one step of a path: a field name, an index, or every
+// element when all is set.
+type selectStep struct {
+	field string
+	index int
+	isKey bool
+	all   bool
+}
+
+func (s selectStep) String() string {
+	switch {
+	case s.all:
+		return "[*]"
+	case s.isKey:
+		return "." + s.field
+	default:
+		return "[" + strconv.Itoa(s.index) + "]"
+	}
+}
+
+// parseSelectPath splits a path into its steps.
+func parseSelectPath(path string) ([]selectStep, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	var steps []selectStep
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			fallthrough
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid -select path: empty field name")
+			}
+			steps = append(steps, selectStep{field: path[:end], isKey: true})
+			path = path[end:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid -select path: unclosed [")
+			}
+			inner := strings.TrimSpace(path[1:end])
+			path = path[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, selectStep{all: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, selectStep{field: inner[1 : len(inner)-1], isKey: true})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid -select path: bad index [%s]", inner)
+				}
+				steps = append(steps, selectStep{index: index})
+			}
+		}
+	}
+	return steps, nil
+}
+
+// selectPath follows steps from v. at is the path so far, for errors.
+func selectPath(v any, steps []selectStep, at string) (any, error) {
+	if len(steps) == 0 {
+		return v, nil
+	}
+	step, rest := steps[0], steps[1:]
+	if step.isKey {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("-select: %s is not an object", at)
+		}
+		child, ok := obj[step.field]
+		if !ok {
+			return nil, fmt.Errorf("-select: %s has no field %q", at, step.field)
+		}
+		return selectPath(child, rest, at+step.String())
+	}
+
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("-select: %s is not an array", at)
+	}
+	if step.all {
+		out := make([]any, len(arr))
+		for i, item := range arr {
+			selected, err := selectPath(item, rest, fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = selected
+		}
+		return out, nil
+	}
+	index := step.index
+	if index < 0 {
+		index += len(arr)
+	}
+	if index < 0 || index >= len(arr) {
+		return nil, fmt.Errorf("-select: %s has no element %d", at, step.index)
+	}
+	return selectPath(arr[index], rest, at+step.String())
+}
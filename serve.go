@@ -0,0 +1,272 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// serverFlags are the options requests may set: those that only change
+// how the chart is drawn. Options that read or write local files, fetch
+// data, run commands or never return are left out, as is any option added
+// later until it is known to be safe.
+var serverFlags = map[string]bool{
+	"theme": true, "width": true, "height": true, "color": true,
+	"axes": true, "grid": true, "y-min": true, "y-max": true, "y-log": true,
+	"number-format": true, "date-format": true, "locale": true,
+	"spark-style": true, "spark-labels": true,
+	"sort": true, "bars": true, "heat": true, "border": true,
+	"bar-mode": true, "orientation": true,
+	"mark": true, "hline": true, "shade": true, "callout": true,
+	"layout": true, "buckets": true, "quantiles": true, "scale": true, "legend": true,
+	"select": true, "x": true, "y": true, "series": true,
+	"input": true, "metric": true, "by": true, "agg": true,
+}
+
+// contentTypes maps each output format to its media type.
+var contentTypes = map[string]string{
+	"svg":      "image/svg+xml",
+	"png":      "image/png",
+	"terminal": "text/plain; charset=utf-8",
+}
+
+// chartServer renders charts over HTTP. maxSize bounds the width and the
+// height of a chart and maxArea their product, so that a request cannot
+// make the server allocate an arbitrarily large canvas.
+type chartServer struct {
+	maxBytes   int64
+	maxAge     time.Duration
+	maxSize    int
+	maxArea    int
+	rasterizer string
+}
+
+// serve runs the serve subcommand and returns the process exit code.
+func serve(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Listen address")
+	s := chartServer{}
+	fs.Int64Var(&s.maxBytes, "max-bytes", 1<<20, "Largest accepted request body")
+	fs.DurationVar(&s.maxAge, "max-age", 5*time.Minute, "Cache-Control max-age")
+	fs.IntVar(&s.maxSize, "max-size", 4096, "Largest accepted width or height")
+	fs.IntVar(&s.maxArea, "max-area", 4<<20, "Largest accepted width times height")
+	fs.StringVar(&s.rasterizer, "rasterizer", "rsvg-convert", "SVG to PNG converter")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	fs.Parse(args)
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "Serving charts on %s\n", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (s chartServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /render/{type}", s.render)
+	mux.HandleFunc("POST /render/{type}", s.render)
+	return mux
+}
+
+// render handles /render/{type}. The data is the request body, or the
+// "data" query parameter for GET requests; other query parameters are
+// chart options named like the command line flags, e.g. ?axes=true&width=600.
+func (s chartServer) render(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	data, err := s.readData(w, r, query)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("data larger than %d bytes", s.maxBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := negotiateFormat(query.Get("format"), r.Header.Get("Accept"))
+	if format == "" {
+		http.Error(w, "unsupported format; use svg, png or terminal", http.StatusNotAcceptable)
+		return
+	}
+
+	args := []string{"-type", r.PathValue("type"), "-format", format}
+	for _, name := range sortedKeys(query) {
+		if name == "format" || name == "data" {
+			continue
+		}
+		if !serverFlags[name] {
+			http.Error(w, fmt.Sprintf("option %q is not allowed", name), http.StatusBadRequest)
+			return
+		}
+		for _, value := range query[name] {
+			args = append(args, "-"+name+"="+value)
+		}
+	}
+
+	etag := chartETag(args, data)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(s.maxAge.Seconds())))
+	w.Header().Set("Vary", "Accept")
+	if match := r.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	fs := flag.NewFlagSet("request", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg, err := parseFlags(fs, args)
+	if err == nil {
+		err = s.checkConfig(cfg)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if format == "png" {
+		cfg.format = "svg"
+	}
+	if cfg.fetch.Select != "" {
+		if data, err = selectData(data, cfg.fetch.Select); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	output, err := render(cfg, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := []byte(output)
+	if format == "png" {
+		if body, err = rasterizeSVG(r.Context(), s.rasterizer, body); err != nil {
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.Write(body)
+}
+
+// checkConfig rejects charts larger than the server's limits and colors
+// that are not plain color values. Colors are written into SVG attributes,
+// some of them by renderers that do not escape them.
+func (s chartServer) checkConfig(cfg Config) error {
+	if cfg.width <= 0 || cfg.height <= 0 {
+		return fmt.Errorf("width and height must be positive")
+	}
+	if cfg.width > s.maxSize || cfg.height > s.maxSize {
+		return fmt.Errorf("width and height must be at most %d", s.maxSize)
+	}
+	if cfg.width*cfg.height > s.maxArea {
+		return fmt.Errorf("width times height must be at most %d", s.maxArea)
+	}
+	if !isColorValue(cfg.color) {
+		return fmt.Errorf("invalid color %q (want e.g. #3B82F6 or steelblue)", cfg.color)
+	}
+	return nil
+}
+
+// isColorValue reports whether c is a hex color or a color name.
+func isColorValue(c string) bool {
+	if hex, ok := strings.CutPrefix(c, "#"); ok {
+		switch len(hex) {
+		case 3, 4, 6, 8:
+		default:
+			return false
+		}
+		return strings.Trim(strings.ToLower(hex), "0123456789abcdef") == ""
+	}
+	return c != "" && strings.Trim(strings.ToLower(c), "abcdefghijklmnopqrstuvwxyz") == ""
+}
+
+// readData returns the chart data from the request, limited to maxBytes.
+func (s chartServer) readData(w http.ResponseWriter, r *http.Request, query url.Values) ([]byte, error) {
+	if r.Method == http.MethodGet {
+		data := query.Get("data")
+		if data == "" {
+			return nil, errors.New("no data; POST it or pass it in the data parameter")
+		}
+		if int64(len(data)) > s.maxBytes {
+			return nil, &http.MaxBytesError{Limit: s.maxBytes}
+		}
+		return []byte(data), nil
+	}
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBytes))
+}
+
+// negotiateFormat picks the output format from the format parameter, or
+// else from the first supported media type in the Accept header, or else
+// SVG. It returns "" for an unknown format parameter.
+func negotiateFormat(param, accept string) string {
+	switch param {
+	case "svg", "png", "terminal":
+		return param
+	case "text":
+		return "terminal"
+	case "":
+	default:
+		return ""
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		switch strings.TrimSpace(mediaType) {
+		case "image/svg+xml":
+			return "svg"
+		case "image/png":
+			return "png"
+		case "text/plain":
+			return "terminal"
+		}
+	}
+	return "svg"
+}
+
+// chartETag identifies a chart by everything it is rendered from, so an
+// unchanged request can be answered without rendering it again.
+func chartETag(args []string, data []byte) string {
+	h := sha256.New()
+	for _, arg := range args {
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+func sortedKeys(values url.Values) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testServer() chartServer {
+	return chartServer{maxBytes: 1 << 20, maxSize: 4096, maxArea: 4 << 20}
+}
+
+func TestServeRender(t *testing.T) {
+	gauge := `{"value": 40, "max": 100}`
+	tests := []struct {
+		name   string
+		method string
+		query  string
+		status int
+	}{
+		{"svg", "POST", "width=300&height=200", http.StatusOK},
+		{"get", "GET", "data=" + url.QueryEscape(gauge), http.StatusOK},
+		{"terminal", "POST", "format=text&width=40&height=10", http.StatusOK},
+		{"unknown format", "POST", "format=gif", http.StatusNotAcceptable},
+		{"no data", "GET", "", http.StatusBadRequest},
+		{"file option", "POST", "output=/tmp/x.svg", http.StatusBadRequest},
+		{"unknown option", "POST", "rasterizer=sh", http.StatusBadRequest},
+		{"wide", "POST", "width=60000&height=10", http.StatusBadRequest},
+		{"large area", "POST", "width=4096&height=4096", http.StatusBadRequest},
+		{"zero height", "POST", "height=0", http.StatusBadRequest},
+		{"hex color", "POST", "color=%23abc", http.StatusOK},
+		{"named color", "POST", "color=steelblue", http.StatusOK},
+		{"injected color", "POST", "color=" + url.QueryEscape(injected), http.StatusBadRequest},
+		{"short hex color", "POST", "color=%2312", http.StatusBadRequest},
+	}
+	handler := testServer().handler()
+	for _, tt := range tests {
+		var body *strings.Reader
+		if tt.method == "POST" {
+			body = strings.NewReader(gauge)
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(tt.method, "/render/gauge?"+tt.query, body)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+		if rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "<script>") {
+			t.Errorf("%s: markup injected into\n%s", tt.name, rec.Body)
+		}
+	}
+
+	// Text and colors in the data reach the dataviz SVG renderer, which
+	// writes them as given.
+	points := `[{"date": "2024-01-01T00:00:00Z", "value": 1}, {"date": "2024-01-02T00:00:00Z", "value": 2}]`
+	posts := []struct {
+		name   string
+		chart  string
+		data   string
+		status int
+	}{
+		{"stat text", "stat-card", fmt.Sprintf(`{"title": %q, "value": %q, "subtitle": %q}`, injected, injected, injected), http.StatusOK},
+		{"stat color", "stat-card", fmt.Sprintf(`{"title": "up", "color": %q}`, injected), http.StatusBadRequest},
+		{"line color", "line-graph", fmt.Sprintf(`{"color": %q, "points": %s}`, injected, points), http.StatusBadRequest},
+		{"line gradient", "line-graph",
+			fmt.Sprintf(`{"useGradient": true, "fillColor": "#3B82F6", "gradientId": %q, "points": %s}`, injected, points), http.StatusOK},
+		{"series", "line-graph", fmt.Sprintf(`{"series": [{"label": %q, "color": %q, "points": %s}]}`, injected, injected, points), http.StatusOK},
+		{"bar color", "bar-chart", fmt.Sprintf(`{"color": %q, "bars": [{"label": "a", "value": 1}]}`, injected), http.StatusBadRequest},
+		{"bar label", "bar-chart", fmt.Sprintf(`{"bars": [{"label": %q, "value": 1.5}]}`, injected), http.StatusOK},
+	}
+	for _, tt := range posts {
+		req := httptest.NewRequest("POST", "/render/"+tt.chart, strings.NewReader(tt.data))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("injected %s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+		if rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "<script>") {
+			t.Errorf("injected %s: markup injected into\n%s", tt.name, rec.Body)
+		}
+	}
+}
+
+func TestServeNotModified(t *testing.T) {
+	handler := testServer().handler()
+	req := httptest.NewRequest("POST", "/render/gauge", strings.NewReader(`{"value": 1}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status %d, ETag %q", rec.Code, etag)
+	}
+	req = httptest.NewRequest("POST", "/render/gauge", strings.NewReader(`{"value": 1}`))
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("repeated request: status %d, want %d", rec.Code, http.StatusNotModified)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct{ param, accept, want string }{
+		{"", "", "svg"},
+		{"text", "image/png", "terminal"},
+		{"", "text/html, image/png;q=0.9", "png"},
+		{"", "text/plain", "terminal"},
+		{"pdf", "", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateFormat(tt.param, tt.accept); got != tt.want {
+			t.Errorf("negotiateFormat(%q, %q) = %q, want %q", tt.param, tt.accept, got, tt.want)
+		}
+	}
+}
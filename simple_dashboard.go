@@ -1,3 +1,5 @@
+//go:build simpledashboard
+
 package main
 
 import (
@@ -7,9 +9,9 @@ import (
 	"os"
 	"time"
 
-	tea "github.com/charmbracelet/bubbletea"
 	"github.com/SCKelemen/dataviz"
 	design "github.com/SCKelemen/design-system"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 type tickMsg time.Time
@@ -115,6 +117,41 @@ func (m simpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// kpis returns the stat cards above the charts: contributions and the
+// busiest day over the last 30 days against the 30 before, and the latest
+// metric against a week earlier.
+func (m simpleModel) kpis() []kpiCard {
+	days := m.heatmap.Days
+	recent, prior := days[max(0, len(days)-30):], days[max(0, len(days)-60):max(0, len(days)-30)]
+	sum := func(days []dataviz.ContributionDay) (total, peak int) {
+		for _, d := range days {
+			total += d.Count
+			peak = max(peak, d.Count)
+		}
+		return total, peak
+	}
+	total, peak := sum(recent)
+	priorTotal, priorPeak := sum(prior)
+	var trend []dataviz.TimeSeriesData
+	for _, d := range recent {
+		trend = append(trend, dataviz.TimeSeriesData{Date: d.Date, Value: d.Count})
+	}
+
+	contributions := newKPICard("Contributions", fmt.Sprintf("%d", total), float64(total), float64(priorTotal), trend)
+	contributions.Subtitle = "vs prior 30d"
+	busiest := newKPICard("Busiest day", fmt.Sprintf("%d", peak), float64(peak), float64(priorPeak), trend)
+	busiest.Subtitle = "vs prior 30d"
+
+	cards := []kpiCard{contributions, busiest}
+	if points := m.lineGraph.Points; len(points) > 0 {
+		latest, weekAgo := points[len(points)-1].Value, points[max(0, len(points)-8)].Value
+		metric := newKPICard("Metric", fmt.Sprintf("%d", latest), float64(latest), float64(weekAgo), points)
+		metric.Subtitle = "vs 7d ago"
+		cards = append(cards, metric)
+	}
+	return cards
+}
+
 // hexToANSI converts a hex color to ANSI escape code
 func hexToANSI(hexColor string) string {
 	if hexColor == "" {
@@ -184,6 +221,23 @@ func (m simpleModel) View() string {
 	output += titleBar.RenderBottom()
 	output += "\n"
 
+	// KPI strip, wrapping when the terminal is narrower than the boxes
+	cards := m.kpis()
+	for i := range cards {
+		cards[i].Color = accentColor
+		cards[i].TrendColor = accentColor
+	}
+	strip := kpiStrip{
+		Cards: cards,
+		Width: min(boxWidth, m.width),
+		Style: LightBorderStyle,
+		Good:  "#4CAF50",
+		Bad:   "#F44336",
+	}
+	if strip.Height() > 0 {
+		output += strip.Render() + "\n\n"
+	}
+
 	// Heatmap
 	heatmapBox := &Box{
 		Label:       "CONTRIBUTION HEATMAP",
@@ -0,0 +1,21 @@
+//go:build simpledashboard
+
+package main
+
+import (
+	"testing"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func TestSimpleKPIs(t *testing.T) {
+	m := initialSimpleModel()
+	if got := len(m.kpis()); got != 3 {
+		t.Errorf("%d cards, want 3", got)
+	}
+	m.lineGraph.Points = nil
+	m.heatmap.Days = []dataviz.ContributionDay{}
+	if got := len(m.kpis()); got != 2 {
+		t.Errorf("without metric points: %d cards, want 2", got)
+	}
+}
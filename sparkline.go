@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// sparkBlocks are the glyphs of a block sparkline, lowest first. The lowest
+// level still draws a glyph so that every sample is visible.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkBrailleRows are the braille dots of the left and right column of a
+// cell, bottom row first.
+var sparkBrailleRows = [2][4]rune{
+	{0x40, 0x04, 0x02, 0x01},
+	{0x80, 0x20, 0x10, 0x08},
+}
+
+// sparklineInput is the JSON accepted by -type sparkline: the same points
+// as a single-series line graph.
+type sparklineInput struct {
+	lineGraphInput
+	style  string
+	labels []string
+}
+
+// sparkOptions are the sparkline settings from the command line: the glyph
+// style, "blocks" or "braille", and which of "min", "max" and "last" to
+// print after the line.
+type sparkOptions struct {
+	Style  string
+	Labels []string
+}
+
+// apply takes the style and labels from the command line.
+func (in *sparklineInput) apply(opts sparkOptions) {
+	in.style, in.labels = opts.Style, opts.Labels
+}
+
+// parseSparkLabels splits the -spark-labels flag.
+func parseSparkLabels(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	labels := strings.Split(spec, ",")
+	for i, l := range labels {
+		l = strings.TrimSpace(l)
+		switch l {
+		case "min", "max", "last":
+		default:
+			return nil, fmt.Errorf("unknown sparkline label %q (want min, max or last)", l)
+		}
+		labels[i] = l
+	}
+	return labels, nil
+}
+
+// sparkAnnotation renders the requested labels, e.g. "↓3 ↑48 →40".
+func sparkAnnotation(values []float64, labels []string, nf numberFormat) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var parts []string
+	for _, a := range labels {
+		switch a {
+		case "min":
+			parts = append(parts, "↓"+nf.Format(lo))
+		case "max":
+			parts = append(parts, "↑"+nf.Format(hi))
+		case "last":
+			parts = append(parts, "→"+nf.Format(values[len(values)-1]))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// resample reduces or stretches values to exactly n samples. Each sample is
+// the mean of the values that fall into its share of the series, or the
+// nearest value when the series is shorter than n.
+func resample(values []float64, n int) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		from := i * len(values) / n
+		to := max(from+1, (i+1)*len(values)/n)
+		sum := 0.0
+		for _, v := range values[from:to] {
+			sum += v
+		}
+		samples[i] = sum / float64(to-from)
+	}
+	return samples
+}
+
+// Terminal implements chart. The result is exactly bounds.Width columns of
+// plain text on one line, without color or a trailing newline, so that it
+// can be embedded in status lines and prompts. Annotations are dropped when
+// they would leave no room for the line itself.
+func (in sparklineInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	width := bounds.Width
+	if width < 1 {
+		return ""
+	}
+	values := pointValues(sortedPoints(in.Points))
+	if len(values) == 0 {
+		return strings.Repeat(" ", width)
+	}
+
+	note := sparkAnnotation(values, in.labels, opts.Numbers)
+	cells := width
+	if note != "" {
+		cells = width - 1 - utf8.RuneCountInString(note)
+		if cells < 1 {
+			note, cells = "", width
+		}
+	}
+
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	scale := newValueScale(lo, hi, opts, 2)
+
+	var b strings.Builder
+	if in.style == "braille" {
+		samples := resample(values, cells*2)
+		for c := 0; c < cells; c++ {
+			glyph := rune(0x2800)
+			for side := 0; side < 2; side++ {
+				dots := 1 + int(math.Round(scale.Ratio(samples[c*2+side])*3))
+				for d := 0; d < dots; d++ {
+					glyph |= sparkBrailleRows[side][d]
+				}
+			}
+			b.WriteRune(glyph)
+		}
+	} else {
+		for _, v := range resample(values, cells) {
+			b.WriteRune(sparkBlocks[int(math.Round(scale.Ratio(v)*7))])
+		}
+	}
+	if note != "" {
+		b.WriteString(" ")
+		b.WriteString(note)
+	}
+	return b.String()
+}
+
+// SVG implements chart. The line fills the image up to a small inset so
+// it can sit inline next to text.
+func (in sparklineInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	doc := newSVGDocument(bounds.Width, bounds.Height, "")
+	points := sortedPoints(in.Points)
+	if len(points) == 0 {
+		return doc.String()
+	}
+	color := in.Color
+	if color == "" {
+		color = config.Color
+	}
+
+	values := pointValues(points)
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	scale := newValueScale(lo, hi, opts, 2)
+	ext, _ := extentOf([]lineSeries{{Points: points}})
+
+	const inset = 2.0 // keeps the stroke and end marker inside the image
+	w, h := float64(bounds.Width)-2*inset, float64(bounds.Height)-2*inset
+	line := svgPolyline{Stroke: color, Width: 1.5}
+	for _, p := range points {
+		line.Points = append(line.Points, svgPoint{
+			X: inset + ext.xRatio(p.Date)*w,
+			Y: inset + (1-scale.Ratio(p.Value))*h,
+		})
+	}
+	last := line.Points[len(line.Points)-1]
+	doc.Add(line, svgCircle{CX: last.X, CY: last.Y, R: inset, Fill: color, Title: opts.Numbers.Format(values[len(values)-1])})
+	return doc.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func sparkInput(values ...float64) sparklineInput {
+	var in sparklineInput
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, v := range values {
+		in.Points = append(in.Points, timePoint{Date: day.AddDate(0, 0, i), Value: v})
+	}
+	return in
+}
+
+func TestSparklineTerminal(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   sparkOptions
+		values []float64
+		width  int
+		want   string
+	}{
+		{"blocks", sparkOptions{Style: "blocks"}, []float64{0, 1, 2, 3, 4, 5, 6, 7}, 8, "▁▂▃▄▅▆▇█"},
+		{"resampled", sparkOptions{Style: "blocks"}, []float64{0, 0, 7, 7}, 2, "▁█"},
+		{"stretched", sparkOptions{Style: "blocks"}, []float64{0, 7}, 4, "▁▁██"},
+		{"labels", sparkOptions{Style: "blocks", Labels: []string{"min", "last"}}, []float64{3, 9, 5}, 10, "▁▁█▃ ↓3 →5"},
+		{"labels dropped", sparkOptions{Style: "blocks", Labels: []string{"max"}}, []float64{3, 9}, 3, "▁▁█"},
+		{"braille", sparkOptions{Style: "braille"}, []float64{0, 7}, 1, "⣸"},
+		{"empty", sparkOptions{Style: "blocks"}, nil, 3, "   "},
+	}
+	for _, tt := range tests {
+		in := sparkInput(tt.values...)
+		in.apply(tt.opts)
+		got := in.Terminal(dataviz.Bounds{Width: tt.width, Height: 1}, dataviz.RenderConfig{}, defaultChartOptions())
+		if got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+		if n := utf8.RuneCountInString(got); n != tt.width {
+			t.Errorf("%s: %d columns, want exactly %d", tt.name, n, tt.width)
+		}
+	}
+}
+
+func TestParseSparkLabels(t *testing.T) {
+	labels, err := parseSparkLabels("min, max,last")
+	if err != nil || !slices.Equal(labels, []string{"min", "max", "last"}) {
+		t.Errorf("parseSparkLabels = %q, %v", labels, err)
+	}
+	if _, err := parseSparkLabels("median"); err == nil {
+		t.Error("parseSparkLabels accepted an unknown label")
+	}
+}
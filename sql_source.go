@@ -0,0 +1,160 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlOptions is a query for -sql against the database named by -db, a URL
+// such as sqlite:///var/lib/app.db.
+type sqlOptions struct {
+	Query string
+	DB    string
+}
+
+// sqlDriver opens one kind of database through database/sql. Another
+// database is added by importing its driver and registering its URL
+// scheme here, e.g. "postgres" passing the URL through unchanged.
+type sqlDriver struct {
+	name string
+	dsn  func(u *url.URL) (string, error)
+}
+
+var sqlDrivers = map[string]sqlDriver{
+	"sqlite":  {name: "sqlite3", dsn: sqliteDSN},
+	"sqlite3": {name: "sqlite3", dsn: sqliteDSN},
+}
+
+// sqliteDSN opens the database file read-only. Both sqlite:///abs/path.db
+// and sqlite://relative/path.db are accepted; query parameters are passed
+// to the driver.
+func sqliteDSN(u *url.URL) (string, error) {
+	path := u.Host + u.Path
+	if path == "" {
+		return "", fmt.Errorf("no database file in %q", u.String())
+	}
+	params := u.Query()
+	params.Set("mode", "ro")
+	dsn := url.URL{Scheme: "file", Path: path, RawQuery: params.Encode(), OmitHost: true}
+	return dsn.String(), nil
+}
+
+// resolveDB makes the file of a relative SQLite -db URL relative to dir,
+// as batch manifests do for data files. Other URLs are returned as they
+// are.
+func resolveDB(dir, db string) string {
+	u, err := url.Parse(db)
+	if err != nil || sqlDrivers[u.Scheme].name != "sqlite3" {
+		return db
+	}
+	path := u.Host + u.Path
+	if path == "" || filepath.IsAbs(path) {
+		return db
+	}
+	resolved := u.Scheme + "://" + filepath.ToSlash(filepath.Join(dir, path))
+	if u.RawQuery != "" {
+		resolved += "?" + u.RawQuery
+	}
+	return resolved
+}
+
+// querySQL runs the query and returns its result as CSV, so that it maps
+// onto charts like any other table.
+func querySQL(opts sqlOptions, timeout time.Duration) ([]byte, error) {
+	u, err := url.Parse(opts.DB)
+	if err != nil || u.Scheme == "" {
+		return nil, fmt.Errorf("invalid -db %q (want e.g. sqlite:///path.db)", opts.DB)
+	}
+	driver, ok := sqlDrivers[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database %q (supported: %s)", u.Scheme, strings.Join(sqlSchemes(), ", "))
+	}
+	dsn, err := driver.dsn(u)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(driver.name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	rows, err := db.QueryContext(ctx, opts.Query)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+	values := make([]any, len(columns))
+	pointers := make([]any, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			record[i] = sqlText(v)
+		}
+		w.Write(record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// sqlText formats a scanned value as a CSV field. NULL is empty and
+// timestamps are RFC 3339, which the column mapping reads as dates.
+func sqlText(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sqlSchemes() []string {
+	schemes := make([]string, 0, len(sqlDrivers))
+	for scheme := range sqlDrivers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
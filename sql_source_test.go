@@ -0,0 +1,81 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct{ db, want string }{
+		{"sqlite:///var/lib/app.db", "file:/var/lib/app.db?mode=ro"},
+		{"sqlite://data/app.db", "file:data/app.db?mode=ro"},
+		{"sqlite:///tmp/a b#1.db?cache=shared", "file:/tmp/a%20b%231.db?cache=shared&mode=ro"},
+		{"sqlite:///tmp/x.db?mode=rw", "file:/tmp/x.db?mode=ro"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(strings.Replace(tt.db, "#", "%23", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := sqliteDSN(u)
+		if err != nil || got != tt.want {
+			t.Errorf("sqliteDSN(%s) = %q, %v, want %q", tt.db, got, err, tt.want)
+		}
+	}
+	if _, err := sqliteDSN(&url.URL{Scheme: "sqlite"}); err == nil {
+		t.Error("sqliteDSN without a file succeeded")
+	}
+}
+
+func TestResolveDB(t *testing.T) {
+	tests := []struct{ db, want string }{
+		{"sqlite://app.db", "sqlite://reports/app.db"},
+		{"sqlite3://../app.db?cache=shared", "sqlite3://app.db?cache=shared"},
+		{"sqlite:///var/lib/app.db", "sqlite:///var/lib/app.db"},
+		{"postgres://db.example.com/app", "postgres://db.example.com/app"},
+	}
+	for _, tt := range tests {
+		if got := resolveDB("reports", tt.db); got != tt.want {
+			t.Errorf("resolveDB(%q) = %q, want %q", tt.db, got, tt.want)
+		}
+	}
+	if got := resolveDB("/srv/reports", "sqlite://app.db"); got != "sqlite:///srv/reports/app.db" {
+		t.Errorf("resolveDB against an absolute directory = %q", got)
+	}
+}
+
+func TestQuerySQL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daily events.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE events (day TEXT, kind TEXT, n INTEGER, share REAL);
+		INSERT INTO events VALUES ('2024-03-01', 'deploy', 3, 0.5), ('2024-03-02', NULL, 4, 0.25)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := querySQL(sqlOptions{DB: "sqlite://" + path, Query: "SELECT * FROM events ORDER BY day"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "day,kind,n,share\n2024-03-01,deploy,3,0.5\n2024-03-02,,4,0.25\n"
+	if string(out) != want {
+		t.Errorf("querySQL = %q, want %q", out, want)
+	}
+	if _, err := querySQL(sqlOptions{DB: "sqlite://" + path, Query: "DELETE FROM events"}, 0); err == nil {
+		t.Error("write through the read-only connection succeeded")
+	}
+	for _, bad := range []string{"app.db", "mysql://host/db"} {
+		if _, err := querySQL(sqlOptions{DB: bad, Query: "SELECT 1"}, 0); err == nil {
+			t.Errorf("-db %s accepted", bad)
+		}
+	}
+}
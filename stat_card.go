@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// statCardInput is the JSON accepted by -type stat-card. The value may be a
+// pre-formatted string or a number, which is formatted with -number-format.
+type statCardInput struct {
+	dataviz.StatCardData
+	Value     json.RawMessage `json:"value"`
+	TrendData []timePoint     `json:"trendData"`
+}
+
+// resolve returns the card data with its value rendered as a string.
+func (in statCardInput) resolve(nf numberFormat) (dataviz.StatCardData, error) {
+	card := in.StatCardData
+	card.TrendData = toTimeSeries(in.TrendData)
+	if len(in.Value) == 0 {
+		return card, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(in.Value, &text); err == nil {
+		card.Value = text
+		return card, nil
+	}
+	var number float64
+	if err := json.Unmarshal(in.Value, &number); err != nil {
+		return card, fmt.Errorf("value must be a string or a number, got %s", in.Value)
+	}
+	card.Value = nf.Format(number)
+	return card, nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+// A card of a KPI strip is kpiHeight lines: its borders, the value, the
+// change and the trend. Cards narrower than kpiMinWidth wrap onto another
+// row instead.
+const (
+	kpiHeight   = 5
+	kpiMinWidth = 24
+)
+
+// trendBlocks are the glyphs of the trend line of a card, lowest first.
+var trendBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// kpiCard is a stat card of a KPI strip. Change and ChangePct compare the
+// value with the previous period, which Subtitle names, and count as good
+// when they go up, or down for LowerIsBetter.
+type kpiCard struct {
+	dataviz.StatCardData
+	LowerIsBetter bool
+}
+
+// newKPICard returns a card showing value, with the change from previous
+// to current and the trend leading up to it.
+func newKPICard(title, value string, current, previous float64, trend []dataviz.TimeSeriesData) kpiCard {
+	card := kpiCard{StatCardData: dataviz.StatCardData{
+		Title:     title,
+		Value:     value,
+		Change:    int(math.Round(current - previous)),
+		TrendData: trend,
+	}}
+	if previous != 0 {
+		card.ChangePct = (current - previous) / math.Abs(previous) * 100
+	}
+	return card
+}
+
+// direction returns 1 when the card went up, -1 when it went down and 0
+// when it held. Without a percentage, as when the previous value was zero,
+// it goes by the absolute change.
+func (c kpiCard) direction() int {
+	change := c.ChangePct
+	if change == 0 {
+		change = float64(c.Change)
+	}
+	switch {
+	case change > 0:
+		return 1
+	case change < 0:
+		return -1
+	}
+	return 0
+}
+
+// changeText writes the change with an arrow. Its color is good when it
+// went the right way, bad when it went the wrong one and empty when it
+// held.
+func (c kpiCard) changeText(good, bad string) (text, color string) {
+	dir := c.direction()
+	switch dir {
+	case 1:
+		text = "▲ "
+	case -1:
+		text = "▼ "
+	default:
+		text = "= "
+	}
+	if c.ChangePct != 0 || c.Change == 0 {
+		text += fmt.Sprintf("%+.1f%%", c.ChangePct)
+	} else {
+		text += fmt.Sprintf("%+d", c.Change)
+	}
+	if c.Subtitle != "" {
+		text += " " + c.Subtitle
+	}
+	if c.LowerIsBetter {
+		dir = -dir
+	}
+	switch dir {
+	case 1:
+		color = good
+	case -1:
+		color = bad
+	}
+	return text, color
+}
+
+// trendText draws the trend in at most width blocks, each the mean of its
+// share of the points, scaled between the lowest and highest of them.
+func (c kpiCard) trendText(width int) string {
+	n := min(width, len(c.TrendData))
+	if n <= 0 {
+		return ""
+	}
+	values := make([]float64, n)
+	for i := range values {
+		from, to := i*len(c.TrendData)/n, (i+1)*len(c.TrendData)/n
+		for _, p := range c.TrendData[from:to] {
+			values[i] += float64(p.Value)
+		}
+		values[i] /= float64(to - from)
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := len(trendBlocks) / 2
+		if hi > lo {
+			level = int(math.Round((v - lo) / (hi - lo) * float64(len(trendBlocks)-1)))
+		}
+		b.WriteRune(trendBlocks[level])
+	}
+	return b.String()
+}
+
+// lines draws the card as a box width columns wide, kpiHeight lines high.
+func (c kpiCard) lines(width int, style BorderStyle, good, bad string) []string {
+	label := c.Title
+	if utf8.RuneCountInString(label) > width-4 {
+		label = string([]rune(label)[:max(0, width-4)])
+	}
+	box := Box{Label: label, Width: width, BorderColor: ansiColor(c.Color), Style: style}
+	trend := colorize(c.trendText(width-4), c.TrendColor)
+	if trend == "" {
+		// WrapContent drops empty lines, so keep the box its full height.
+		trend = " "
+	}
+	change, color := c.changeText(good, bad)
+	if utf8.RuneCountInString(change) > width-4 {
+		// Leave out the period rather than cut the change short.
+		c.Subtitle = ""
+		change, color = c.changeText(good, bad)
+	}
+	content := ansiBold + c.Value + ansiReset + "\n" + colorize(change, color) + "\n" + trend
+	return strings.Split(strings.TrimSuffix(box.RenderComplete(content), "\n"), "\n")
+}
+
+// kpiStrip lays stat cards out side by side across Width columns, one
+// column apart, wrapping onto more rows when they would be narrower than
+// kpiMinWidth. Good and Bad color changes for the better and the worse.
+type kpiStrip struct {
+	Cards []kpiCard
+	Width int
+	Style BorderStyle
+	Good  string
+	Bad   string
+}
+
+// perRow returns the number of cards on each row, or zero when the strip
+// is too narrow for even one.
+func (s kpiStrip) perRow() int {
+	return min(len(s.Cards), (s.Width+1)/(kpiMinWidth+1))
+}
+
+// cardWidth returns the width of every card.
+func (s kpiStrip) cardWidth() int {
+	n := s.perRow()
+	if n == 0 {
+		return 0
+	}
+	return (s.Width - (n - 1)) / n
+}
+
+// Height returns the number of lines the strip takes.
+func (s kpiStrip) Height() int {
+	n := s.perRow()
+	if n == 0 {
+		return 0
+	}
+	return (len(s.Cards) + n - 1) / n * kpiHeight
+}
+
+// Render draws the strip, without a trailing newline. Every card has the
+// same width, so the cards of a shorter last row line up with those above.
+func (s kpiStrip) Render() string {
+	n := s.perRow()
+	if n == 0 {
+		return ""
+	}
+	width := s.cardWidth()
+	var rows []string
+	for start := 0; start < len(s.Cards); start += n {
+		row := make([]string, kpiHeight)
+		for i, card := range s.Cards[start:min(start+n, len(s.Cards))] {
+			for j, line := range card.lines(width, s.Style, s.Good, s.Bad) {
+				if i > 0 {
+					row[j] += " "
+				}
+				row[j] += line
+			}
+		}
+		rows = append(rows, row...)
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/SCKelemen/dataviz"
+)
+
+func trendOf(values ...int) []dataviz.TimeSeriesData {
+	trend := make([]dataviz.TimeSeriesData, len(values))
+	for i, v := range values {
+		trend[i].Value = v
+	}
+	return trend
+}
+
+func TestKPICardChange(t *testing.T) {
+	tests := []struct {
+		name              string
+		current, previous float64
+		lowerIsBetter     bool
+		text, color       string
+	}{
+		{"up", 110, 100, false, "▲ +10.0% vs last hour", "good"},
+		{"down", 90, 100, false, "▼ -10.0% vs last hour", "bad"},
+		{"down is better", 90, 100, true, "▼ -10.0% vs last hour", "good"},
+		{"held", 100, 100, false, "= +0.0% vs last hour", ""},
+		{"from zero", 3, 0, false, "▲ +3 vs last hour", "good"},
+	}
+	for _, tt := range tests {
+		c := newKPICard("Errors", "x", tt.current, tt.previous, nil)
+		c.Subtitle = "vs last hour"
+		c.LowerIsBetter = tt.lowerIsBetter
+		text, color := c.changeText("good", "bad")
+		if text != tt.text || color != tt.color {
+			t.Errorf("%s: changeText = %q, %q, want %q, %q", tt.name, text, color, tt.text, tt.color)
+		}
+	}
+}
+
+func TestKPICardTrendText(t *testing.T) {
+	tests := []struct {
+		trend []dataviz.TimeSeriesData
+		width int
+		want  string
+	}{
+		{trendOf(0, 7, 14), 10, "▁▅█"},
+		{trendOf(0, 0, 14, 14), 2, "▁█"},
+		{trendOf(5, 5), 4, "▅▅"},
+		{nil, 4, ""},
+	}
+	for _, tt := range tests {
+		c := kpiCard{StatCardData: dataviz.StatCardData{TrendData: tt.trend}}
+		if got := c.trendText(tt.width); got != tt.want {
+			t.Errorf("trendText(%d) of %v = %q, want %q", tt.width, tt.trend, got, tt.want)
+		}
+	}
+}
+
+func TestKPIStripLayout(t *testing.T) {
+	cards := make([]kpiCard, 3)
+	for i := range cards {
+		cards[i] = newKPICard("Latency", "180 ms", 180, 200, trendOf(3, 1, 2))
+		cards[i].Subtitle = "vs last hour"
+	}
+	tests := []struct {
+		width, perRow, cardWidth, height int
+	}{
+		{100, 3, 32, kpiHeight},
+		{60, 2, 29, 2 * kpiHeight},
+		{30, 1, 30, 3 * kpiHeight},
+		{20, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		s := kpiStrip{Cards: cards, Width: tt.width, Style: LightBorderStyle}
+		if s.perRow() != tt.perRow || s.cardWidth() != tt.cardWidth || s.Height() != tt.height {
+			t.Errorf("width %d: %d per row, %d wide, %d high, want %d, %d, %d",
+				tt.width, s.perRow(), s.cardWidth(), s.Height(), tt.perRow, tt.cardWidth, tt.height)
+		}
+		out := s.Render()
+		if tt.height == 0 {
+			if out != "" {
+				t.Errorf("width %d: rendered %q, want nothing", tt.width, out)
+			}
+			continue
+		}
+		lines := strings.Split(out, "\n")
+		if len(lines) != tt.height {
+			t.Errorf("width %d: %d lines, want %d", tt.width, len(lines), tt.height)
+		}
+		for i, line := range lines {
+			// The last row may hold fewer cards.
+			n := min(tt.perRow, len(cards)-i/kpiHeight*tt.perRow)
+			rowWidth := n*tt.cardWidth + n - 1
+			if got := utf8.RuneCountInString(stripANSI(line)); got != rowWidth {
+				t.Errorf("width %d: line %d is %d wide, want %d: %q", tt.width, i, got, rowWidth, stripANSI(line))
+			}
+		}
+	}
+}
+
+func TestKPICardLongLabel(t *testing.T) {
+	c := newKPICard("Température moyenne journalière", "21 °C", 21, 20, trendOf(1, 2))
+	for i, line := range c.lines(20, LightBorderStyle, "", "") {
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d is not valid UTF-8: %q", i, line)
+		}
+		if got := utf8.RuneCountInString(stripANSI(line)); got != 20 {
+			t.Errorf("line %d is %d wide, want 20: %q", i, got, stripANSI(line))
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// svgShape is a single drawable element of an svgDocument.
+type svgShape interface {
+	writeSVG(b *strings.Builder)
+}
+
+// svgDocument is a standalone SVG image assembled from simple shapes. The
+// shapes are kept as values rather than markup so the same drawing can be
+// inspected or re-encoded by other outputs. Colors and other attribute
+// values may come from user data, so they are escaped like text.
+type svgDocument struct {
+	Width      int
+	Height     int
+	Background string
+	Shapes     []svgShape
+}
+
+// newSVGDocument creates an empty document of the given size.
+func newSVGDocument(width, height int, background string) *svgDocument {
+	return &svgDocument{Width: width, Height: height, Background: background}
+}
+
+// Add appends shapes in paint order.
+func (d *svgDocument) Add(shapes ...svgShape) {
+	d.Shapes = append(d.Shapes, shapes...)
+}
+
+// String serializes the document.
+func (d *svgDocument) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		d.Width, d.Height, d.Width, d.Height)
+	if d.Background != "" {
+		fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, html.EscapeString(d.Background))
+	}
+	for _, s := range d.Shapes {
+		s.writeSVG(&b)
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// svgPoint is a coordinate in document space.
+type svgPoint struct {
+	X, Y float64
+}
+
+type svgLine struct {
+	X1, Y1, X2, Y2 float64
+	Stroke         string
+	Width          float64
+	Opacity        float64
+	Dash           string
+}
+
+func (l svgLine) writeSVG(b *strings.Builder) {
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s" stroke-width="%.1f"`,
+		l.X1, l.Y1, l.X2, l.Y2, html.EscapeString(l.Stroke), l.Width)
+	writeOpacity(b, "stroke-opacity", l.Opacity)
+	if l.Dash != "" {
+		fmt.Fprintf(b, ` stroke-dasharray="%s"`, html.EscapeString(l.Dash))
+	}
+	b.WriteString("/>")
+}
+
+type svgPolyline struct {
+	Points []svgPoint
+	Stroke string
+	Width  float64
+}
+
+func (p svgPolyline) writeSVG(b *strings.Builder) {
+	b.WriteString(`<polyline points="`)
+	writePoints(b, p.Points)
+	fmt.Fprintf(b, `" fill="none" stroke="%s" stroke-width="%.1f" stroke-linejoin="round" stroke-linecap="round"/>`,
+		html.EscapeString(p.Stroke), p.Width)
+}
+
+type svgPolygon struct {
+	Points  []svgPoint
+	Fill    string
+	Opacity float64
+	Title   string // tooltip
+}
+
+func (p svgPolygon) writeSVG(b *strings.Builder) {
+	b.WriteString(`<polygon points="`)
+	writePoints(b, p.Points)
+	fmt.Fprintf(b, `" fill="%s"`, html.EscapeString(p.Fill))
+	writeOpacity(b, "fill-opacity", p.Opacity)
+	closeWithTitle(b, "polygon", p.Title)
+}
+
+type svgRect struct {
+	X, Y, W, H  float64
+	Fill        string
+	Opacity     float64
+	Radius      float64
+	Stroke      string // outline, if set
+	StrokeWidth float64
+	Title       string // tooltip
+}
+
+func (r svgRect) writeSVG(b *strings.Builder) {
+	fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"`, r.X, r.Y, r.W, r.H, html.EscapeString(r.Fill))
+	if r.Radius > 0 {
+		fmt.Fprintf(b, ` rx="%.1f"`, r.Radius)
+	}
+	writeOpacity(b, "fill-opacity", r.Opacity)
+	writeStroke(b, r.Stroke, r.StrokeWidth)
+	closeWithTitle(b, "rect", r.Title)
+}
+
+type svgCircle struct {
+	CX, CY, R   float64
+	Fill        string
+	Opacity     float64
+	Stroke      string // outline, if set
+	StrokeWidth float64
+	Title       string // tooltip
+}
+
+func (c svgCircle) writeSVG(b *strings.Builder) {
+	fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s"`, c.CX, c.CY, c.R, html.EscapeString(c.Fill))
+	writeOpacity(b, "fill-opacity", c.Opacity)
+	writeStroke(b, c.Stroke, c.StrokeWidth)
+	closeWithTitle(b, "circle", c.Title)
+}
+
+// svgText is a single line of text. Anchor is "start", "middle" or "end".
+// Mono selects a monospace font for text laid out on a character grid.
+type svgText struct {
+	X, Y   float64
+	Text   string
+	Fill   string
+	Size   float64
+	Anchor string
+	Mono   bool
+	Bold   bool
+}
+
+func (t svgText) writeSVG(b *strings.Builder) {
+	anchor := t.Anchor
+	if anchor == "" {
+		anchor = "start"
+	}
+	family := "system-ui, sans-serif"
+	if t.Mono {
+		family = "ui-monospace, Menlo, monospace"
+	}
+	fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="%s" font-size="%.1f" font-family="%s" text-anchor="%s"`,
+		t.X, t.Y, html.EscapeString(t.Fill), t.Size, family, html.EscapeString(anchor))
+	if t.Bold {
+		b.WriteString(` font-weight="bold"`)
+	}
+	fmt.Fprintf(b, ">%s</text>", html.EscapeString(t.Text))
+}
+
+// svgGroup places markup that is already rendered, such as a chart from
+// another renderer, with its origin at X, Y.
+type svgGroup struct {
+	X, Y    float64
+	Content string
+}
+
+func (g svgGroup) writeSVG(b *strings.Builder) {
+	fmt.Fprintf(b, `<g transform="translate(%.1f,%.1f)">%s</g>`, g.X, g.Y, g.Content)
+}
+
+func writePoints(b *strings.Builder, points []svgPoint) {
+	for i, p := range points {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(b, "%.1f,%.1f", p.X, p.Y)
+	}
+}
+
+// closeWithTitle ends an element, nesting a <title> tooltip when one is set.
+func closeWithTitle(b *strings.Builder, element, title string) {
+	if title == "" {
+		b.WriteString("/>")
+		return
+	}
+	fmt.Fprintf(b, "><title>%s</title></%s>", html.EscapeString(title), element)
+}
+
+// writeOpacity writes an opacity attribute unless it is unset (0) or opaque.
+func writeOpacity(b *strings.Builder, attr string, opacity float64) {
+	if opacity > 0 && opacity < 1 {
+		fmt.Fprintf(b, ` %s="%.2f"`, attr, opacity)
+	}
+}
+
+// writeStroke adds an outline to a filled shape when a color is given.
+func writeStroke(b *strings.Builder, color string, width float64) {
+	if color == "" {
+		return
+	}
+	fmt.Fprintf(b, ` stroke="%s" stroke-width="%.1f"`, html.EscapeString(color), width)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// injected is a color that would close its attribute and open an element
+// if it were written unescaped.
+const injected = `"/><script>alert(1)</script><x a="`
+
+func TestSVGAttributesEscaped(t *testing.T) {
+	shapes := []svgShape{
+		svgLine{X2: 10, Y2: 10, Stroke: injected, Width: 1, Dash: injected},
+		svgPolyline{Points: []svgPoint{{0, 0}, {5, 5}}, Stroke: injected, Width: 1},
+		svgPolygon{Points: []svgPoint{{0, 0}, {5, 0}, {5, 5}}, Fill: injected, Title: injected},
+		svgRect{W: 5, H: 5, Fill: injected, Stroke: injected, StrokeWidth: 1, Title: injected},
+		svgCircle{R: 5, Fill: injected, Stroke: injected, StrokeWidth: 1},
+		svgText{Text: injected, Fill: injected, Size: 10, Anchor: injected},
+	}
+	for _, shape := range shapes {
+		doc := newSVGDocument(10, 10, injected)
+		doc.Add(shape)
+		out := doc.String()
+		if strings.Contains(out, "<script>") {
+			t.Errorf("%T: markup injected into\n%s", shape, out)
+		}
+		if err := checkXML(out); err != nil {
+			t.Errorf("%T: %v in\n%s", shape, err, out)
+		}
+	}
+}
+
+func TestSVGColorRoundTrip(t *testing.T) {
+	doc := newSVGDocument(10, 10, "")
+	doc.Add(svgRect{W: 5, H: 5, Fill: `a"b&c`})
+	var parsed struct {
+		Rect struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"rect"`
+	}
+	if err := xml.Unmarshal([]byte(doc.String()), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Rect.Fill != `a"b&c` {
+		t.Errorf("fill = %q, want %q", parsed.Rect.Fill, `a"b&c`)
+	}
+}
+
+// checkXML reports whether s is well-formed XML.
+func checkXML(s string) error {
+	d := xml.NewDecoder(strings.NewReader(s))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
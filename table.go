@@ -0,0 +1,527 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SCKelemen/dataviz"
+	"github.com/SCKelemen/text"
+)
+
+// tableColumn describes one column of a table. Style is empty for plain
+// values, "bar" for an inline bar after each value, or "heat" for cell
+// backgrounds shaded by value.
+type tableColumn struct {
+	Name  string `json:"name"`
+	Style string `json:"style"`
+}
+
+// UnmarshalJSON accepts a bare column name as well as an object.
+func (c *tableColumn) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*c = tableColumn{Name: name}
+		return nil
+	}
+	type plain tableColumn
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// tableCell is one table value. Numeric cells keep their value for bars,
+// shading and sorting, and are printed with -number-format.
+type tableCell struct {
+	Text    string
+	Value   float64
+	Numeric bool
+}
+
+// UnmarshalJSON accepts numbers, strings, booleans and null.
+func (c *tableCell) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		*c = tableCell{Value: v, Numeric: true}
+	case string:
+		*c = tableCell{Text: v}
+	case nil:
+		*c = tableCell{}
+	default:
+		*c = tableCell{Text: fmt.Sprint(v)}
+	}
+	return nil
+}
+
+// tableInput is the JSON accepted by -type table. CSV with a header row is
+// accepted too; see parseTable.
+type tableInput struct {
+	Columns []tableColumn `json:"columns"`
+	Rows    [][]tableCell `json:"rows"`
+	border  string
+}
+
+// tableOptions are the table settings from the command line. Columns are
+// named case-insensitively; Sort is a column name, prefixed with "-" for
+// descending order.
+type tableOptions struct {
+	Sort   string
+	Bars   []string
+	Heat   []string
+	Border string
+}
+
+// parseTable reads a table from JSON, or from CSV when the data does not
+// start with "{". In CSV input the first record names the columns and
+// cells that parse as numbers are numeric.
+func parseTable(data []byte) (tableInput, error) {
+	var in tableInput
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err := json.Unmarshal(trimmed, &in)
+		return in, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return in, err
+	}
+	if len(records) == 0 {
+		return in, fmt.Errorf("no header row")
+	}
+	for _, name := range records[0] {
+		in.Columns = append(in.Columns, tableColumn{Name: name})
+	}
+	for _, record := range records[1:] {
+		row := make([]tableCell, len(record))
+		for i, field := range record {
+			if v, err := strconv.ParseFloat(strings.TrimSpace(field), 64); err == nil {
+				row[i] = tableCell{Value: v, Numeric: true}
+			} else {
+				row[i] = tableCell{Text: field}
+			}
+		}
+		in.Rows = append(in.Rows, row)
+	}
+	return in, nil
+}
+
+// column returns the index of the named column.
+func (in tableInput) column(name string) (int, error) {
+	for i, c := range in.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown column %q", name)
+}
+
+// cell returns the cell of row in column col; short rows read as empty.
+func (in tableInput) cell(row []tableCell, col int) tableCell {
+	if col < len(row) {
+		return row[col]
+	}
+	return tableCell{}
+}
+
+// apply sets the border and column styles from opts and sorts the rows.
+func (in *tableInput) apply(opts tableOptions) error {
+	in.border = opts.Border
+	for _, styled := range []struct {
+		names []string
+		style string
+	}{{opts.Bars, "bar"}, {opts.Heat, "heat"}} {
+		for _, name := range styled.names {
+			i, err := in.column(name)
+			if err != nil {
+				return err
+			}
+			in.Columns[i].Style = styled.style
+		}
+	}
+	for _, c := range in.Columns {
+		if c.Style != "" && c.Style != "bar" && c.Style != "heat" {
+			return fmt.Errorf("column %q: unknown style %q", c.Name, c.Style)
+		}
+	}
+
+	if opts.Sort == "" {
+		return nil
+	}
+	name, descending := strings.CutPrefix(opts.Sort, "-")
+	col, err := in.column(name)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(in.Rows, func(a, b int) bool {
+		x, y := in.cell(in.Rows[a], col), in.cell(in.Rows[b], col)
+		if descending {
+			x, y = y, x
+		}
+		switch {
+		case x.Numeric && y.Numeric:
+			return x.Value < y.Value
+		case x.Numeric != y.Numeric:
+			// Numbers sort before text either way.
+			return x.Numeric != descending
+		default:
+			return x.Text < y.Text
+		}
+	})
+	return nil
+}
+
+// tableLayout is a table sized to a grid of character cells, shared by the
+// terminal and SVG renderings so that both have the same shape.
+type tableLayout struct {
+	columns  []tableColumn
+	numeric  []bool     // every non-empty cell of the column is a number
+	widths   []int      // text width of each column
+	lo, hi   []float64  // value range of each numeric column
+	text     [][]string // formatted cells of the rows shown
+	values   [][]tableCell
+	barWidth int
+	hidden   int // rows that did not fit
+	border   BorderStyle
+}
+
+// cellWidth is the width of column i's content, including any bar.
+func (l tableLayout) cellWidth(i int) int {
+	if l.columns[i].Style == "bar" {
+		return l.widths[i] + 1 + l.barWidth
+	}
+	return l.widths[i]
+}
+
+// totalWidth is the width of a whole table line.
+func (l tableLayout) totalWidth() int {
+	total := 0
+	for i := range l.columns {
+		total += l.cellWidth(i)
+	}
+	if l.border.Vertical == "" {
+		return total + 2*max(0, len(l.columns)-1)
+	}
+	return total + 3*len(l.columns) + 1
+}
+
+// ratio positions a numeric cell within its column's range.
+func (l tableLayout) ratio(col int, v float64) float64 {
+	if l.columns[col].Style == "bar" {
+		// Bars grow from zero; negative values draw no bar.
+		if l.hi[col] <= 0 {
+			return 0
+		}
+		return math.Max(0, v/l.hi[col])
+	}
+	if l.hi[col] == l.lo[col] {
+		return 1
+	}
+	return (v - l.lo[col]) / (l.hi[col] - l.lo[col])
+}
+
+// layout fits the table into width x height cells. Text columns are
+// truncated when the table is too wide and rows beyond the height are
+// counted in hidden.
+func (in tableInput) layout(width, height int, opts chartOptions) tableLayout {
+	measure := text.NewTerminal()
+	n := len(in.Columns)
+	l := tableLayout{
+		columns: in.Columns,
+		numeric: make([]bool, n),
+		widths:  make([]int, n),
+		lo:      make([]float64, n),
+		hi:      make([]float64, n),
+		border:  borderStyles[in.border],
+	}
+
+	overhead := 1 // header
+	if l.border.Vertical != "" {
+		overhead = 4 // top, header, separator, bottom
+	}
+	rows := in.Rows
+	if len(rows) > height-overhead {
+		shown := max(0, height-overhead-1)
+		l.hidden = len(rows) - shown
+		rows = rows[:shown]
+	}
+
+	for i, c := range in.Columns {
+		l.widths[i] = int(measure.Width(c.Name))
+		l.numeric[i] = true
+		l.lo[i], l.hi[i] = math.Inf(1), math.Inf(-1)
+		for _, row := range in.Rows {
+			cell := in.cell(row, i)
+			if cell.Numeric {
+				l.lo[i], l.hi[i] = math.Min(l.lo[i], cell.Value), math.Max(l.hi[i], cell.Value)
+			} else if cell.Text != "" {
+				l.numeric[i] = false
+			}
+		}
+	}
+	for _, row := range rows {
+		cells := make([]tableCell, n)
+		texts := make([]string, n)
+		for i := range in.Columns {
+			cells[i] = in.cell(row, i)
+			texts[i] = cells[i].Text
+			if cells[i].Numeric {
+				texts[i] = opts.Numbers.Format(cells[i].Value)
+			}
+			l.widths[i] = max(l.widths[i], int(measure.Width(texts[i])))
+		}
+		l.values = append(l.values, cells)
+		l.text = append(l.text, texts)
+	}
+
+	bars := 0
+	for _, c := range in.Columns {
+		if c.Style == "bar" {
+			bars++
+		}
+	}
+	if bars > 0 {
+		spare := width - l.totalWidth() - bars
+		l.barWidth = min(20, max(4, spare/bars))
+	}
+
+	// Narrow the widest text column until the table fits.
+	for l.totalWidth() > width {
+		widest := -1
+		for i := range l.columns {
+			if !l.numeric[i] && l.widths[i] > 3 && (widest < 0 || l.widths[i] > l.widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		l.widths[widest]--
+	}
+	return l
+}
+
+// fitText truncates s to width cells with an ellipsis and pads it to
+// exactly width, aligned right when right is set.
+func fitText(s string, width int, right bool) string {
+	measure := text.NewTerminal()
+	if int(measure.Width(s)) > width {
+		runes := []rune(s)
+		for len(runes) > 0 && int(measure.Width(string(runes)))+1 > width {
+			runes = runes[:len(runes)-1]
+		}
+		s = string(runes) + "…"
+	}
+	pad := strings.Repeat(" ", max(0, width-int(measure.Width(s))))
+	if right {
+		return pad + s
+	}
+	return s + pad
+}
+
+// heatColor returns the background for a shaded cell: the theme background
+// blended toward the chart color by the cell's position in its column.
+func heatColor(ratio float64, config dataviz.RenderConfig) string {
+	background, _ := themeColors(config)
+	if background == "" {
+		background = "#FFFFFF"
+	}
+	return mixColors(background, config.Color, 0.15+0.85*ratio)
+}
+
+// Terminal implements chart.
+func (in tableInput) Terminal(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	if len(in.Columns) == 0 {
+		return ""
+	}
+	l := in.layout(bounds.Width, bounds.Height, opts)
+	bs := l.border
+	framed := bs.Vertical != ""
+
+	rule := func(left, mid, right string) string {
+		parts := make([]string, len(l.columns))
+		for i := range l.columns {
+			parts[i] = strings.Repeat(bs.Horizontal, l.cellWidth(i)+2)
+		}
+		return ansiDim + left + strings.Join(parts, mid) + right + ansiReset + "\n"
+	}
+	line := func(cells []string) string {
+		if !framed {
+			return strings.TrimRight(strings.Join(cells, "  "), " ") + "\n"
+		}
+		edge := ansiDim + bs.Vertical + ansiReset
+		var b strings.Builder
+		b.WriteString(edge)
+		for _, c := range cells {
+			b.WriteString(c)
+			b.WriteString(edge)
+		}
+		b.WriteString("\n")
+		return b.String()
+	}
+	// pad surrounds a cell with its one-column margins when framed.
+	pad := func(s string) string {
+		if framed {
+			return " " + s + " "
+		}
+		return s
+	}
+
+	var b strings.Builder
+	if framed {
+		b.WriteString(rule(bs.TopLeft, bs.TeeDown, bs.TopRight))
+	}
+	header := make([]string, len(l.columns))
+	for i, c := range l.columns {
+		header[i] = pad(ansiBold + fitText(c.Name, l.cellWidth(i), l.numeric[i] && c.Style != "bar") + ansiReset)
+	}
+	b.WriteString(line(header))
+	if framed {
+		b.WriteString(rule(bs.TeeRight, bs.Cross, bs.TeeLeft))
+	}
+
+	for r, texts := range l.text {
+		cells := make([]string, len(l.columns))
+		for i, c := range l.columns {
+			value := l.values[r][i]
+			content := fitText(texts[i], l.widths[i], l.numeric[i])
+			switch {
+			case c.Style == "bar" && value.Numeric:
+				bar := barCells(l.ratio(i, value.Value), l.barWidth, -1)
+				for j, cell := range bar {
+					if cell == "" {
+						bar[j] = " "
+					}
+				}
+				cells[i] = pad(content + " " + colorize(strings.Join(bar, ""), config.Color))
+			case c.Style == "bar":
+				cells[i] = pad(content + strings.Repeat(" ", 1+l.barWidth))
+			case c.Style == "heat" && value.Numeric:
+				bg := heatColor(l.ratio(i, value.Value), config)
+				cells[i] = ansiBackground(bg) + ansiColor(contrastText(bg)) + pad(content) + ansiReset
+			default:
+				cells[i] = pad(content)
+			}
+		}
+		b.WriteString(line(cells))
+	}
+
+	if framed {
+		b.WriteString(rule(bs.BottomLeft, bs.TeeUp, bs.BottomRight))
+	}
+	if l.hidden > 0 {
+		fmt.Fprintf(&b, "%s… %d more rows%s\n", ansiDim, l.hidden, ansiReset)
+	}
+	return b.String()
+}
+
+// SVG implements chart. The table is laid out on the same character grid
+// as in the terminal, in a monospace font, with the border rules drawn as
+// lines between rows and columns.
+func (in tableInput) SVG(bounds dataviz.Bounds, config dataviz.RenderConfig, opts chartOptions) string {
+	const (
+		fontSize  = 12.0
+		charWidth = fontSize * 0.6
+		rowHeight = 20.0
+	)
+	background, textColor := themeColors(config)
+	doc := newSVGDocument(bounds.Width, bounds.Height, background)
+	if len(in.Columns) == 0 {
+		return doc.String()
+	}
+
+	cols := int((float64(bounds.Width) - 2*svgPadding) / charWidth)
+	lines := int((float64(bounds.Height) - 2*svgPadding) / rowHeight)
+	framed := borderStyles[in.border].Vertical != ""
+	if framed {
+		// The border rules are drawn between rows rather than taking rows
+		// of their own.
+		lines += 3
+	}
+	l := in.layout(cols, lines, opts)
+
+	// starts[i] is the first character cell of column i's content, laid
+	// out as in the terminal: "│ a │ b │" or "a  b".
+	starts := make([]int, len(l.columns))
+	pos, gap := 0, 2
+	if framed {
+		pos, gap = 2, 3
+	}
+	for i := range l.columns {
+		starts[i] = pos
+		pos += l.cellWidth(i) + gap
+	}
+	pos -= gap
+	if framed {
+		pos += 2
+	}
+	x := func(cell int) float64 { return svgPadding + float64(cell)*charWidth }
+	baseline := func(row int) float64 { return svgPadding + float64(row)*rowHeight + rowHeight*0.7 }
+	left, right := x(0), x(pos)
+	if framed {
+		left, right = x(0)+charWidth/2, x(pos)-charWidth/2
+	}
+	bottom := svgPadding + float64(len(l.text)+1)*rowHeight
+
+	for r, texts := range l.text {
+		top := svgPadding + float64(r+1)*rowHeight
+		for i, c := range l.columns {
+			value := l.values[r][i]
+			if c.Style == "heat" && value.Numeric {
+				bg := heatColor(l.ratio(i, value.Value), config)
+				doc.Add(svgRect{X: x(starts[i]) - charWidth, Y: top, W: float64(l.cellWidth(i)+2) * charWidth, H: rowHeight, Fill: bg})
+				doc.Add(svgText{X: x(starts[i] + l.widths[i]), Y: baseline(r + 1), Text: texts[i], Fill: contrastText(bg), Size: fontSize, Anchor: "end", Mono: true})
+				continue
+			}
+			if c.Style == "bar" && value.Numeric {
+				doc.Add(svgRect{
+					X: x(starts[i] + l.widths[i] + 1), Y: top + 4,
+					W: l.ratio(i, value.Value) * float64(l.barWidth) * charWidth, H: rowHeight - 8,
+					Fill: config.Color, Radius: 2,
+				})
+			}
+			tx, anchor := x(starts[i]), "start"
+			if l.numeric[i] {
+				tx, anchor = x(starts[i]+l.widths[i]), "end"
+			}
+			doc.Add(svgText{X: tx, Y: baseline(r + 1), Text: fitText(texts[i], l.widths[i], false), Fill: textColor, Size: fontSize, Anchor: anchor, Mono: true})
+		}
+	}
+	for i, c := range l.columns {
+		tx, anchor := x(starts[i]), "start"
+		if l.numeric[i] && c.Style != "bar" {
+			tx, anchor = x(starts[i]+l.cellWidth(i)), "end"
+		}
+		doc.Add(svgText{X: tx, Y: baseline(0), Text: fitText(c.Name, l.cellWidth(i), false), Fill: textColor, Size: fontSize, Anchor: anchor, Mono: true, Bold: true})
+	}
+
+	if framed {
+		rule := func(x1, y1, x2, y2 float64) svgLine {
+			return svgLine{X1: x1, Y1: y1, X2: x2, Y2: y2, Stroke: textColor, Width: 1, Opacity: 0.3}
+		}
+		doc.Add(
+			rule(left, svgPadding, right, svgPadding),
+			rule(left, svgPadding+rowHeight, right, svgPadding+rowHeight),
+			rule(left, bottom, right, bottom),
+			rule(left, svgPadding, left, bottom),
+			rule(right, svgPadding, right, bottom),
+		)
+		for i := 1; i < len(l.columns); i++ {
+			sep := x(starts[i]) - 1.5*charWidth
+			doc.Add(rule(sep, svgPadding, sep, bottom))
+		}
+	}
+	if l.hidden > 0 {
+		doc.Add(svgText{X: left, Y: bottom + rowHeight*0.7, Text: fmt.Sprintf("… %d more rows", l.hidden), Fill: textColor, Size: fontSize, Mono: true})
+	}
+	return doc.String()
+}
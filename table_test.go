@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		columns []string
+		cells   [][]tableCell
+	}{
+		{
+			"csv",
+			"repo, stars\nviz, 1200\ncli, n/a\n",
+			[]string{"repo", "stars"},
+			[][]tableCell{{{Text: "viz"}, {Value: 1200, Numeric: true}}, {{Text: "cli"}, {Text: "n/a"}}},
+		},
+		{
+			"json",
+			`{"columns": ["repo", {"name": "stars", "style": "bar"}], "rows": [["viz", 1200], ["cli", null]]}`,
+			[]string{"repo", "stars"},
+			[][]tableCell{{{Text: "viz"}, {Value: 1200, Numeric: true}}, {{Text: "cli"}, {}}},
+		},
+	}
+	for _, tt := range tests {
+		in, err := parseTable([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var columns []string
+		for _, c := range in.Columns {
+			columns = append(columns, c.Name)
+		}
+		if !slices.Equal(columns, tt.columns) {
+			t.Errorf("%s: columns = %q, want %q", tt.name, columns, tt.columns)
+		}
+		for i, row := range tt.cells {
+			for j, cell := range row {
+				if got := in.cell(in.Rows[i], j); got != cell {
+					t.Errorf("%s: cell %d,%d = %+v, want %+v", tt.name, i, j, got, cell)
+				}
+			}
+		}
+	}
+}
+
+func TestTableApply(t *testing.T) {
+	data := "name,score\nb,2\na,10\nc,x\nd,1\n"
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		{"score", []string{"d", "b", "a", "c"}},
+		{"-SCORE", []string{"a", "b", "d", "c"}},
+		{"name", []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		in, _ := parseTable([]byte(data))
+		if err := in.apply(tableOptions{Sort: tt.sort, Bars: []string{"Score"}, Border: "rounded"}); err != nil {
+			t.Errorf("sort %q: %v", tt.sort, err)
+			continue
+		}
+		var names []string
+		for _, row := range in.Rows {
+			names = append(names, row[0].Text)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("sort %q: rows %q, want %q", tt.sort, names, tt.want)
+		}
+		if in.Columns[1].Style != "bar" || in.border != "rounded" {
+			t.Errorf("sort %q: style %q and border %q not applied", tt.sort, in.Columns[1].Style, in.border)
+		}
+	}
+
+	for _, opts := range []tableOptions{{Sort: "missing"}, {Heat: []string{"missing"}}} {
+		in, _ := parseTable([]byte(data))
+		if err := in.apply(opts); err == nil {
+			t.Errorf("apply(%+v) succeeded, want an unknown column error", opts)
+		}
+	}
+}
+
+func TestTableLayoutHidesRows(t *testing.T) {
+	in, _ := parseTable([]byte("n\n1\n2\n3\n4\n5\n"))
+	in.apply(tableOptions{Border: "none"})
+	// One line for the header and one for the count of hidden rows.
+	l := in.layout(20, 4, defaultChartOptions())
+	if len(l.text) != 2 || l.hidden != 3 {
+		t.Errorf("showed %d rows and hid %d, want 2 and 3", len(l.text), l.hidden)
+	}
+}
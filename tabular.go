@@ -0,0 +1,378 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// columnMapping picks the table columns that feed a chart. X names the
+// date or label column, Y the value columns and Series a column whose
+// values split the rows into series. Empty fields are inferred: X is the
+// first column, and Y every numeric column other than X and Series.
+type columnMapping struct {
+	X      string
+	Y      []string
+	Series string
+}
+
+// isTabular reports whether data is CSV rather than JSON: its first line
+// is a header of two or more columns with no JSON brackets in it. Anything
+// else is left to the JSON parser, so that malformed JSON is reported as
+// such.
+func isTabular(data []byte) bool {
+	header, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+	if bytes.ContainsAny(header, "{[") {
+		return false
+	}
+	fields, err := csv.NewReader(bytes.NewReader(header)).Read()
+	return err == nil && len(fields) > 1
+}
+
+// tabularData converts a table, from CSV or a database query, into the
+// JSON input of the chart type using the column mapping.
+func tabularData(data []byte, vizType string, mapping columnMapping) ([]byte, error) {
+	table, err := parseTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing table data: %w", err)
+	}
+	if len(table.Columns) == 0 {
+		return nil, fmt.Errorf("table has no columns")
+	}
+	m, err := table.mapColumns(mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	switch vizType {
+	case "line-graph", "area", "sparkline":
+		return m.lines()
+	case "heatmap":
+		return m.heatmap()
+	case "bar-chart":
+		return m.bars()
+	case "pie", "donut":
+		return m.slices()
+	case "scatter":
+		return m.scatter()
+	case "histogram":
+		return json.Marshal(map[string]any{"values": m.values(0)})
+	case "stat-card":
+		return m.stat()
+	case "gauge", "progress", "bullet":
+		return m.gauge()
+	}
+	return nil, fmt.Errorf("tabular input cannot draw %s", vizType)
+}
+
+// mappedTable is a table with its columns resolved: x, the y columns and
+// the optional series column, with x's type inferred.
+type mappedTable struct {
+	table  tableInput
+	x      int
+	y      []int
+	series int
+	dates  []time.Time
+}
+
+// mapColumns resolves the mapping against the table's columns and infers
+// whether the x column holds dates.
+func (in tableInput) mapColumns(mapping columnMapping) (mappedTable, error) {
+	m := mappedTable{table: in, series: -1}
+	var err error
+	if mapping.X != "" {
+		if m.x, err = in.column(mapping.X); err != nil {
+			return m, err
+		}
+	}
+	if mapping.Series != "" {
+		if m.series, err = in.column(mapping.Series); err != nil {
+			return m, err
+		}
+	}
+	for _, name := range mapping.Y {
+		col, err := in.column(name)
+		if err != nil {
+			return m, err
+		}
+		m.y = append(m.y, col)
+	}
+	if len(m.y) == 0 {
+		for col := range in.Columns {
+			if col != m.x && col != m.series && in.numericColumn(col) {
+				m.y = append(m.y, col)
+			}
+		}
+	}
+	if len(m.y) == 0 {
+		return m, fmt.Errorf("no numeric value column; name one with -y")
+	}
+
+	m.dates = make([]time.Time, len(in.Rows))
+	for i, row := range in.Rows {
+		t, ok := cellTime(in.cell(row, m.x))
+		if !ok {
+			m.dates = nil
+			break
+		}
+		m.dates[i] = t
+	}
+	return m, nil
+}
+
+// numericColumn reports whether every non-empty cell of col is a number.
+func (in tableInput) numericColumn(col int) bool {
+	seen := false
+	for _, row := range in.Rows {
+		c := in.cell(row, col)
+		switch {
+		case c.Numeric:
+			seen = true
+		case strings.TrimSpace(c.Text) != "":
+			return false
+		}
+	}
+	return seen
+}
+
+// cellTime reads a date from a text cell in RFC 3339 or a shorter ISO
+// form, as written by CSV exports and databases.
+func cellTime(c tableCell) (time.Time, bool) {
+	if c.Numeric {
+		return time.Time{}, false
+	}
+	text := strings.TrimSpace(c.Text)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// label returns the x cell of row as text.
+func (m mappedTable) label(row []tableCell) string {
+	c := m.table.cell(row, m.x)
+	if c.Numeric {
+		return fmt.Sprint(c.Value)
+	}
+	return c.Text
+}
+
+// values returns the numbers in the i-th y column, skipping empty cells.
+func (m mappedTable) values(i int) []float64 {
+	var values []float64
+	for _, row := range m.table.Rows {
+		if c := m.table.cell(row, m.y[i]); c.Numeric {
+			values = append(values, c.Value)
+		}
+	}
+	return values
+}
+
+// requireDates fails unless the x column holds dates.
+func (m mappedTable) requireDates() error {
+	if m.dates == nil {
+		return fmt.Errorf("column %q does not hold dates; name the date column with -x", m.table.Columns[m.x].Name)
+	}
+	return nil
+}
+
+// lines makes one series per y column, or per value of the series column.
+func (m mappedTable) lines() ([]byte, error) {
+	if err := m.requireDates(); err != nil {
+		return nil, err
+	}
+	var series []lineSeries
+	index := map[string]int{}
+	for r, row := range m.table.Rows {
+		for i, col := range m.y {
+			c := m.table.cell(row, col)
+			if !c.Numeric {
+				continue
+			}
+			label := m.table.Columns[col].Name
+			if m.series >= 0 {
+				label = m.table.cell(row, m.series).Text
+				if len(m.y) > 1 {
+					label += " " + m.table.Columns[m.y[i]].Name
+				}
+			}
+			s, ok := index[label]
+			if !ok {
+				s = len(series)
+				index[label] = s
+				series = append(series, lineSeries{Label: label})
+			}
+			series[s].Points = append(series[s].Points, timePoint{Date: m.dates[r], Value: c.Value})
+		}
+	}
+	for _, s := range series {
+		sort.SliceStable(s.Points, func(i, j int) bool { return s.Points[i].Date.Before(s.Points[j].Date) })
+	}
+	if len(series) == 1 {
+		return json.Marshal(map[string]any{"points": series[0].Points})
+	}
+	return json.Marshal(map[string]any{"series": series})
+}
+
+// heatmap counts the first y column at each row's date; the heatmap sums
+// them per cell of its layout.
+func (m mappedTable) heatmap() ([]byte, error) {
+	if err := m.requireDates(); err != nil {
+		return nil, err
+	}
+	var days []heatmapDay
+	for r, row := range m.table.Rows {
+		if c := m.table.cell(row, m.y[0]); c.Numeric {
+			days = append(days, heatmapDay{Date: m.dates[r], Count: c.Value})
+		}
+	}
+	sort.SliceStable(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+	return json.Marshal(map[string]any{"days": days})
+}
+
+// bars makes one bar per row labelled by x; several y columns, or a
+// series column, become the segments of each bar.
+func (m mappedTable) bars() ([]byte, error) {
+	if m.series >= 0 {
+		return m.seriesBars()
+	}
+	bars := make([]barValue, len(m.table.Rows))
+	for r, row := range m.table.Rows {
+		bars[r].Label = m.label(row)
+		if len(m.y) == 1 {
+			bars[r].Value = m.table.cell(row, m.y[0]).Value
+			continue
+		}
+		for _, col := range m.y {
+			bars[r].Values = append(bars[r].Values, m.table.cell(row, col).Value)
+		}
+	}
+	if len(m.y) == 1 {
+		return json.Marshal(map[string]any{"bars": bars})
+	}
+	segments := make([]barSegment, len(m.y))
+	for i, col := range m.y {
+		segments[i] = barSegment{Label: m.table.Columns[col].Name}
+	}
+	return json.Marshal(map[string]any{"segments": segments, "bars": bars})
+}
+
+// seriesBars pivots long rows of (x, series, y) into one bar per x value
+// with a segment per series value.
+func (m mappedTable) seriesBars() ([]byte, error) {
+	var labels, names []string
+	labelIndex, nameIndex := map[string]int{}, map[string]int{}
+	cells := map[[2]int]float64{}
+	for _, row := range m.table.Rows {
+		label, name := m.label(row), m.table.cell(row, m.series).Text
+		if _, ok := labelIndex[label]; !ok {
+			labelIndex[label] = len(labels)
+			labels = append(labels, label)
+		}
+		if _, ok := nameIndex[name]; !ok {
+			nameIndex[name] = len(names)
+			names = append(names, name)
+		}
+		cells[[2]int{labelIndex[label], nameIndex[name]}] += m.table.cell(row, m.y[0]).Value
+	}
+	bars := make([]barValue, len(labels))
+	for i, label := range labels {
+		bars[i] = barValue{Label: label, Values: make([]float64, len(names))}
+		for j := range names {
+			bars[i].Values[j] = cells[[2]int{i, j}]
+		}
+	}
+	segments := make([]barSegment, len(names))
+	for j, name := range names {
+		segments[j] = barSegment{Label: name}
+	}
+	return json.Marshal(map[string]any{"segments": segments, "bars": bars})
+}
+
+// slices makes one slice per row, labelled by x.
+func (m mappedTable) slices() ([]byte, error) {
+	slices := make([]pieSlice, 0, len(m.table.Rows))
+	for _, row := range m.table.Rows {
+		slices = append(slices, pieSlice{Label: m.label(row), Value: m.table.cell(row, m.y[0]).Value})
+	}
+	return json.Marshal(map[string]any{"slices": slices})
+}
+
+// scatter plots the first y column against x, which may be numbers or
+// dates, with one series per value of the series column.
+func (m mappedTable) scatter() ([]byte, error) {
+	var series []scatterSeries
+	index := map[string]int{}
+	for r, row := range m.table.Rows {
+		y := m.table.cell(row, m.y[0])
+		x := m.table.cell(row, m.x)
+		if !y.Numeric || (m.dates == nil && !x.Numeric) {
+			continue
+		}
+		p := scatterPoint{X: x.Value, Y: y.Value}
+		if m.dates != nil {
+			p = scatterPoint{Date: m.dates[r], Y: y.Value}
+		}
+		label := m.table.Columns[m.y[0]].Name
+		if m.series >= 0 {
+			label = m.table.cell(row, m.series).Text
+		}
+		s, ok := index[label]
+		if !ok {
+			s = len(series)
+			index[label] = s
+			series = append(series, scatterSeries{Label: label})
+		}
+		series[s].Points = append(series[s].Points, p)
+	}
+	if len(series) == 0 {
+		return nil, fmt.Errorf("column %q holds neither numbers nor dates", m.table.Columns[m.x].Name)
+	}
+	return json.Marshal(map[string]any{"series": series})
+}
+
+// last returns the last value of the first y column.
+func (m mappedTable) last() (float64, error) {
+	values := m.values(0)
+	if len(values) == 0 {
+		return 0, fmt.Errorf("column %q has no values", m.table.Columns[m.y[0]].Name)
+	}
+	return values[len(values)-1], nil
+}
+
+// stat shows the last value of the first y column, with the column as a
+// trend when x holds dates.
+func (m mappedTable) stat() ([]byte, error) {
+	value, err := m.last()
+	if err != nil {
+		return nil, err
+	}
+	card := map[string]any{"title": m.table.Columns[m.y[0]].Name, "value": value}
+	if m.dates != nil {
+		var trend []timePoint
+		for r, row := range m.table.Rows {
+			if c := m.table.cell(row, m.y[0]); c.Numeric {
+				trend = append(trend, timePoint{Date: m.dates[r], Value: c.Value})
+			}
+		}
+		card["trendData"] = trend
+	}
+	return json.Marshal(card)
+}
+
+// gauge shows the last value of the first y column.
+func (m mappedTable) gauge() ([]byte, error) {
+	value, err := m.last()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{"label": m.table.Columns[m.y[0]].Name, "value": value})
+}
@@ -0,0 +1,152 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const tabularCSV = `day,kind,events,errors
+2024-03-02,deploy,4,1
+2024-03-01,deploy,3,0
+2024-03-01,alert,2,2
+`
+
+func TestIsTabular(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{tabularCSV, true},
+		{"\n  day,events\n2024-03-01,3\n", true},
+		{`"name, first",team` + "\n", true},
+		{`{"points": []}`, false},
+		{"[1, 2]", false},
+		{`x{"points": [1, 2]}`, false},
+		{")]}'\n{\"points\": []}", false},
+		{"events\n3\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isTabular([]byte(tt.data)); got != tt.want {
+			t.Errorf("isTabular(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestRenderReportsMalformedJSON(t *testing.T) {
+	cfg, err := parseFlags(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-type", "line-graph"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = render(cfg, []byte(`x{"points": [{"date": "2024-03-01T00:00:00Z", "value": 1}]}`))
+	if err == nil || !strings.Contains(err.Error(), "parsing line graph data") {
+		t.Errorf("render = %v, want a JSON parse error", err)
+	}
+}
+
+func TestTabularLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping columnMapping
+		labels  []string
+	}{
+		{"numeric columns inferred", columnMapping{}, []string{"events", "errors"}},
+		{"named y", columnMapping{Y: []string{"errors"}}, nil},
+		{"series column", columnMapping{X: "day", Y: []string{"events"}, Series: "kind"}, []string{"deploy", "alert"}},
+		{"series and several y", columnMapping{Series: "kind"}, []string{"deploy events", "deploy errors", "alert events", "alert errors"}},
+	}
+	for _, tt := range tests {
+		out, err := tabularData([]byte(tabularCSV), "line-graph", tt.mapping)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var in lineGraphInput
+		if err := json.Unmarshal(out, &in); err != nil {
+			t.Fatal(err)
+		}
+		if tt.labels == nil {
+			if len(in.Points) != 3 || !in.Points[0].Date.Before(in.Points[2].Date) {
+				t.Errorf("%s: points = %+v, want three sorted by date", tt.name, in.Points)
+			}
+			continue
+		}
+		var labels []string
+		for _, s := range in.Series {
+			labels = append(labels, s.Label)
+		}
+		if !slices.Equal(labels, tt.labels) {
+			t.Errorf("%s: series %q, want %q", tt.name, labels, tt.labels)
+		}
+	}
+}
+
+func TestTabularBarsPivot(t *testing.T) {
+	out, err := tabularData([]byte(tabularCSV), "bar-chart", columnMapping{Y: []string{"events"}, Series: "kind"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in barChartInput
+	if err := json.Unmarshal(out, &in); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.Bars) != 2 || in.Bars[0].Label != "2024-03-02" || len(in.Bars[1].Values) != 2 ||
+		in.Bars[1].Values[0] != 3 || in.Bars[1].Values[1] != 2 {
+		t.Errorf("bars = %+v, want 2024-03-01 split into deploy 3 and alert 2", in.Bars)
+	}
+}
+
+func TestTabularStatAndGauge(t *testing.T) {
+	out, err := tabularData([]byte(tabularCSV), "gauge", columnMapping{Y: []string{"errors"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"label":"errors","value":2}` {
+		t.Errorf("gauge = %s", out)
+	}
+	out, err = tabularData([]byte(tabularCSV), "stat-card", columnMapping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"trendData"`) {
+		t.Errorf("stat card without a trend: %s", out)
+	}
+}
+
+func TestTabularErrors(t *testing.T) {
+	tests := []struct {
+		data, vizType string
+		mapping       columnMapping
+		want          string
+	}{
+		{tabularCSV, "line-graph", columnMapping{Y: []string{"missing"}}, "missing"},
+		{tabularCSV, "line-graph", columnMapping{X: "kind"}, "does not hold dates"},
+		{"name,team\nann,web\n", "bar-chart", columnMapping{}, "no numeric value column"},
+		{tabularCSV, "gauge", columnMapping{Series: "nope"}, "nope"},
+		{tabularCSV, "treemap", columnMapping{}, "cannot draw treemap"},
+	}
+	for _, tt := range tests {
+		_, err := tabularData([]byte(tt.data), tt.vizType, tt.mapping)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s with %+v: error %v, want %q", tt.vizType, tt.mapping, err, tt.want)
+		}
+	}
+}
+
+func TestCellTime(t *testing.T) {
+	for _, text := range []string{"2024-03-01", "2024-03-01 12:30", "2024-03-01T12:30:05", "2024-03-01T12:30:05.25Z"} {
+		if _, ok := cellTime(tableCell{Text: text}); !ok {
+			t.Errorf("cellTime(%q) is not a date", text)
+		}
+	}
+	for _, c := range []tableCell{{Text: "March"}, {Value: 20240301, Numeric: true}} {
+		if _, ok := cellTime(c); ok {
+			t.Errorf("cellTime(%+v) is a date", c)
+		}
+	}
+}
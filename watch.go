@@ -0,0 +1,198 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultPoll is how often the data file is checked when change
+// notifications are unavailable.
+const defaultPoll = time.Second
+
+// watchOptions configures -watch. A zero Poll uses the platform's change
+// notifications where it has them.
+type watchOptions struct {
+	Enabled  bool
+	Debounce time.Duration
+	Poll     time.Duration
+}
+
+// watch renders the chart and renders it again whenever the data file
+// changes, until interrupted.
+func watch(cfg Config) error {
+	if cfg.dataFile == "" || cfg.dataFile == "-" || isURL(cfg.dataFile) {
+		return fmt.Errorf("-watch needs a local -data file")
+	}
+	live, err := newLiveOutput(cfg)
+	if err != nil {
+		return err
+	}
+	changes, err := watchFile(cfg.dataFile, cfg.watch.Poll)
+	if err != nil {
+		return err
+	}
+
+	redraw := func() {
+		data, err := readData(cfg.dataFile, cfg.fetch)
+		var output string
+		if err == nil {
+			output, err = render(cfg, data)
+		}
+		live.update(output, err, "Watching "+cfg.dataFile)
+	}
+
+	redraw()
+	for range debounce(changes, cfg.watch.Debounce) {
+		redraw()
+	}
+	return fmt.Errorf("stopped watching %s", cfg.dataFile)
+}
+
+// liveOutput shows successive renders of a chart. Terminal output redraws
+// the screen; output to a file rewrites the file and logs each write. A
+// render that fails leaves the last good chart in place and reports the
+// error instead of exiting.
+type liveOutput struct {
+	cfg    Config
+	toFile bool
+	last   string
+}
+
+func newLiveOutput(cfg Config) (*liveOutput, error) {
+	toFile := cfg.output != "" && cfg.output != "-"
+	if cfg.format == "svg" && !toFile {
+		return nil, fmt.Errorf("SVG output in watch and follow modes needs an -output file")
+	}
+	return &liveOutput{cfg: cfg, toFile: toFile}, nil
+}
+
+// update shows a render, or the error that stopped it, with a status line
+// below terminal output.
+func (l *liveOutput) update(output string, err error, status string) {
+	now := time.Now().Format("15:04:05")
+	if l.toFile {
+		if err == nil {
+			err = writeOutput(l.cfg.output, output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Error: %v\n", now, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s Wrote %s\n", now, l.cfg.output)
+		}
+		return
+	}
+
+	if err == nil {
+		l.last = output
+	}
+	var b strings.Builder
+	b.WriteString(ansiClear)
+	b.WriteString(l.last)
+	if !strings.HasSuffix(l.last, "\n") && l.last != "" {
+		b.WriteString("\n")
+	}
+	if err != nil {
+		b.WriteString(colorize(fmt.Sprintf("%s Error: %v", now, err), statusColor(l.cfg.theme, "critical")))
+	} else {
+		b.WriteString(ansiDim + fmt.Sprintf("%s · updated %s", status, now) + ansiReset)
+	}
+	b.WriteString("\n")
+	fmt.Print(b.String())
+}
+
+// watchFile reports changes to the file at path. With a zero interval it
+// uses change notifications, falling back to polling once a second where
+// they are unavailable.
+func watchFile(path string, interval time.Duration) (<-chan struct{}, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		if changes, err := notifyFile(path); err == nil {
+			return changes, nil
+		}
+		interval = defaultPoll
+	}
+	return pollFile(path, interval), nil
+}
+
+// fileStamp identifies a version of a file well enough to notice that it
+// was rewritten.
+type fileStamp struct {
+	modTime int64
+	size    int64
+	exists  bool
+}
+
+func stampFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size(), exists: true}
+}
+
+// pollFile checks the file at path every interval and reports when its
+// modification time, size or existence changes. It works on any
+// filesystem, including network mounts that do not deliver notifications.
+func pollFile(path string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	last := stampFile(path)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if stamp := stampFile(path); stamp != last {
+				last = stamp
+				notify(changes)
+			}
+		}
+	}()
+	return changes
+}
+
+// notify sends on a buffered channel without blocking; a change that is
+// already pending covers this one too.
+func notify(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
+
+// debounce passes on a change only once events have been quiet for delay,
+// so that a file written in several steps is rendered once, complete.
+func debounce(events <-chan struct{}, delay time.Duration) <-chan struct{} {
+	out := make(chan struct{})
+	go func() {
+		defer close(out)
+		var timer <-chan time.Time
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+				timer = time.After(delay)
+			case <-timer:
+				timer = nil
+				out <- struct{}{}
+			}
+		}
+	}()
+	return out
+}
+
+// watchedName splits path into the directory to watch and the file name
+// to look for in its events. Watching the directory rather than the file
+// keeps working when the file is replaced by renaming a new one over it,
+// as editors and generators commonly do.
+func watchedName(path string) (dir, name string) {
+	path = filepath.Clean(path)
+	return filepath.Dir(path), filepath.Base(path)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !dashboard && !simpledashboard
+
+package main
+
+import "errors"
+
+// notifyFile is only implemented on Linux; elsewhere watching polls.
+func notifyFile(path string) (<-chan struct{}, error) {
+	return nil, errors.New("file change notifications are not supported on this platform")
+}
@@ -0,0 +1,69 @@
+//go:build !dashboard && !simpledashboard
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebounceCollapsesBursts(t *testing.T) {
+	events := make(chan struct{})
+	out := debounce(events, 20*time.Millisecond)
+	for i := 0; i < 5; i++ {
+		events <- struct{}{}
+	}
+	select {
+	case <-out:
+	case <-time.After(time.Second):
+		t.Fatal("no change passed on after the burst")
+	}
+	select {
+	case <-out:
+		t.Error("one burst passed on more than once")
+	case <-time.After(60 * time.Millisecond):
+	}
+	close(events)
+	if _, ok := <-out; ok {
+		t.Error("output not closed with the events")
+	}
+}
+
+func TestPollFileNoticesRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes := pollFile(path, 5*time.Millisecond)
+	if err := os.WriteFile(path, []byte(`{"a": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("rewrite not reported")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("removal not reported")
+	}
+}
+
+func TestWatchFileMissing(t *testing.T) {
+	if _, err := watchFile(filepath.Join(t.TempDir(), "missing.json"), 0); err == nil {
+		t.Error("watching a missing file succeeded")
+	}
+}
+
+func TestWatchedName(t *testing.T) {
+	dir, name := watchedName("data/./reports/../metrics.json")
+	if dir != "data" || name != "metrics.json" {
+		t.Errorf("watchedName = %q, %q, want %q, %q", dir, name, "data", "metrics.json")
+	}
+}